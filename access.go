@@ -0,0 +1,80 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AccessLog records which credentials the provider read, when, and whether the reads succeeded.
+// It never records credential values. See WithAccessLog.
+type AccessLog struct {
+	mu      sync.Mutex
+	records map[string]*AccessRecord
+	now     func() time.Time
+}
+
+// AccessRecord describes the reads of a single credential.
+type AccessRecord struct {
+	// Name is the name of the credential.
+	Name string `json:"name"`
+	// Source is where the credential was last read from.
+	Source string `json:"source,omitempty"`
+	// Reads is the number of times the credential was read.
+	Reads int `json:"reads"`
+	// Failures is the number of reads that failed.
+	Failures int `json:"failures"`
+	// LastRead is the time of the last read.
+	LastRead time.Time `json:"last_read"`
+	// LastSuccess is the time of the last successful read, zero if there was none.
+	LastSuccess time.Time `json:"last_success,omitzero"`
+	// LastError is the error of the last read, empty if it succeeded.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// NewAccessLog returns a new, empty AccessLog.
+func NewAccessLog() *AccessLog {
+	return &AccessLog{records: map[string]*AccessRecord{}, now: time.Now}
+}
+
+// record records a read of the credential called name from source that failed with err, if not nil.
+func (a *AccessLog) record(name, source string, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rec, ok := a.records[name]
+	if !ok {
+		rec = &AccessRecord{Name: name}
+		a.records[name] = rec
+	}
+	now := a.now()
+	rec.Reads++
+	rec.LastRead = now
+	if source != "" {
+		rec.Source = source
+	}
+	if err != nil {
+		rec.Failures++
+		rec.LastError = err.Error()
+		return
+	}
+	rec.LastSuccess = now
+	rec.LastError = ""
+}
+
+// Records returns the records of all credentials read so far, sorted by name.
+func (a *AccessLog) Records() []AccessRecord {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	records := make([]AccessRecord, 0, len(a.records))
+	for _, rec := range slices.SortedFunc(maps.Values(a.records), func(a, b *AccessRecord) int {
+		return strings.Compare(a.Name, b.Name)
+	}) {
+		records = append(records, *rec)
+	}
+	return records
+}
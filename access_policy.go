@@ -0,0 +1,56 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"fmt"
+	"path"
+)
+
+// checkAllowed returns an error if the credential called name may not be read according to
+// the patterns set with WithAllowedCredentials and WithDeniedCredentials.
+func (p *provider) checkAllowed(name string) error {
+	denied, err := matchAny(p.cfg.deniedCredentials, name)
+	if err != nil {
+		return err
+	}
+	if denied {
+		return withKind(ErrNotAllowed, fmt.Errorf("credential %q is denied", name))
+	}
+	if p.cfg.allowedCredentials == nil {
+		return nil
+	}
+	allowed, err := matchAny(p.cfg.allowedCredentials, name)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return withKind(ErrNotAllowed, fmt.Errorf("credential %q is not in the list of allowed credentials", name))
+	}
+	return nil
+}
+
+// checkUnrestricted returns an error if the patterns set with WithAllowedCredentials or
+// WithDeniedCredentials restrict the credentials that can be read, as source, such as an `env:`
+// or `file:` source, isn't a credential they could apply to.
+func (p *provider) checkUnrestricted(source string) error {
+	if p.cfg.allowedCredentials == nil && p.cfg.deniedCredentials == nil {
+		return nil
+	}
+	return withKind(ErrNotAllowed, fmt.Errorf("%s sources are not allowed when the credentials that can be read are restricted", source))
+}
+
+// matchAny reports whether name matches any of the glob patterns, see path.Match.
+func matchAny(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("invalid credential pattern %q: %w", pattern, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
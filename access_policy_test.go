@@ -0,0 +1,127 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestAllowedAndDeniedCredentials(t *testing.T) {
+	credDir := t.TempDir()
+	for _, name := range []string{"tenant-a.token", "tenant-a.admin", "tenant-b.token", "host.key"} {
+		require.NoError(t, os.WriteFile(filepath.Join(credDir, name), []byte(testCredValue), 0600))
+	}
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		allowed []string
+		denied  []string
+	}{
+		{
+			name:    "no restrictions",
+			allowed: []string{"tenant-a.token", "tenant-a.admin", "tenant-b.token", "host.key"},
+		},
+		{
+			name:    "allowed",
+			opts:    []Option{WithAllowedCredentials("tenant-a.*")},
+			allowed: []string{"tenant-a.token", "tenant-a.admin"},
+			denied:  []string{"tenant-b.token", "host.key"},
+		},
+		{
+			name:    "denied",
+			opts:    []Option{WithDeniedCredentials("host.*")},
+			allowed: []string{"tenant-a.token", "tenant-a.admin", "tenant-b.token"},
+			denied:  []string{"host.key"},
+		},
+		{
+			name:    "denied takes precedence",
+			opts:    []Option{WithAllowedCredentials("tenant-a.*"), WithAllowedCredentials("host.key"), WithDeniedCredentials("*.admin")},
+			allowed: []string{"tenant-a.token", "host.key"},
+			denied:  []string{"tenant-a.admin", "tenant-b.token"},
+		},
+		{
+			name:   "alias to a denied credential",
+			opts:   []Option{WithAliases(map[string]string{"token": "host.key"}), WithDeniedCredentials("host.*")},
+			denied: []string{"token"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prov := NewFactory(append([]Option{WithCredentialsDirectory(credDir)}, tt.opts...)...).Create(confmaptest.NewNopProviderSettings())
+			for _, name := range tt.allowed {
+				_, err := prov.Retrieve(context.Background(), credSchemePrefix+name, nil)
+				assert.NoError(t, err, name)
+			}
+			for _, name := range tt.denied {
+				_, err := prov.Retrieve(context.Background(), credSchemePrefix+name+"?optional=true", nil)
+				assert.ErrorIs(t, err, ErrNotAllowed, name)
+			}
+			assert.NoError(t, prov.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestAllowedCredentialsBypass(t *testing.T) {
+	credDir := t.TempDir()
+	for name, val := range map[string]string{
+		"allowed":  "${env:RESTRICTED_SECRET}",
+		"Host.Key": testCredValue,
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(credDir, name), []byte(val), 0600))
+	}
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte(testCredValue), 0600))
+	t.Setenv("RESTRICTED_SECRET", testCredValue)
+
+	for _, opts := range [][]Option{
+		{WithAllowedCredentials("allowed", "missing")},
+		{WithDeniedCredentials("host.*", "Host.*")},
+	} {
+		prov := NewFactory(append([]Option{WithCredentialsDirectory(credDir), WithFileFallback(true), WithCaseInsensitiveFallback(true)}, opts...)...).Create(confmaptest.NewNopProviderSettings())
+		for _, uri := range []string{
+			"missing|env:RESTRICTED_SECRET",
+			"missing|file:" + secretFile,
+			"allowed?expand=true",
+		} {
+			_, err := prov.Retrieve(context.Background(), credSchemePrefix+uri, nil)
+			assert.ErrorIs(t, err, ErrNotAllowed, uri)
+		}
+		assert.NoError(t, prov.Shutdown(context.Background()))
+	}
+
+	// The credential found by the case-insensitive fallback has to be allowed as well.
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithCaseInsensitiveFallback(true), WithDeniedCredentials("Host.*")).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"host.key", nil)
+	assert.ErrorIs(t, err, ErrNotAllowed)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestDeniedCredentialsAreNotSuggested(t *testing.T) {
+	credDir := t.TempDir()
+	for _, name := range []string{"api_token", "api_token_v2"} {
+		require.NoError(t, os.WriteFile(filepath.Join(credDir, name), []byte(testCredValue), 0600))
+	}
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithDeniedCredentials("*_v2")).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api-token", nil)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `did you mean "api_token"?`)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestInvalidCredentialPattern(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithDeniedCredentials("[")).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	assert.ErrorContains(t, err, `invalid credential pattern "["`)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
@@ -0,0 +1,53 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestAccessLog(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	log := NewAccessLog()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	log.now = func() time.Time { return now }
+
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithAccessLog(log)).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"missing:-default", nil)
+	require.NoError(t, err)
+
+	later := now.Add(time.Minute)
+	log.now = func() time.Time { return later }
+	require.NoError(t, os.Remove(filepath.Join(credDir, "api_token")))
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.Error(t, err)
+
+	records := log.Records()
+	require.Len(t, records, 2)
+	assert.Equal(t, "api_token", records[0].Name)
+	assert.Equal(t, filepath.Join(credDir, "api_token"), records[0].Source)
+	assert.Equal(t, 2, records[0].Reads)
+	assert.Equal(t, 1, records[0].Failures)
+	assert.Equal(t, later, records[0].LastRead)
+	assert.Equal(t, now, records[0].LastSuccess)
+	assert.Contains(t, records[0].LastError, "no such file or directory")
+	assert.Equal(t, "missing", records[1].Name)
+	assert.Equal(t, 1, records[1].Failures)
+	assert.True(t, records[1].LastSuccess.IsZero())
+	for _, rec := range records {
+		assert.NotContains(t, rec.LastError, testCredValue)
+	}
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
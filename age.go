@@ -0,0 +1,105 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// defaultAgeCommand is the command used to decrypt age-encrypted credentials, see WithAgeCommand.
+	defaultAgeCommand = "age"
+	// identityFile is the path under which decryption commands find the identity passed to them,
+	// the first of cmd.ExtraFiles.
+	identityFile = "/dev/fd/3"
+)
+
+// decryptAge decrypts val, the age-encrypted content of the credential called name retrieved for
+// uri, with the identities in the credential called identityName.
+func (p *provider) decryptAge(ctx context.Context, uri, name, identityName string, val []byte, bufs *credentialBuffers) ([]byte, error) {
+	identityName, identity, err := p.readAgeIdentity(ctx, uri, identityName, bufs)
+	if err != nil {
+		return nil, err
+	}
+	val, err = runDecryptCommand(ctx, p.cfg.ageCommand, []string{"--decrypt", "--identity", identityFile}, nil, val, identity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credential %q with age identity %q: %w", name, identityName, err)
+	}
+	return bufs.add(val), nil
+}
+
+// readAgeIdentity reads the credential called name holding age identities for uri, returning the
+// name of the credential after resolving aliases and its content.
+func (p *provider) readAgeIdentity(ctx context.Context, uri, name string, bufs *credentialBuffers) (string, []byte, error) {
+	name, err := p.credentialName(name)
+	if err != nil {
+		return "", nil, err
+	}
+	identity, _, err := p.readCredentialObserved(ctx, uri, name)
+	if err != nil {
+		// A missing identity must not make the credential itself count as missing.
+		return "", nil, fmt.Errorf("failed to read age identity %q: %v", name, err)
+	}
+	return name, bufs.add(identity), nil
+}
+
+// runDecryptCommand runs command with args and the extra environment variables env to decrypt
+// val, returning its output. A non-nil identity is passed through a pipe at identityFile, so
+// that it is never written to disk.
+func runDecryptCommand(ctx context.Context, command string, args, env []string, val, identity []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Stdin = bytes.NewReader(val)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var identityReader, identityWriter *os.File
+	if identity != nil {
+		var err error
+		if identityReader, identityWriter, err = os.Pipe(); err != nil {
+			return nil, err
+		}
+		cmd.ExtraFiles = []*os.File{identityReader}
+	}
+	err := cmd.Start()
+	if identityReader != nil {
+		// The child has its own copy of the read end; closing ours makes writing fail rather
+		// than block if the child exits without reading the identity.
+		identityReader.Close()
+	}
+	if err != nil {
+		if identityWriter != nil {
+			identityWriter.Close()
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("%s is not available, it is needed to decrypt the credential: %w", command, err)
+		}
+		return nil, err
+	}
+	var writeErr error
+	if identityWriter != nil {
+		_, writeErr = identityWriter.Write(identity)
+		identityWriter.Close()
+	}
+	if err := cmd.Wait(); err != nil {
+		clear(stdout.Bytes())
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s failed: %s: %w", command, msg, err)
+		}
+		return nil, fmt.Errorf("%s failed: %w", command, err)
+	}
+	if writeErr != nil {
+		clear(stdout.Bytes())
+		return nil, fmt.Errorf("failed to pass the identity to %s: %w", command, writeErr)
+	}
+	return stdout.Bytes(), nil
+}
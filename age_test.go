@@ -0,0 +1,103 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+// testAgeIdentity is the only identity the fake age accepts.
+const testAgeIdentity = "AGE-SECRET-KEY-1TEST"
+
+// fakeAge writes a script standing in for age, which "decrypts" by reversing its input when the
+// identity is testAgeIdentity.
+func fakeAge(t *testing.T) string {
+	script := filepath.Join(t.TempDir(), "age")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+[ "$1" = --decrypt ] && [ "$2" = --identity ] || exit 2
+if ! grep -qx `+testAgeIdentity+` "$3"; then
+	echo "age: error: no identity matched any of the recipients" >&2
+	exit 1
+fi
+rev
+`), 0700))
+	return script
+}
+
+func TestAgeEncryptedCredential(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("54321-nekot-terces-ym\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "age_key"), []byte("# created: 2024-01-01T00:00:00Z\n"+testAgeIdentity+"\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "other_key"), []byte("AGE-SECRET-KEY-1OTHER\n"), 0600))
+
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithAgeCommand(fakeAge(t))).Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token?age_identity=age_key", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token?age_identity=other_key", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `failed to decrypt credential "api_token" with age identity "other_key"`)
+	assert.Contains(t, err.Error(), "no identity matched any of the recipients")
+
+	// A missing identity doesn't count as a missing credential, so the default doesn't apply.
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token:-fallback?age_identity=missing", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `failed to read age identity "missing"`)
+	assert.NotErrorIs(t, err, ErrNotFound)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token?age_identity=../age_key", nil)
+	assert.ErrorIs(t, err, ErrInvalidName)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestAgeEncryptedCredentialWithoutAge(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("blob"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "age_key"), []byte(testAgeIdentity), 0600))
+
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithAgeCommand("age-does-not-exist")).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token?age_identity=age_key", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "age-does-not-exist is not available, it is needed to decrypt the credential")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestAgeIdentityObserved(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("54321-nekot-terces-ym\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "age_key"), []byte(testAgeIdentity+"\n"), 0600))
+
+	accessLog := NewAccessLog()
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithAgeCommand(fakeAge(t)), WithAccessLog(accessLog),
+		WithWatchInterval(10*time.Millisecond)).Create(confmaptest.NewNopProviderSettings())
+	t.Cleanup(func() {
+		assert.NoError(t, prov.Shutdown(context.Background()))
+	})
+	events := retrieveWatched(t, prov, credSchemePrefix+"api_token?age_identity=age_key")
+	var names []string
+	for _, rec := range accessLog.Records() {
+		names = append(names, rec.Name)
+	}
+	assert.Equal(t, []string{"age_key", "api_token"}, names)
+
+	// Rotating the identity is a change of the retrieved value.
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "age_key"), []byte("AGE-SECRET-KEY-1ROTATED\n"), 0600))
+	select {
+	case event := <-events:
+		assert.NoError(t, event.Error)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher not called after the age identity changed")
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"os"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"bou.ke/systemdcredentialprovider/internal/journal"
+)
+
+// auditMessageID identifies credential access entries in the journal, e.g. for
+// `journalctl MESSAGE_ID=0b3f6f1e2c8a4f5d9e7a1c2b3d4e5f60`.
+const auditMessageID = "0b3f6f1e2c8a4f5d9e7a1c2b3d4e5f60"
+
+// audit writes a journal entry recording that the credential called name was read for uri
+// from source, and failed with err if not nil. The credential value is never logged.
+func (p *provider) audit(name, uri, source string, err error) {
+	outcome, priority, msg := "success", "6", "Credential "+name+" read"
+	if err != nil {
+		outcome, priority, msg = "failure", "4", "Credential "+name+" could not be read"
+	}
+	fields := []journal.Field{
+		{Name: "MESSAGE", Value: msg},
+		{Name: "MESSAGE_ID", Value: auditMessageID},
+		{Name: "PRIORITY", Value: priority},
+		{Name: "SYSLOG_IDENTIFIER", Value: "systemdcredentialprovider"},
+		{Name: "CREDENTIAL_NAME", Value: name},
+		{Name: "CREDENTIAL_SCHEME", Value: p.cfg.scheme},
+		{Name: "CREDENTIAL_URI", Value: uri},
+		{Name: "CREDENTIAL_OUTCOME", Value: outcome},
+		// journald adds the trusted _PID field as well, this one is kept for log forwarders
+		// that drop trusted fields.
+		{Name: "CREDENTIAL_CALLER_PID", Value: strconv.Itoa(os.Getpid())},
+	}
+	if unit, user := p.serviceUnit(); unit != "" {
+		// Like the entries systemd writes about units, so that `journalctl -u` and
+		// `journalctl --user-unit` show them.
+		field := "UNIT"
+		if user {
+			field = "USER_UNIT"
+		}
+		fields = append(fields, journal.Field{Name: field, Value: unit})
+	}
+	if source != "" {
+		fields = append(fields, journal.Field{Name: "CREDENTIAL_SOURCE", Value: source})
+	}
+	if err != nil {
+		fields = append(fields, journal.Field{Name: "CREDENTIAL_ERROR", Value: err.Error()})
+	}
+	if sendErr := journal.Send(p.cfg.journalSocket, fields...); sendErr != nil {
+		p.cfg.logger.Warn("Failed to write credential audit entry to the journal", zap.String("credential", name), zap.Error(sendErr))
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestAuditLog(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	socket := filepath.Join(t.TempDir(), "journal")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+	readEntry := func() map[string]string {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		buf := make([]byte, 4096)
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		assert.NotContains(t, string(buf[:n]), testCredValue)
+		entry := map[string]string{}
+		for _, line := range strings.Split(strings.TrimSuffix(string(buf[:n]), "\n"), "\n") {
+			name, value, _ := strings.Cut(line, "=")
+			entry[name] = value
+		}
+		return entry
+	}
+
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithAuditLog(true), optionFunc(func(cfg *config) {
+		cfg.journalSocket = socket
+	})).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token?trim=all-whitespace", nil)
+	require.NoError(t, err)
+	entry := readEntry()
+	assert.Equal(t, "Credential api_token read", entry["MESSAGE"])
+	assert.Equal(t, auditMessageID, entry["MESSAGE_ID"])
+	assert.Equal(t, "6", entry["PRIORITY"])
+	assert.Equal(t, "api_token", entry["CREDENTIAL_NAME"])
+	assert.Equal(t, "systemdcredential", entry["CREDENTIAL_SCHEME"])
+	assert.Equal(t, credSchemePrefix+"api_token?trim=all-whitespace", entry["CREDENTIAL_URI"])
+	assert.Equal(t, "success", entry["CREDENTIAL_OUTCOME"])
+	assert.Equal(t, filepath.Join(credDir, "api_token"), entry["CREDENTIAL_SOURCE"])
+	assert.Equal(t, strconv.Itoa(os.Getpid()), entry["CREDENTIAL_CALLER_PID"])
+	assert.NotContains(t, entry, "CREDENTIAL_ERROR")
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"missing:-default", nil)
+	require.NoError(t, err)
+	entry = readEntry()
+	assert.Equal(t, "Credential missing could not be read", entry["MESSAGE"])
+	assert.Equal(t, "4", entry["PRIORITY"])
+	assert.Equal(t, "failure", entry["CREDENTIAL_OUTCOME"])
+	assert.Contains(t, entry["CREDENTIAL_ERROR"], "no such file or directory")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
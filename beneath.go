@@ -0,0 +1,100 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SymlinkPolicy controls whether credentials that are symlinks are read, see WithSymlinkPolicy.
+type SymlinkPolicy string
+
+const (
+	// SymlinkFollow follows symlinks wherever they point.
+	SymlinkFollow SymlinkPolicy = "follow"
+	// SymlinkFollowWithinDirectory follows symlinks as long as they resolve inside the credentials directory.
+	SymlinkFollowWithinDirectory SymlinkPolicy = "follow-within-directory"
+	// SymlinkReject refuses to read credentials through symlinks.
+	SymlinkReject SymlinkPolicy = "reject"
+)
+
+var (
+	// errPathEscapes is returned when a credential name resolves outside of its directory.
+	errPathEscapes = errors.New("path escapes from the credentials directory")
+	// errSymlink is returned for credentials that are symlinks under SymlinkReject.
+	errSymlink = errors.New("credential is a symlink")
+)
+
+// beneathFS is a file system rooted at dir that opens files with openat2 and RESOLVE_BENEATH,
+// so that no path, including the symlinks it traverses, can resolve outside of dir. Where
+// openat2 is unavailable, the symlink policy is checked before opening the file with os.DirFS.
+type beneathFS struct {
+	fs.FS
+	dir    string
+	policy SymlinkPolicy
+}
+
+// credentialsDirFS returns the file system credentials in dir are read from under policy.
+func credentialsDirFS(dir string, policy SymlinkPolicy) fs.FS {
+	if policy == SymlinkFollow {
+		return os.DirFS(dir)
+	}
+	return beneathFS{FS: os.DirFS(dir), dir: dir, policy: policy}
+}
+
+func (fsys beneathFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return fsys.FS.Open(name)
+	}
+	f, err := openBeneath(fsys.dir, name, fsys.policy)
+	if errors.Is(err, errors.ErrUnsupported) {
+		if err := checkSymlinkPolicy(fsys.dir, name, fsys.policy); err != nil {
+			return nil, err
+		}
+		return fsys.FS.Open(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// checkSymlinkPolicy checks name, relative to dir, against policy without openat2. Unlike
+// openat2 this is racy, but still catches symlinks that are put in place ahead of time.
+func checkSymlinkPolicy(dir, name string, policy SymlinkPolicy) error {
+	path := filepath.Join(dir, name)
+	switch policy {
+	case SymlinkReject:
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return &fs.PathError{Op: "open", Path: path, Err: errSymlink}
+		}
+	case SymlinkFollowWithinDirectory:
+		resolvedDir, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return err
+		}
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return err
+		}
+		if rel, err := filepath.Rel(resolvedDir, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return &fs.PathError{Op: "open", Path: path, Err: errPathEscapes}
+		}
+	default:
+		return fmt.Errorf("unsupported symlink policy %q", policy)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// sysOpenat2 is the number of the openat2 system call, the same on every architecture.
+	sysOpenat2 = 437
+
+	resolveNoMagiclinks = 0x02
+	resolveNoSymlinks   = 0x04
+	resolveBeneath      = 0x08
+)
+
+// openHow is struct open_how from linux/openat2.h.
+type openHow struct {
+	flags   uint64
+	mode    uint64
+	resolve uint64
+}
+
+// symlinkResolve holds the openat2 resolve flags implementing every SymlinkPolicy that uses openat2.
+var symlinkResolve = map[SymlinkPolicy]uint64{
+	SymlinkFollowWithinDirectory: resolveBeneath | resolveNoMagiclinks,
+	SymlinkReject:                resolveBeneath | resolveNoSymlinks,
+}
+
+// openBeneath opens name relative to dir with openat2, refusing to resolve outside of dir
+// or through magic links such as /proc/self/fd/N, or through any symlink under SymlinkReject.
+// It returns an error matching errors.ErrUnsupported on kernels older than 5.6, which lack openat2.
+func openBeneath(dir, name string, policy SymlinkPolicy) (*os.File, error) {
+	path := filepath.Join(dir, name)
+	resolve, ok := symlinkResolve[policy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported symlink policy %q", policy)
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	namePtr, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "openat2", Path: path, Err: err}
+	}
+	how := openHow{
+		flags:   syscall.O_RDONLY | syscall.O_CLOEXEC,
+		resolve: resolve,
+	}
+	for {
+		fd, _, errno := syscall.Syscall6(sysOpenat2, d.Fd(), uintptr(unsafe.Pointer(namePtr)), uintptr(unsafe.Pointer(&how)), unsafe.Sizeof(how), 0, 0)
+		switch errno {
+		case 0:
+			return os.NewFile(fd, path), nil
+		case syscall.EINTR, syscall.EAGAIN:
+			// EAGAIN is returned when a concurrent rename may have let the lookup escape.
+			continue
+		case syscall.EXDEV:
+			return nil, &fs.PathError{Op: "openat2", Path: path, Err: errPathEscapes}
+		case syscall.ELOOP:
+			if policy == SymlinkReject {
+				return nil, &fs.PathError{Op: "openat2", Path: path, Err: errSymlink}
+			}
+			return nil, &fs.PathError{Op: "openat2", Path: path, Err: errno}
+		default:
+			return nil, &fs.PathError{Op: "openat2", Path: path, Err: errno}
+		}
+	}
+}
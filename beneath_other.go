@@ -0,0 +1,16 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"errors"
+	"os"
+)
+
+// openBeneath is only implemented on Linux, other platforms fall back to os.DirFS.
+func openBeneath(string, string, SymlinkPolicy) (*os.File, error) {
+	return nil, errors.ErrUnsupported
+}
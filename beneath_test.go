@@ -0,0 +1,105 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestSymlinkPolicy(t *testing.T) {
+	credDir := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "outside")
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	require.NoError(t, os.WriteFile(outside, []byte("outside"), 0600))
+	require.NoError(t, os.Symlink("api_token", filepath.Join(credDir, "alias")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(credDir, "escape")))
+
+	tests := []struct {
+		policy     SymlinkPolicy
+		aliasErr   error
+		escapeErr  error
+		escapeWant string
+	}{
+		{policy: SymlinkFollow, escapeWant: "outside"},
+		{policy: SymlinkFollowWithinDirectory, escapeErr: errPathEscapes},
+		{policy: SymlinkReject, aliasErr: errSymlink, escapeErr: errSymlink},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			prov := NewFactory(WithCredentialsDirectory(credDir), WithSymlinkPolicy(tt.policy)).Create(confmaptest.NewNopProviderSettings())
+
+			ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+			require.NoError(t, err)
+			str, err := ret.AsString()
+			require.NoError(t, err)
+			assert.Equal(t, testCredValue, str)
+
+			ret, err = prov.Retrieve(context.Background(), credSchemePrefix+"alias", nil)
+			if tt.aliasErr != nil {
+				assert.ErrorIs(t, err, tt.aliasErr)
+			} else {
+				require.NoError(t, err)
+				str, err = ret.AsString()
+				require.NoError(t, err)
+				assert.Equal(t, testCredValue, str)
+			}
+
+			ret, err = prov.Retrieve(context.Background(), credSchemePrefix+"escape", nil)
+			if tt.escapeErr != nil {
+				assert.ErrorIs(t, err, tt.escapeErr)
+			} else {
+				require.NoError(t, err)
+				str, err = ret.AsString()
+				require.NoError(t, err)
+				assert.Equal(t, tt.escapeWant, str)
+			}
+			assert.NoError(t, prov.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestCheckSymlinkPolicy(t *testing.T) {
+	credDir := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "outside")
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	require.NoError(t, os.WriteFile(outside, []byte("outside"), 0600))
+	require.NoError(t, os.Symlink("api_token", filepath.Join(credDir, "alias")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(credDir, "escape")))
+
+	assert.NoError(t, checkSymlinkPolicy(credDir, "api_token", SymlinkReject))
+	assert.ErrorIs(t, checkSymlinkPolicy(credDir, "alias", SymlinkReject), errSymlink)
+	assert.NoError(t, checkSymlinkPolicy(credDir, "alias", SymlinkFollowWithinDirectory))
+	assert.ErrorIs(t, checkSymlinkPolicy(credDir, "escape", SymlinkFollowWithinDirectory), errPathEscapes)
+	assert.ErrorContains(t, checkSymlinkPolicy(credDir, "api_token", "sometimes"), `unsupported symlink policy "sometimes"`)
+}
+
+func TestOpenBeneath(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	f, err := openBeneath(credDir, "api_token", SymlinkFollowWithinDirectory)
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip("openat2 is not supported")
+	}
+	require.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	_, err = openBeneath(credDir, "api_token", "sometimes")
+	assert.ErrorContains(t, err, `unsupported symlink policy "sometimes"`)
+}
+
+func TestCredentialsDirFSInvalidPath(t *testing.T) {
+	fsys := credentialsDirFS(t.TempDir(), SymlinkFollowWithinDirectory)
+	for _, name := range []string{"../escape", "/etc/passwd", "a/../../b"} {
+		_, err := fsys.Open(name)
+		assert.ErrorIs(t, err, os.ErrInvalid, name)
+	}
+}
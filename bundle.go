@@ -0,0 +1,104 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// bundleSeparator separates the name of a bundle credential from the member that is read from
+// it, e.g. `bundle/api_token`. Credential names can't contain '/', so the names are unambiguous.
+const bundleSeparator = "/"
+
+// tarMagicOffset is the offset of the magic of a POSIX or GNU tar header, "ustar".
+const tarMagicOffset = 257
+
+// splitBundleMember splits name, as written in the configuration, into the name of the
+// credential and the member of the bundle to read from it, which is empty for plain credentials.
+func splitBundleMember(name string) (string, string, error) {
+	bundle, member, found := strings.Cut(name, bundleSeparator)
+	if found && member == "" {
+		return "", "", withKind(ErrInvalidName, fmt.Errorf("credential name %q selects an empty bundle member", name))
+	}
+	return bundle, member, nil
+}
+
+// isTar reports whether val starts with a tar header.
+func isTar(val []byte) bool {
+	return len(val) >= tarMagicOffset+5 && string(val[tarMagicOffset:tarMagicOffset+5]) == "ustar"
+}
+
+// bundleMember returns the content of member in the bundle val, which is either a tar archive
+// or a JSON or YAML mapping. Members of tar archives are addressed by their path, members of
+// mappings by their key, where '/' selects a key of a nested mapping. A member that doesn't
+// exist is reported as fs.ErrNotExist.
+func bundleMember(val []byte, member string) ([]byte, error) {
+	if isTar(val) {
+		return tarMember(val, member)
+	}
+	return mappingMember(val, member)
+}
+
+func tarMember(val []byte, member string) ([]byte, error) {
+	r := tar.NewReader(bytes.NewReader(val))
+	for {
+		hdr, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("bundle has no member %q: %w", member, fs.ErrNotExist)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle as tar archive: %w", err)
+		}
+		if strings.TrimPrefix(path.Clean(hdr.Name), "./") != member {
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("bundle member %q is not a regular file", member)
+		}
+		// The header can't claim more than the bundle holds, which is already bounded in size.
+		if hdr.Size > int64(len(val)) {
+			return nil, fmt.Errorf("bundle member %q is truncated", member)
+		}
+		content := make([]byte, hdr.Size)
+		if _, err := io.ReadFull(r, content); err != nil {
+			return nil, fmt.Errorf("failed to read bundle member %q: %w", member, err)
+		}
+		return content, nil
+	}
+}
+
+func mappingMember(val []byte, member string) ([]byte, error) {
+	var node any
+	if err := yaml.Unmarshal(val, &node); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle as JSON or YAML: %w", err)
+	}
+	for _, key := range strings.Split(member, "/") {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil, errors.New("bundle is not a mapping of members")
+		}
+		if node, ok = m[key]; !ok {
+			return nil, fmt.Errorf("bundle has no member %q: %w", member, fs.ErrNotExist)
+		}
+	}
+	switch v := node.(type) {
+	case string:
+		return []byte(v), nil
+	case map[string]any, []any:
+		return nil, fmt.Errorf("bundle member %q is not a scalar", member)
+	case nil:
+		return []byte{}, nil
+	default:
+		return []byte(fmt.Sprint(v)), nil
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tarBundle(t *testing.T, members map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	require.NoError(t, w.WriteHeader(&tar.Header{Name: "./tls/", Typeflag: tar.TypeDir, Mode: 0700}))
+	for name, content := range members {
+		require.NoError(t, w.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0600, Size: int64(len(content))}))
+		_, err := w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestBundle(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "tarbundle"), tarBundle(t, map[string]string{
+		"./api_token": testCredValue,
+		"tls/key.pem": "key",
+	}), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "yamlbundle"), []byte("api_token: "+testCredValue+"\nport: 4317\ndb:\n  password: hunter2\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "jsonbundle"), []byte(`{"api_token": "`+testCredValue+`", "enabled": true}`), 0600))
+
+	prov := createProvider()
+	for _, tt := range []struct {
+		uri  string
+		want any
+	}{
+		{uri: "tarbundle/api_token", want: testCredValue},
+		{uri: "tarbundle/tls/key.pem", want: "key"},
+		{uri: "yamlbundle/api_token", want: testCredValue},
+		{uri: "yamlbundle/port?parse=yaml", want: 4317},
+		{uri: "yamlbundle/db/password", want: "hunter2"},
+		{uri: "jsonbundle/api_token+jsonbundle/enabled", want: testCredValue + "\ntrue"},
+		{uri: "yamlbundle%2Fapi_token", want: testCredValue},
+		{uri: "yamlbundle/missing?optional=true", want: nil},
+		{uri: "tarbundle/missing:-fallback", want: "fallback"},
+	} {
+		t.Run(tt.uri, func(t *testing.T) {
+			ret, err := prov.Retrieve(context.Background(), credSchemePrefix+tt.uri, nil)
+			require.NoError(t, err)
+			raw, err := ret.AsRaw()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, raw)
+		})
+	}
+
+	for _, tt := range []struct {
+		uri     string
+		wantErr string
+	}{
+		{uri: "yamlbundle/missing", wantErr: `bundle has no member "missing"`},
+		{uri: "tarbundle/tls", wantErr: `bundle member "tls" is not a regular file`},
+		{uri: "yamlbundle/db", wantErr: `bundle member "db" is not a scalar`},
+		{uri: "yamlbundle/port/number", wantErr: "bundle is not a mapping of members"},
+		{uri: "yamlbundle/", wantErr: "selects an empty bundle member"},
+		{uri: "../yamlbundle/api_token", wantErr: "invalid name"},
+		{uri: "yamlbundle/api_token?dropins=true", wantErr: "must not combine dropins with bundle members"},
+		{uri: "yamlbundle/api_token?exists=true", wantErr: "must not combine exists with bundle members"},
+	} {
+		t.Run(tt.uri, func(t *testing.T) {
+			_, err := prov.Retrieve(context.Background(), credSchemePrefix+tt.uri, nil)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"yamlbundle/missing", nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"yamlbundle/", nil)
+	assert.ErrorIs(t, err, ErrInvalidName)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
@@ -0,0 +1,130 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// credentialCache caches the contents of credentials by name for a limited time, and
+// de-duplicates concurrent reads of the same credential, see WithCache.
+type credentialCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// cacheEntry is a credential that is being read or has been read.
+type cacheEntry struct {
+	// ready is closed once the read finished and the fields below are set.
+	ready   chan struct{}
+	done    bool
+	val     []byte
+	source  string
+	err     error
+	expires time.Time
+}
+
+// readCredentialCached reads the credential called name through the cache, if enabled.
+func (p *provider) readCredentialCached(ctx context.Context, name string) ([]byte, string, error) {
+	if p.cache == nil {
+		return p.readCredentialWithRetry(ctx, name)
+	}
+	return p.cache.get(ctx, name, func() ([]byte, string, error) {
+		return p.readCredentialWithRetry(ctx, name)
+	})
+}
+
+func newCredentialCache(ttl time.Duration) *credentialCache {
+	return &credentialCache{ttl: ttl, now: time.Now, entries: map[string]*cacheEntry{}}
+}
+
+// get returns a copy of the cached content of the credential called name, calling read to
+// read it if it isn't cached or has expired. Concurrent calls for the same name share a
+// single call to read. Failed reads aren't cached. A read that failed because the context of
+// the caller doing it was done is repeated by the other callers, with their own read.
+func (c *credentialCache) get(ctx context.Context, name string, read func() ([]byte, string, error)) ([]byte, string, error) {
+	c.mu.Lock()
+	e, ok := c.entries[name]
+	if ok && e.done && !c.now().Before(e.expires) {
+		c.remove(name, e)
+		ok = false
+	}
+	if !ok {
+		e = &cacheEntry{ready: make(chan struct{})}
+		c.entries[name] = e
+		c.mu.Unlock()
+
+		val, source, err := read()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		e.val, e.source, e.err, e.done = val, source, err, true
+		e.expires = c.now().Add(c.ttl)
+		close(e.ready)
+		cached := c.entries[name] == e
+		if err != nil {
+			if cached {
+				delete(c.entries, name)
+			}
+			return nil, source, err
+		}
+		ret := bytes.Clone(val)
+		if !cached {
+			// The entry was invalidated while it was being read.
+			clear(val)
+		}
+		return ret, source, nil
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-e.ready:
+	case <-ctx.Done():
+		return nil, "", ctx.Err()
+	}
+	c.mu.Lock()
+	// The content of an entry is wiped when it is removed, so it is only valid while cached.
+	valid := e.err != nil || c.entries[name] == e
+	val := bytes.Clone(e.val)
+	c.mu.Unlock()
+	if !valid || errors.Is(e.err, context.Canceled) || errors.Is(e.err, context.DeadlineExceeded) {
+		return c.get(ctx, name, read)
+	}
+	return val, e.source, e.err
+}
+
+// invalidate removes the credentials called names from the cache, wiping their contents.
+func (c *credentialCache) invalidate(names ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, name := range names {
+		if e, ok := c.entries[name]; ok {
+			c.remove(name, e)
+		}
+	}
+}
+
+// invalidateAll removes all credentials from the cache, wiping their contents.
+func (c *credentialCache) invalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, e := range c.entries {
+		c.remove(name, e)
+	}
+}
+
+// remove removes e, cached as name, wiping its content if its read finished. c.mu must be held.
+func (c *credentialCache) remove(name string, e *cacheEntry) {
+	delete(c.entries, name)
+	if e.done {
+		clear(e.val)
+	}
+}
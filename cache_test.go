@@ -0,0 +1,153 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestCache(t *testing.T) {
+	fsys := &flakyFS{FS: fstest.MapFS{"api_token": {Data: []byte(testCredValue)}}}
+	prov := NewFactory(WithFS(fsys), WithCache(time.Minute)).Create(confmaptest.NewNopProviderSettings()).(*provider)
+	now := time.Now()
+	prov.cache.now = func() time.Time { return now }
+
+	retrieve := func() *confmap.Retrieved {
+		ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+		require.NoError(t, err)
+		str, err := ret.AsString()
+		require.NoError(t, err)
+		assert.Equal(t, testCredValue, str)
+		return ret
+	}
+	first := retrieve()
+	retrieve()
+	retrieve()
+	assert.Equal(t, 1, fsys.opens)
+
+	// Closing a value, as the resolver does on reload, invalidates the credentials it was read from.
+	require.NoError(t, first.Close(context.Background()))
+	retrieve()
+	assert.Equal(t, 2, fsys.opens)
+
+	now = now.Add(time.Minute)
+	retrieve()
+	assert.Equal(t, 3, fsys.opens)
+
+	assert.NoError(t, prov.Shutdown(context.Background()))
+	assert.Empty(t, prov.cache.entries)
+}
+
+func TestCacheDoesNotCacheErrors(t *testing.T) {
+	fsys := &flakyFS{FS: fstest.MapFS{"api_token": {Data: []byte(testCredValue)}}, failures: 1}
+	prov := NewFactory(WithFS(fsys), WithCache(time.Minute)).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.Error(t, err)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, fsys.opens)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestCacheSingleflight(t *testing.T) {
+	cache := newCredentialCache(time.Minute)
+	var reads atomic.Int32
+	release := make(chan struct{})
+	read := func() ([]byte, string, error) {
+		reads.Add(1)
+		<-release
+		return []byte(testCredValue), "source", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]byte, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, _, err := cache.get(context.Background(), "api_token", read)
+			assert.NoError(t, err)
+			results[i] = val
+		}()
+	}
+	// Give the goroutines a chance to wait for the first read before it finishes.
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), reads.Load())
+	for _, val := range results {
+		assert.Equal(t, testCredValue, string(val))
+	}
+	// Every caller gets its own copy, so that wiping one doesn't affect the others or the cache.
+	clear(results[0])
+	val, _, err := cache.get(context.Background(), "api_token", read)
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, string(val))
+}
+
+func TestCacheWaitCanceled(t *testing.T) {
+	cache := newCredentialCache(time.Minute)
+	release := make(chan struct{})
+	defer close(release)
+	go func() {
+		_, _, _ = cache.get(context.Background(), "api_token", func() ([]byte, string, error) {
+			<-release
+			return nil, "", nil
+		})
+	}()
+	require.Eventually(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return len(cache.entries) == 1
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := cache.get(ctx, "api_token", nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCacheReadCanceled(t *testing.T) {
+	cache := newCredentialCache(time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error, 1)
+	go func() {
+		_, _, err := cache.get(ctx, "api_token", func() ([]byte, string, error) {
+			<-ctx.Done()
+			return nil, "", ctx.Err()
+		})
+		canceled <- err
+	}()
+	require.Eventually(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return len(cache.entries) == 1
+	}, time.Second, time.Millisecond)
+
+	// A caller waiting for the read of a canceled caller reads the credential itself.
+	result := make(chan []byte, 1)
+	go func() {
+		val, _, err := cache.get(context.Background(), "api_token", func() ([]byte, string, error) {
+			return []byte(testCredValue), "source", nil
+		})
+		assert.NoError(t, err)
+		result <- val
+	}()
+	// Give the caller a chance to wait for the first read before it fails.
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-canceled, context.Canceled)
+	assert.Equal(t, testCredValue, string(<-result))
+}
@@ -0,0 +1,63 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// pemCertificateHeader starts every PEM-encoded certificate.
+var pemCertificateHeader = []byte("-----BEGIN CERTIFICATE-----")
+
+// checkCertificateExpiry warns about the certificates in val, the content of the credential
+// called name, that expire within the window set with WithCertificateExpiryWarning.
+func (p *provider) checkCertificateExpiry(ctx context.Context, name string, val []byte) {
+	if !bytes.Contains(val, pemCertificateHeader) {
+		return
+	}
+	now := p.now()
+	var earliest *x509.Certificate
+	for rest := val; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			clear(block.Bytes)
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			p.cfg.logger.Debug("Skipping certificate that failed to parse", zap.String("credential", name), zap.Error(err))
+			continue
+		}
+		if earliest == nil || cert.NotAfter.Before(earliest.NotAfter) {
+			earliest = cert
+		}
+		fields := []zap.Field{
+			zap.String("credential", name),
+			zap.String("subject", cert.Subject.String()),
+			zap.Time("not_after", cert.NotAfter),
+		}
+		switch remaining := cert.NotAfter.Sub(now); {
+		case remaining <= 0:
+			p.cfg.logger.Warn("Certificate in credential has expired", fields...)
+		case remaining <= p.cfg.certExpiryWindow:
+			p.cfg.logger.Warn("Certificate in credential expires soon", append(fields, zap.Duration("remaining", remaining.Round(time.Second)))...)
+		}
+	}
+	if earliest != nil && p.metrics != nil {
+		p.metrics.expiry.Record(ctx, earliest.NotAfter.Sub(now).Seconds(),
+			metric.WithAttributes(attribute.String("scheme", p.cfg.scheme), attribute.String("credential.name", name)))
+	}
+}
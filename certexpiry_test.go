@@ -0,0 +1,95 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// selfSignedCertificate returns a PEM-encoded self-signed certificate for commonName valid until notAfter.
+func selfSignedCertificate(t *testing.T, commonName string, notAfter time.Time) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCertificateExpiryWarning(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	credDir := t.TempDir()
+	valid := selfSignedCertificate(t, "valid", now.Add(90*24*time.Hour))
+	expiring := selfSignedCertificate(t, "expiring", now.Add(48*time.Hour))
+	expired := selfSignedCertificate(t, "expired", now.Add(-time.Hour))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "valid.crt"), valid, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "chain.crt"), append(append([]byte{}, valid...), expiring...), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "expired.crt"), expired, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	meter := &fakeMeter{measurements: map[string][]float64{}}
+	prov := NewFactory(
+		WithCredentialsDirectory(credDir),
+		WithCertificateExpiryWarning(7*24*time.Hour),
+		WithMeterProvider(fakeMeterProvider{meter: meter}),
+		WithLogger(zap.New(core)),
+	).Create(confmaptest.NewNopProviderSettings())
+	prov.(*provider).now = func() time.Time { return now }
+	for _, name := range []string{"valid.crt", "chain.crt", "expired.crt", "api_token"} {
+		_, err := prov.Retrieve(context.Background(), credSchemePrefix+name, nil)
+		require.NoError(t, err)
+	}
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "Certificate in credential expires soon", entries[0].Message)
+	assert.Equal(t, "chain.crt", entries[0].ContextMap()["credential"])
+	assert.Equal(t, "CN=expiring", entries[0].ContextMap()["subject"])
+	assert.Equal(t, "Certificate in credential has expired", entries[1].Message)
+	assert.Equal(t, "expired.crt", entries[1].ContextMap()["credential"])
+
+	m := meter.measurements
+	assert.Equal(t, []float64{(90 * 24 * time.Hour).Seconds()}, m["systemdcredential.certificate.time_to_expiry{credential.name=valid.crt,scheme=systemdcredential}"])
+	assert.Equal(t, []float64{(48 * time.Hour).Seconds()}, m["systemdcredential.certificate.time_to_expiry{credential.name=chain.crt,scheme=systemdcredential}"])
+	assert.Equal(t, []float64{-time.Hour.Seconds()}, m["systemdcredential.certificate.time_to_expiry{credential.name=expired.crt,scheme=systemdcredential}"])
+	assert.NotContains(t, m, "systemdcredential.certificate.time_to_expiry{credential.name=api_token,scheme=systemdcredential}")
+}
+
+func TestCertificateExpiryWarningDisabled(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "expired.crt"), selfSignedCertificate(t, "expired", time.Now().Add(-time.Hour)), 0600))
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithLogger(zap.New(core))).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"expired.crt", nil)
+	require.NoError(t, err)
+	assert.Zero(t, logs.Len())
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
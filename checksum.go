@@ -0,0 +1,72 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ChecksumPolicy controls how credentials are verified against their checksum sidecar, the
+// credential called NAME.sha256 holding the SHA-256 checksum of the credential called NAME.
+type ChecksumPolicy string
+
+const (
+	// ChecksumOff doesn't verify credentials.
+	ChecksumOff ChecksumPolicy = "off"
+	// ChecksumIfPresent verifies credentials that have a checksum sidecar.
+	ChecksumIfPresent ChecksumPolicy = "if-present"
+	// ChecksumRequired fails to read credentials without a checksum sidecar.
+	ChecksumRequired ChecksumPolicy = "required"
+)
+
+// checksumSuffix is appended to the name of a credential to get the name of its checksum sidecar.
+const checksumSuffix = ".sha256"
+
+// verifyChecksum verifies val, the content of the credential called name, against its checksum
+// sidecar, read with read, according to the configured ChecksumPolicy.
+func (p *provider) verifyChecksum(ctx context.Context, name string, val []byte, read sidecarReader) error {
+	sidecar := name + checksumSuffix
+	content, _, err := read(ctx, sidecar)
+	switch {
+	case err != nil && isMissing(err) && p.cfg.checksumPolicy == ChecksumIfPresent:
+		return nil
+	case err != nil && isMissing(err):
+		// The sidecar being missing must not make the credential itself count as missing.
+		return withKind(ErrChecksumMismatch, fmt.Errorf("checksum sidecar %q of credential %q doesn't exist", sidecar, name))
+	case err != nil:
+		return fmt.Errorf("failed to read checksum sidecar %q of credential %q: %w", sidecar, name, err)
+	}
+	want, err := parseChecksum(content)
+	if err != nil {
+		return fmt.Errorf("invalid checksum sidecar %q of credential %q: %w", sidecar, name, err)
+	}
+	got := sha256.Sum256(val)
+	if subtle.ConstantTimeCompare(got[:], want) != 1 {
+		return withKind(ErrChecksumMismatch, fmt.Errorf("credential %q doesn't match the checksum in %q", name, sidecar))
+	}
+	return nil
+}
+
+// parseChecksum parses a SHA-256 checksum, either on its own or in the format written by
+// `sha256sum`, i.e. followed by the name of the file.
+func parseChecksum(content []byte) ([]byte, error) {
+	fields := bytes.Fields(content)
+	if len(fields) == 0 {
+		return nil, errors.New("no checksum found")
+	}
+	sum := make([]byte, sha256.Size)
+	if len(fields[0]) != hex.EncodedLen(sha256.Size) {
+		return nil, fmt.Errorf("expected %d hexadecimal characters", hex.EncodedLen(sha256.Size))
+	}
+	if _, err := hex.Decode(sum, fields[0]); err != nil {
+		return nil, err
+	}
+	return sum, nil
+}
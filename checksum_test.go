@@ -0,0 +1,94 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestChecksumVerification(t *testing.T) {
+	sum := sha256.Sum256([]byte(testCredValue + "\n"))
+	credDir := t.TempDir()
+	for name, content := range map[string]string{
+		"api_token":        testCredValue + "\n",
+		"api_token.sha256": hex.EncodeToString(sum[:]) + "  api_token\n",
+		"bare":             testCredValue + "\n",
+		"bare.sha256":      hex.EncodeToString(sum[:]),
+		"corrupted":        testCredValue[:4],
+		"corrupted.sha256": hex.EncodeToString(sum[:]) + "\n",
+		"unverified":       testCredValue,
+		"invalid":          testCredValue,
+		"invalid.sha256":   "not a checksum\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(credDir, name), []byte(content), 0600))
+	}
+
+	tests := []struct {
+		name    string
+		policy  ChecksumPolicy
+		uri     string
+		want    string
+		wantErr string
+	}{
+		{name: "sha256sum format", policy: ChecksumIfPresent, uri: "api_token", want: testCredValue},
+		{name: "bare checksum", policy: ChecksumRequired, uri: "bare", want: testCredValue},
+		{name: "mismatch", policy: ChecksumIfPresent, uri: "corrupted", wantErr: `credential "corrupted" doesn't match the checksum in "corrupted.sha256"`},
+		{name: "no sidecar", policy: ChecksumIfPresent, uri: "unverified", want: testCredValue},
+		{name: "required sidecar", policy: ChecksumRequired, uri: "unverified", wantErr: `checksum sidecar "unverified.sha256" of credential "unverified" doesn't exist`},
+		{name: "required sidecar with default", policy: ChecksumRequired, uri: "unverified:-fallback", wantErr: "doesn't exist"},
+		{name: "invalid sidecar", policy: ChecksumIfPresent, uri: "invalid", wantErr: `invalid checksum sidecar "invalid.sha256"`},
+		{name: "off", policy: ChecksumOff, uri: "corrupted", want: testCredValue[:4]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prov := NewFactory(WithCredentialsDirectory(credDir), WithChecksumVerification(tt.policy)).Create(confmaptest.NewNopProviderSettings())
+			ret, err := prov.Retrieve(context.Background(), credSchemePrefix+tt.uri, nil)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				str, err := ret.AsString()
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, str)
+			}
+			assert.NoError(t, prov.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestChecksumMismatchError(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token.sha256"), []byte(hex.EncodeToString(make([]byte, sha256.Size))), 0600))
+
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithChecksumVerification(ChecksumIfPresent)).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.NotErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestParseChecksum(t *testing.T) {
+	sum := sha256.Sum256([]byte("value"))
+	encoded := hex.EncodeToString(sum[:])
+	for _, content := range []string{encoded, encoded + "\n", encoded + "  file\n", encoded + " *file\n"} {
+		got, err := parseChecksum([]byte(content))
+		require.NoError(t, err, content)
+		assert.Equal(t, sum[:], got)
+	}
+	for _, content := range []string{"", "\n", encoded[:10], encoded + "00", "zz" + encoded[2:]} {
+		_, err := parseChecksum([]byte(content))
+		assert.Error(t, err, content)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Command systemdcred-check verifies that every `${systemdcredential:...}` reference in
+// collector configuration files resolves, without printing any credential. It is meant to run
+// as ExecStartPre= of the collector service, so that a missing or unreadable credential fails
+// the start with a full report instead of a collector that exits on the first error:
+//
+//	[Service]
+//	LoadCredential=api_token:/etc/otel/api_token
+//	ExecStartPre=/usr/bin/systemdcred-check -unit %n /etc/otel/config.yaml
+//	ExecStart=/usr/bin/otelcol --config /etc/otel/config.yaml
+//
+// Credentials are read from $CREDENTIALS_DIRECTORY, or the directory set with -directory.
+// With -unit, the credential settings of the unit are looked up over D-Bus as well, and every
+// referenced credential the unit doesn't pass is reported.
+//
+// The exit status is 0 when every reference resolves, 1 when some don't and 2 on usage errors.
+package main // import "bou.ke/systemdcredentialprovider/cmd/systemdcred-check"
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.uber.org/zap"
+
+	"bou.ke/systemdcredentialprovider"
+)
+
+// lookupUnitCredentials is replaced in tests, which can't rely on a systemd manager.
+var lookupUnitCredentials = systemdcredentialprovider.LookupUnitCredentials
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("systemdcred-check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: systemdcred-check [-directory dir] [-unit unit] config.yaml...")
+		flags.PrintDefaults()
+	}
+	directory := flags.String("directory", "", "credentials directory, instead of $CREDENTIALS_DIRECTORY")
+	unit := flags.String("unit", "", "also check that the credentials are passed by this systemd `unit`")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	var opts []systemdcredentialprovider.Option
+	if *directory != "" {
+		opts = append(opts, systemdcredentialprovider.WithCredentialsDirectory(*directory))
+	}
+	prov := systemdcredentialprovider.NewFactory(opts...).Create(confmap.ProviderSettings{Logger: zap.NewNop()})
+	defer prov.Shutdown(ctx)
+
+	var unitCreds *systemdcredentialprovider.UnitCredentials
+	if *unit != "" {
+		var err error
+		if unitCreds, err = lookupUnitCredentials(ctx, *unit); err != nil {
+			fmt.Fprintf(stderr, "systemdcred-check: %v\n", err)
+			return 1
+		}
+	}
+
+	var checked, failed int
+	for _, path := range flags.Args() {
+		conf, err := readConfig(path)
+		if err != nil {
+			fmt.Fprintf(stdout, "FAIL %s: %v\n", path, err)
+			failed++
+			continue
+		}
+		uris, err := systemdcredentialprovider.CredentialReferenceURIs(conf)
+		if err != nil {
+			fmt.Fprintf(stdout, "FAIL %s: %v\n", path, err)
+			failed++
+			continue
+		}
+		if unitCreds != nil {
+			names, _ := systemdcredentialprovider.CredentialReferences(conf)
+			for _, name := range names {
+				if !unitCreds.Provides(name) {
+					fmt.Fprintf(stdout, "FAIL %s: credential %q is not passed by unit %q\n", path, name, unitCreds.Unit)
+					failed++
+				}
+			}
+		}
+		for _, uri := range uris {
+			checked++
+			if err := check(ctx, prov, uri); err != nil {
+				fmt.Fprintf(stdout, "FAIL %s: ${%s}: %v\n", path, uri, err)
+				failed++
+				continue
+			}
+			fmt.Fprintf(stdout, "ok   %s: ${%s}\n", path, uri)
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(stdout, "%d credential references checked, %d problems found\n", checked, failed)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%d credential references checked, all resolve\n", checked)
+	return 0
+}
+
+// readConfig reads the collector configuration file at path.
+func readConfig(path string) (any, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := confmap.NewRetrievedFromYAML(content)
+	if err != nil {
+		return nil, err
+	}
+	return ret.AsRaw()
+}
+
+// check retrieves uri and discards the value.
+func check(ctx context.Context, prov confmap.Provider, uri string) error {
+	ret, err := prov.Retrieve(ctx, uri, nil)
+	if err != nil {
+		return err
+	}
+	return ret.Close(ctx)
+}
@@ -0,0 +1,112 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bou.ke/systemdcredentialprovider"
+)
+
+const testConfig = `
+exporters:
+  otlphttp:
+    endpoint: ${systemdcredential:endpoint?trim=all-whitespace}
+    headers:
+      Authorization: Bearer ${systemdcredential:api_token}
+      X-Tenant: ${systemdcredential:tenant:-default}
+      X-Region: ${env:REGION}
+`
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+}
+
+func TestRun(t *testing.T) {
+	credDir := t.TempDir()
+	writeFiles(t, credDir, map[string]string{"endpoint": "https://otlp.example.com\n", "api_token": "s3cr3t"})
+	configDir := t.TempDir()
+	writeFiles(t, configDir, map[string]string{"config.yaml": testConfig})
+	config := filepath.Join(configDir, "config.yaml")
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"-directory", credDir, config}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "ok   "+config+": ${systemdcredential:endpoint?trim=all-whitespace}\n"+
+		"ok   "+config+": ${systemdcredential:api_token}\n"+
+		"ok   "+config+": ${systemdcredential:tenant:-default}\n"+
+		"3 credential references checked, all resolve\n", stdout.String())
+	assert.NotContains(t, stdout.String(), "s3cr3t")
+}
+
+func TestRunFailures(t *testing.T) {
+	credDir := t.TempDir()
+	writeFiles(t, credDir, map[string]string{"endpoint": "https://otlp.example.com\n"})
+	configDir := t.TempDir()
+	writeFiles(t, configDir, map[string]string{
+		"config.yaml":  testConfig,
+		"invalid.yaml": "key: ${systemdcredential:a/b}\n",
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{
+		"-directory", credDir,
+		filepath.Join(configDir, "config.yaml"),
+		filepath.Join(configDir, "invalid.yaml"),
+		filepath.Join(configDir, "missing.yaml"),
+	}, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	out := stdout.String()
+	assert.Contains(t, out, "FAIL "+filepath.Join(configDir, "config.yaml")+": ${systemdcredential:api_token}: ")
+	assert.Contains(t, out, "ok   "+filepath.Join(configDir, "config.yaml")+": ${systemdcredential:endpoint?trim=all-whitespace}\n")
+	assert.Contains(t, out, "FAIL "+filepath.Join(configDir, "invalid.yaml")+": ${systemdcredential:a/b}: ")
+	assert.Contains(t, out, "FAIL "+filepath.Join(configDir, "missing.yaml")+": ")
+	assert.Contains(t, out, "4 credential references checked, 3 problems found\n")
+}
+
+func TestRunUnit(t *testing.T) {
+	credDir := t.TempDir()
+	writeFiles(t, credDir, map[string]string{"endpoint": "https://otlp.example.com\n", "api_token": "s3cr3t"})
+	configDir := t.TempDir()
+	writeFiles(t, configDir, map[string]string{"config.yaml": testConfig})
+	config := filepath.Join(configDir, "config.yaml")
+
+	lookupUnitCredentials = func(_ context.Context, unit string) (*systemdcredentialprovider.UnitCredentials, error) {
+		if unit != "otelcol.service" {
+			return nil, errors.New("unit not found")
+		}
+		return &systemdcredentialprovider.UnitCredentials{Unit: unit, Names: []string{"endpoint"}, ImportPatterns: []string{"tenant*"}}, nil
+	}
+	t.Cleanup(func() { lookupUnitCredentials = systemdcredentialprovider.LookupUnitCredentials })
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"-directory", credDir, "-unit", "otelcol.service", config}, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), "FAIL "+config+`: credential "api_token" is not passed by unit "otelcol.service"`)
+	assert.NotContains(t, stdout.String(), `credential "endpoint" is not passed`)
+	assert.NotContains(t, stdout.String(), `credential "tenant" is not passed`)
+
+	stdout.Reset()
+	code = run(context.Background(), []string{"-directory", credDir, "-unit", "other.service", config}, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "unit not found")
+}
+
+func TestRunUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, run(context.Background(), nil, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "usage: systemdcred-check")
+	assert.Equal(t, 2, run(context.Background(), []string{"-unknown"}, &stdout, &stderr))
+}
@@ -0,0 +1,289 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Command systemdcred-dev runs a command, such as a collector, with credentials like systemd
+// passes them to a service, for local development:
+//
+//	systemdcred-dev -f secrets.env -- otelcol --config config.yaml
+//
+// The credentials are written to a new private directory, $CREDENTIALS_DIRECTORY of the command
+// points at it and it is removed once the command exits. The exit status is the one of the
+// command, or 2 when it couldn't be started.
+//
+// Credentials are read from files given with -f: YAML or JSON files (ending in .yaml, .yml or
+// .json) holding a mapping of credential names to values, or env files in the format of
+// EnvironmentFile=, with a variable per credential. With -config, every credential referenced
+// by the collector configuration that isn't set by a file is prompted for.
+//
+// With -encrypt, the credentials are encrypted with `systemd-creds encrypt`, like the ones stored
+// for LoadCredentialEncrypted=, for configurations that decrypt them with `encrypted=true`.
+package main // import "bou.ke/systemdcredentialprovider/cmd/systemdcred-dev"
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"go.opentelemetry.io/collector/confmap"
+
+	"bou.ke/systemdcredentialprovider"
+	"bou.ke/systemdcredentialprovider/creds"
+	"bou.ke/systemdcredentialprovider/internal/envfile"
+)
+
+// setEcho turns echoing of the terminal on stdin on or off while values are prompted for. It is
+// replaced in tests.
+var setEcho = func(on bool) {
+	arg := "-echo"
+	if on {
+		arg = "echo"
+	}
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = os.Stdin
+	// Fails when stdin isn't a terminal, in which case there is nothing to hide.
+	_ = cmd.Run()
+}
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("systemdcred-dev", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: systemdcred-dev [-f file]... [-config config.yaml]... [-encrypt] -- command [arg...]")
+		flags.PrintDefaults()
+	}
+	var files, configs []string
+	flags.Func("f", "read credentials from `file`, YAML, JSON or an env file; can be repeated", func(s string) error {
+		files = append(files, s)
+		return nil
+	})
+	flags.Func("config", "prompt for the credentials referenced by the collector `config` that aren't set; can be repeated", func(s string) error {
+		configs = append(configs, s)
+		return nil
+	})
+	encrypt := flags.Bool("encrypt", false, "encrypt the credentials with systemd-creds")
+	systemdCreds := flags.String("systemd-creds", "systemd-creds", "the systemd-creds `command` used with -encrypt")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	values := map[string][]byte{}
+	for _, file := range files {
+		if err := readValues(file, values); err != nil {
+			fmt.Fprintf(stderr, "systemdcred-dev: %v\n", err)
+			return 2
+		}
+	}
+	if err := promptMissing(configs, values, stdin, stderr); err != nil {
+		fmt.Fprintf(stderr, "systemdcred-dev: %v\n", err)
+		return 2
+	}
+
+	dir, err := writeCredentials(ctx, values, *encrypt, *systemdCreds)
+	if err != nil {
+		fmt.Fprintf(stderr, "systemdcred-dev: %v\n", err)
+		return 2
+	}
+	defer removeCredentials(dir)
+
+	cmd := exec.Command(flags.Arg(0), flags.Args()[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, stdout, stderr
+	cmd.Env = append(removeEnv(os.Environ(), creds.DirectoryEnv), creds.DirectoryEnv+"="+dir)
+	return runCommand(cmd, stderr)
+}
+
+// readValues adds the credentials in file to values.
+func readValues(file string, values map[string][]byte) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	switch filepath.Ext(file) {
+	case ".yaml", ".yml", ".json":
+		ret, err := confmap.NewRetrievedFromYAML(content)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		raw, err := ret.AsRaw()
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		mapping, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s must hold a mapping of credential names to values", file)
+		}
+		for name, val := range mapping {
+			if !creds.ValidName(name) {
+				return fmt.Errorf("invalid credential name %q in %s", name, file)
+			}
+			switch val.(type) {
+			case map[string]any, []any:
+				return fmt.Errorf("value of credential %q in %s must be a scalar", name, file)
+			case nil:
+				values[name] = nil
+			default:
+				values[name] = fmt.Append(nil, val)
+			}
+		}
+	default:
+		for name, val := range envfile.Parse(string(content)) {
+			values[name] = []byte(val)
+		}
+	}
+	return nil
+}
+
+// promptMissing prompts for the credentials referenced by configs that aren't in values.
+func promptMissing(configs []string, values map[string][]byte, stdin io.Reader, stderr io.Writer) error {
+	var missing []string
+	for _, config := range configs {
+		content, err := os.ReadFile(config)
+		if err != nil {
+			return err
+		}
+		ret, err := confmap.NewRetrievedFromYAML(content)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", config, err)
+		}
+		raw, err := ret.AsRaw()
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", config, err)
+		}
+		names, err := systemdcredentialprovider.CredentialReferences(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", config, err)
+		}
+		for _, name := range names {
+			if _, ok := values[name]; !ok && !slices.Contains(missing, name) {
+				missing = append(missing, name)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	setEcho(false)
+	defer setEcho(true)
+	reader := bufio.NewReader(stdin)
+	for _, name := range missing {
+		fmt.Fprintf(stderr, "Value of credential %q: ", name)
+		line, err := reader.ReadString('\n')
+		fmt.Fprintln(stderr)
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			return fmt.Errorf("failed to read value of credential %q: %w", name, err)
+		}
+		values[name] = []byte(strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+	}
+	return nil
+}
+
+// writeCredentials writes values to a new private directory and returns it. The directory is
+// created in $XDG_RUNTIME_DIR when set, which is a tmpfs, so that the credentials don't reach a disk.
+func writeCredentials(ctx context.Context, values map[string][]byte, encrypt bool, systemdCreds string) (string, error) {
+	dir, err := os.MkdirTemp(os.Getenv("XDG_RUNTIME_DIR"), "credentials-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+	for name, val := range values {
+		if encrypt {
+			if val, err = encryptCredential(ctx, systemdCreds, name, val); err != nil {
+				removeCredentials(dir)
+				return "", err
+			}
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), val, 0400); err != nil {
+			removeCredentials(dir)
+			return "", fmt.Errorf("failed to write credential %q: %w", name, err)
+		}
+	}
+	// Like systemd, make the directory read-only once it is populated.
+	if err := os.Chmod(dir, 0500); err != nil {
+		removeCredentials(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// encryptCredential encrypts val, the value of the credential called name, with systemd-creds.
+func encryptCredential(ctx context.Context, systemdCreds, name string, val []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, systemdCreds, "encrypt", "--name="+name, "-", "-")
+	cmd.Stdin = bytes.NewReader(val)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt credential %q: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// removeCredentials removes the credentials directory dir.
+func removeCredentials(dir string) {
+	_ = os.Chmod(dir, 0700)
+	_ = os.RemoveAll(dir)
+}
+
+// runCommand runs cmd, forwarding SIGINT and SIGTERM to it, and returns its exit status.
+func runCommand(cmd *exec.Cmd, stderr io.Writer) int {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(stderr, "systemdcred-dev: %v\n", err)
+		return 2
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				_ = cmd.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var exitErr *exec.ExitError
+	if err := cmd.Wait(); errors.As(err, &exitErr) {
+		if exitErr.ExitCode() < 0 {
+			// Killed by a signal.
+			return 1
+		}
+		return exitErr.ExitCode()
+	} else if err != nil {
+		fmt.Fprintf(stderr, "systemdcred-dev: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// removeEnv returns env without the assignments to the variable name.
+func removeEnv(env []string, name string) []string {
+	out := env[:0:0]
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, name+"=") {
+			out = append(out, kv)
+		}
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Command systemdcred-gen generates a systemd drop-in that passes every credential referenced
+// with `${systemdcredential:...}` in collector configuration files to the collector service,
+// keeping the unit in sync with the configuration:
+//
+//	systemdcred-gen -o /etc/systemd/system/otelcol.service.d/credentials.conf /etc/otel/config.yaml
+//
+// By default a LoadCredential= line without a path is generated for every credential, which
+// makes systemd look for it in the credential stores, such as /etc/credstore. With -encrypted,
+// LoadCredentialEncrypted= is used instead, looking in /etc/credstore.encrypted. With -source,
+// the credentials are loaded from files in that directory.
+package main // import "bou.ke/systemdcredentialprovider/cmd/systemdcred-gen"
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+
+	"bou.ke/systemdcredentialprovider"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("systemdcred-gen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: systemdcred-gen [-encrypted] [-source dir] [-o file] config.yaml...")
+		flags.PrintDefaults()
+	}
+	encrypted := flags.Bool("encrypted", false, "generate LoadCredentialEncrypted= instead of LoadCredential= lines")
+	source := flags.String("source", "", "load the credentials from files in `dir` instead of the credential stores")
+	output := flags.String("o", "", "write the drop-in to `file` instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	var names []string
+	for _, path := range flags.Args() {
+		conf, err := readConfig(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "systemdcred-gen: %v\n", err)
+			return 1
+		}
+		refs, err := systemdcredentialprovider.CredentialReferences(conf)
+		if err != nil {
+			fmt.Fprintf(stderr, "systemdcred-gen: %s: %v\n", path, err)
+			return 1
+		}
+		for _, name := range refs {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+
+	dropIn := generate(flags.Args(), names, *encrypted, *source)
+	if *output == "" {
+		_, _ = stdout.Write(dropIn)
+		return 0
+	}
+	if err := os.WriteFile(*output, dropIn, 0644); err != nil {
+		fmt.Fprintf(stderr, "systemdcred-gen: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// generate returns a drop-in passing the credentials called names, referenced in configs.
+func generate(configs, names []string, encrypted bool, source string) []byte {
+	setting := "LoadCredential"
+	if encrypted {
+		setting = "LoadCredentialEncrypted"
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Generated by systemdcred-gen from %s, do not edit.\n", strings.Join(configs, ", "))
+	b.WriteString("[Service]\n")
+	for _, name := range names {
+		if source == "" {
+			fmt.Fprintf(&b, "%s=%s\n", setting, name)
+			continue
+		}
+		fmt.Fprintf(&b, "%s=%s:%s\n", setting, name, filepath.Join(source, name))
+	}
+	return b.Bytes()
+}
+
+// readConfig reads the collector configuration file at path.
+func readConfig(path string) (any, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := confmap.NewRetrievedFromYAML(content)
+	if err != nil {
+		return nil, err
+	}
+	return ret.AsRaw()
+}
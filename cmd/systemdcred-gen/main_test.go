@@ -0,0 +1,103 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+exporters:
+  otlphttp:
+    endpoint: ${systemdcredential:endpoint?trim=all-whitespace}
+    headers:
+      Authorization: Bearer ${systemdcredential:api_token}
+      X-Tenant: ${env:TENANT|systemdcredential:tenant}
+receivers:
+  otlp:
+    protocols:
+      grpc:
+        tls:
+          key_file: ${systemdcredential:tls.key}
+`
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestRun(t *testing.T) {
+	config := writeConfig(t, "config.yaml", testConfig)
+	other := writeConfig(t, "other.yaml", "extensions:\n  auth:\n    token: ${systemdcredential:api_token} ${systemdcredential:admin_token}\n")
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "credstore",
+			args: []string{config, other},
+			want: "# Generated by systemdcred-gen from " + config + ", " + other + ", do not edit.\n" +
+				"[Service]\n" +
+				"LoadCredential=admin_token\n" +
+				"LoadCredential=api_token\n" +
+				"LoadCredential=endpoint\n" +
+				"LoadCredential=tenant\n" +
+				"LoadCredential=tls.key\n",
+		},
+		{
+			name: "encrypted with source",
+			args: []string{"-encrypted", "-source", "/etc/otel/creds", config},
+			want: "# Generated by systemdcred-gen from " + config + ", do not edit.\n" +
+				"[Service]\n" +
+				"LoadCredentialEncrypted=api_token:/etc/otel/creds/api_token\n" +
+				"LoadCredentialEncrypted=endpoint:/etc/otel/creds/endpoint\n" +
+				"LoadCredentialEncrypted=tenant:/etc/otel/creds/tenant\n" +
+				"LoadCredentialEncrypted=tls.key:/etc/otel/creds/tls.key\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			require.Equal(t, 0, run(tt.args, &stdout, &stderr), stderr.String())
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+}
+
+func TestRunOutput(t *testing.T) {
+	config := writeConfig(t, "config.yaml", testConfig)
+	output := filepath.Join(t.TempDir(), "credentials.conf")
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"-o", output, config}, &stdout, &stderr), stderr.String())
+	assert.Empty(t, stdout.String())
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "[Service]\nLoadCredential=api_token\n")
+}
+
+func TestRunErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, run(nil, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "usage: systemdcred-gen")
+
+	stderr.Reset()
+	assert.Equal(t, 1, run([]string{filepath.Join(t.TempDir(), "missing.yaml")}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "no such file or directory")
+
+	stderr.Reset()
+	invalid := writeConfig(t, "invalid.yaml", "key: ${systemdcredential:token?unknown=1}\n")
+	assert.Equal(t, 1, run([]string{invalid}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "invalid reference")
+	assert.Empty(t, stdout.String())
+}
@@ -0,0 +1,319 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Command systemdcred-migrate moves secrets written inline in a collector configuration into
+// credentials:
+//
+//	systemdcred-migrate -o config.new.yaml -secrets ./secrets config.yaml > encrypt.sh
+//
+// Values that look like secrets, those of keys such as `password`, `token`, `api_key` or
+// `authorization` and PEM-encoded private keys, are replaced with `${systemdcredential:NAME}`
+// references, where NAME is derived from the path of the value in the configuration. The
+// scheme of authorization headers such as `Bearer` is kept in the configuration.
+//
+// Every extracted value is written to a file in the -secrets directory, and the commands
+// encrypting them with `systemd-creds encrypt` into /etc/credstore.encrypted are printed, for
+// LoadCredentialEncrypted=. With -set-credential, a drop-in with SetCredential= lines is
+// printed instead. The files with the extracted values should be removed once the credentials
+// are set up.
+//
+// Escaped dollar signs, `$$`, are written to the credentials as a single '$'. Numbers and booleans
+// are replaced with `${systemdcredential:NAME?parse=yaml}` references, so that they keep their type;
+// secrets of other types, such as timestamps or binary values, are reported and left as is.
+//
+// The rewritten configuration keeps comments, but its formatting may change. Values containing
+// references already are left as is, review the result for secrets that weren't recognized.
+package main // import "bou.ke/systemdcredentialprovider/cmd/systemdcred-migrate"
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+
+	"bou.ke/systemdcredentialprovider/creds"
+)
+
+const (
+	// encryptedCredstore is where systemd looks for credentials for LoadCredentialEncrypted=.
+	encryptedCredstore = "/etc/credstore.encrypted"
+	// maxNameLength is the maximum length of a credential name.
+	maxNameLength = 255
+)
+
+// secretKeys are the parts of keys, lowercase and without '_' and '-', whose values are secrets.
+var secretKeys = []string{"password", "passwd", "secret", "token", "apikey", "accesskey", "privatekey", "authorization", "credential"}
+
+// nonSecretSuffixes are the ends of keys, lowercase and without '_' and '-', whose values refer
+// to a secret rather than holding it, such as `token_file` or `token_url`.
+var nonSecretSuffixes = []string{"file", "path", "url", "endpoint", "name", "type"}
+
+// authorizationScheme matches authorization header values, the scheme being kept in the configuration.
+var authorizationScheme = regexp.MustCompile(`^((?i:bearer|basic|token|apikey|splunk)\s+)(\S+)$`)
+
+// secret is a value extracted from the configuration.
+type secret struct {
+	name  string
+	value string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("systemdcred-migrate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: systemdcred-migrate -o output.yaml -secrets dir [-set-credential] config.yaml")
+		flags.PrintDefaults()
+	}
+	output := flags.String("o", "", "write the rewritten configuration to `file`")
+	secretsDir := flags.String("secrets", "", "write the extracted values to files in `dir`")
+	setCredential := flags.Bool("set-credential", false, "print a drop-in with SetCredential= lines instead of systemd-creds encrypt commands")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || *output == "" || *secretsDir == "" {
+		flags.Usage()
+		return 2
+	}
+
+	content, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "systemdcred-migrate: %v\n", err)
+		return 1
+	}
+	rewritten, secrets, skipped, err := migrate(content)
+	if err != nil {
+		fmt.Fprintf(stderr, "systemdcred-migrate: %s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+	for _, path := range skipped {
+		fmt.Fprintf(stderr, "systemdcred-migrate: %s: skipped %s, its type is not supported\n", flags.Arg(0), path)
+	}
+	if len(secrets) == 0 {
+		fmt.Fprintf(stderr, "systemdcred-migrate: no inline secrets found in %s\n", flags.Arg(0))
+		return 0
+	}
+
+	if err := os.MkdirAll(*secretsDir, 0700); err != nil {
+		fmt.Fprintf(stderr, "systemdcred-migrate: %v\n", err)
+		return 1
+	}
+	for _, s := range secrets {
+		if err := os.WriteFile(filepath.Join(*secretsDir, s.name), []byte(s.value), 0600); err != nil {
+			fmt.Fprintf(stderr, "systemdcred-migrate: %v\n", err)
+			return 1
+		}
+	}
+	if err := os.WriteFile(*output, rewritten, 0600); err != nil {
+		fmt.Fprintf(stderr, "systemdcred-migrate: %v\n", err)
+		return 1
+	}
+	if *setCredential {
+		_, _ = stdout.Write(setCredentialDropIn(secrets))
+	} else {
+		_, _ = stdout.Write(encryptCommands(secrets, *secretsDir))
+	}
+	fmt.Fprintf(stderr, "systemdcred-migrate: moved %d secrets out of %s\n", len(secrets), flags.Arg(0))
+	return 0
+}
+
+// migrate replaces the secrets in the configuration content with credential references, and
+// returns the rewritten configuration, the extracted secrets and the paths of the secrets that
+// were skipped because of their type.
+func migrate(content []byte) ([]byte, []secret, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, nil, nil, err
+	}
+	var secrets []secret
+	var skipped []string
+	taken := map[string]bool{}
+	var walk func(node *yaml.Node, path []string, key string)
+	walk = func(node *yaml.Node, path []string, key string) {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, child := range node.Content {
+				walk(child, path, key)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				k := node.Content[i].Value
+				walk(node.Content[i+1], append(path[:len(path):len(path)], k), k)
+			}
+		case yaml.SequenceNode:
+			for i, child := range node.Content {
+				walk(child, append(path[:len(path):len(path)], strconv.Itoa(i)), key)
+			}
+		case yaml.ScalarNode:
+			switch node.Tag {
+			case "!!str":
+				value, references := unescapeDollarSigns(node.Value)
+				if value == "" || references || !secretKey(key) && !privateKey(value) {
+					return
+				}
+				prefix := ""
+				if m := authorizationScheme.FindStringSubmatch(value); m != nil {
+					prefix, value = m[1], m[2]
+				}
+				name := credentialName(path, taken)
+				secrets = append(secrets, secret{name: name, value: value})
+				node.Value = prefix + "${systemdcredential:" + name + "}"
+			case "!!int", "!!float", "!!bool":
+				if !secretKey(key) {
+					return
+				}
+				name := credentialName(path, taken)
+				secrets = append(secrets, secret{name: name, value: node.Value})
+				node.Value = "${systemdcredential:" + name + "?parse=yaml}"
+				node.Tag = "!!str"
+			case "!!null":
+				return
+			default:
+				if secretKey(key) {
+					skipped = append(skipped, strings.Join(path, "."))
+				}
+				return
+			}
+			node.Style = 0
+		}
+	}
+	walk(&doc, nil, "")
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, nil, nil, err
+	}
+	return b.Bytes(), secrets, skipped, nil
+}
+
+// unescapeDollarSigns replaces the escaped dollar signs, `$$`, in value with '$', and reports
+// whether value contains `${...}` references, like the collector resolves them.
+func unescapeDollarSigns(value string) (string, bool) {
+	if !strings.Contains(value, "$") {
+		return value, false
+	}
+	var b strings.Builder
+	references := false
+	for i := 0; i < len(value); i++ {
+		if value[i] == '$' && i+1 < len(value) {
+			switch value[i+1] {
+			case '$':
+				i++
+			case '{':
+				references = true
+			}
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String(), references
+}
+
+// secretKey reports whether the value of key is likely a secret.
+func secretKey(key string) bool {
+	key = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	for _, suffix := range nonSecretSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return false
+		}
+	}
+	for _, part := range secretKeys {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// privateKey reports whether value is a PEM-encoded private key.
+func privateKey(value string) bool {
+	return strings.Contains(value, "-----BEGIN ") && strings.Contains(value, "PRIVATE KEY-----")
+}
+
+// credentialName returns a credential name for the value at path that isn't taken yet, and takes it.
+func credentialName(path []string, taken map[string]bool) string {
+	parts := make([]string, len(path))
+	for i, part := range path {
+		parts[i] = strings.Map(func(r rune) rune {
+			if r <= ' ' || r > '~' || r == '/' || r == ':' {
+				return '_'
+			}
+			return r
+		}, part)
+	}
+	base := strings.Join(parts, ".")
+	if len(base) > maxNameLength-4 {
+		base = base[len(base)-(maxNameLength-4):]
+	}
+	name := base
+	for i := 2; taken[name] || !creds.ValidName(name); i++ {
+		name = base + "-" + strconv.Itoa(i)
+	}
+	taken[name] = true
+	return name
+}
+
+// encryptCommands returns a script encrypting the secrets written to dir with systemd-creds.
+func encryptCommands(secrets []secret, dir string) []byte {
+	var b bytes.Buffer
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# Encrypts the extracted credentials for LoadCredentialEncrypted=, see systemdcred-gen -encrypted.\n")
+	b.WriteString("set -e\n")
+	fmt.Fprintf(&b, "mkdir -p %s\n", encryptedCredstore)
+	for _, s := range secrets {
+		fmt.Fprintf(&b, "systemd-creds encrypt --name=%s %s %s\n",
+			shellQuote(s.name), shellQuote(filepath.Join(dir, s.name)), shellQuote(encryptedCredstore+"/"+s.name))
+	}
+	return b.Bytes()
+}
+
+// setCredentialDropIn returns a drop-in passing the secrets with SetCredential=.
+func setCredentialDropIn(secrets []secret) []byte {
+	var b bytes.Buffer
+	b.WriteString("# Passes the extracted credentials in plain text, keep this drop-in readable by root only.\n")
+	b.WriteString("[Service]\n")
+	for _, s := range secrets {
+		fmt.Fprintf(&b, "SetCredential=%s:%s\n", s.name, escapeSetting(s.value))
+	}
+	return b.Bytes()
+}
+
+// escapeSetting escapes value for a unit setting: C-style escapes are resolved in
+// SetCredential=, and '%' starts a specifier.
+func escapeSetting(value string) string {
+	var b strings.Builder
+	for _, c := range []byte(value) {
+		switch {
+		case c == '\\':
+			b.WriteString(`\\`)
+		case c == '%':
+			b.WriteString("%%")
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c < ' ' || c == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
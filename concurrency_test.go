@@ -0,0 +1,161 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+const concurrentCredentials = 8
+
+func writeConcurrentCredentials(tb testing.TB) string {
+	credDir := tb.TempDir()
+	for i := range concurrentCredentials {
+		require.NoError(tb, os.WriteFile(filepath.Join(credDir, fmt.Sprintf("cred%d", i)), []byte(fmt.Sprintf("value%d\n", i)), 0600))
+	}
+	return credDir
+}
+
+// TestConcurrentRetrieve retrieves credentials from many goroutines with every stateful feature
+// enabled, closing values as the resolver does on reload. It is meant to be run with -race.
+func TestConcurrentRetrieve(t *testing.T) {
+	credDir := writeConcurrentCredentials(t)
+	meter := &fakeMeter{measurements: map[string][]float64{}}
+	var hookCalls atomic.Int64
+	prov := NewFactory(
+		WithCredentialsDirectory(credDir),
+		WithCache(time.Minute),
+		WithAccessLog(NewAccessLog()),
+		WithValidator(NewValidator()),
+		WithRedactor(NewRedactor()),
+		WithMeterProvider(fakeMeterProvider{meter: meter}),
+		WithRetrieveHook(func(RetrieveEvent) { hookCalls.Add(1) }),
+	).Create(confmaptest.NewNopProviderSettings())
+
+	const goroutines, iterations = 16, 50
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range iterations {
+				n := (g + i) % concurrentCredentials
+				uri := fmt.Sprintf("%scred%d", credSchemePrefix, n)
+				if i%5 == 0 {
+					uri += "+cred0|" + credSchemePrefix + "missing"
+				}
+				ret, err := prov.Retrieve(context.Background(), uri, nil)
+				if !assert.NoError(t, err) {
+					return
+				}
+				str, err := ret.AsString()
+				assert.NoError(t, err)
+				assert.Contains(t, str, fmt.Sprintf("value%d", n))
+				if i%3 == 0 {
+					assert.NoError(t, ret.Close(context.Background()))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(goroutines*iterations), hookCalls.Load())
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+// TestConcurrentRetrievePreloaded races retrievals from a preloaded snapshot against Shutdown.
+func TestConcurrentRetrievePreloaded(t *testing.T) {
+	credDir := writeConcurrentCredentials(t)
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithPreload(true)).Create(confmaptest.NewNopProviderSettings())
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				// Retrievals fail once the snapshot is wiped, but must never return wiped contents.
+				ret, err := prov.Retrieve(context.Background(), fmt.Sprintf("%scred%d", credSchemePrefix, (g+i)%concurrentCredentials), nil)
+				if err != nil {
+					continue
+				}
+				str, err := ret.AsString()
+				assert.NoError(t, err)
+				assert.Regexp(t, `^value\d$`, str)
+			}
+		}()
+	}
+	assert.NoError(t, prov.Shutdown(context.Background()))
+	wg.Wait()
+}
+
+// referencesPerCredential is how often a benchmarked configuration references every credential.
+const referencesPerCredential = 4
+
+// benchmarkResolve benchmarks resolving a configuration that references every credential
+// several times, closing the retrieved values afterwards as the resolver does on reload.
+func benchmarkResolve(b *testing.B, opts ...Option) {
+	credDir := writeConcurrentCredentials(b)
+	prov := NewFactory(append([]Option{WithCredentialsDirectory(credDir)}, opts...)...).Create(confmaptest.NewNopProviderSettings())
+	var uris []string
+	for range referencesPerCredential {
+		for i := range concurrentCredentials {
+			uris = append(uris, fmt.Sprintf("%scred%d", credSchemePrefix, i))
+		}
+	}
+	resolve := func() {
+		rets := make([]*confmap.Retrieved, 0, len(uris))
+		for _, uri := range uris {
+			ret, err := prov.Retrieve(context.Background(), uri, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			rets = append(rets, ret)
+		}
+		for _, ret := range rets {
+			if err := ret.Close(context.Background()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.Run("serial", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			resolve()
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				resolve()
+			}
+		})
+	})
+	require.NoError(b, prov.Shutdown(context.Background()))
+}
+
+func BenchmarkResolve(b *testing.B) {
+	benchmarkResolve(b)
+}
+
+func BenchmarkResolveCached(b *testing.B) {
+	benchmarkResolve(b, WithCache(time.Minute))
+}
+
+func BenchmarkResolvePreloaded(b *testing.B) {
+	benchmarkResolve(b, WithPreload(true))
+}
@@ -0,0 +1,56 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// containerEnv is set by container managers to their name, see
+// https://systemd.io/CONTAINER_INTERFACE/.
+const containerEnv = "container"
+
+// containerCredentialsDirectories maps container managers, as named in $container, to the
+// directories credentials show up in inside their containers, relative to the root.
+var containerCredentialsDirectories = map[string][]string{
+	// systemd-nspawn passes the credentials set with --set-credential= and --load-credential=.
+	"systemd-nspawn": {"run/host/credentials"},
+	// Podman, including quadlets with Secret=, and Docker mount secrets here.
+	"podman": {"run/secrets"},
+	"docker": {"run/secrets"},
+}
+
+// detectContainer returns the name of the container manager the process runs under, or an
+// empty string if it doesn't run in a container.
+func (p *provider) detectContainer() string {
+	if name := os.Getenv(containerEnv); name != "" {
+		return name
+	}
+	// $container is only guaranteed to be set for the init process of the container.
+	if name, err := os.ReadFile(filepath.Join(p.cfg.containerRoot, "run/host/container-manager")); err == nil {
+		return strings.TrimSpace(string(name))
+	}
+	if _, err := os.Stat(filepath.Join(p.cfg.containerRoot, "run/.containerenv")); err == nil {
+		return "podman"
+	}
+	if _, err := os.Stat(filepath.Join(p.cfg.containerRoot, ".dockerenv")); err == nil {
+		return "docker"
+	}
+	return ""
+}
+
+// discoverContainerCredentialsDirectory locates the directory the container manager the
+// process runs under places credentials in, see WithContainerDetection.
+func (p *provider) discoverContainerCredentialsDirectory() (string, string, bool) {
+	manager := p.detectContainer()
+	for _, dir := range containerCredentialsDirectories[manager] {
+		credDir := filepath.Join(p.cfg.containerRoot, dir)
+		if info, err := os.Stat(credDir); err == nil && info.IsDir() {
+			return credDir, manager, true
+		}
+	}
+	return "", manager, false
+}
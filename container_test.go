@@ -0,0 +1,98 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestContainerDetection(t *testing.T) {
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+	require.NoError(t, os.Unsetenv("CREDENTIALS_DIRECTORY"))
+	t.Setenv(invocationIDEnv, "")
+	require.NoError(t, os.Unsetenv(invocationIDEnv))
+
+	for _, tt := range []struct {
+		name      string
+		container string
+		files     map[string]string
+		dir       string
+	}{
+		{
+			name:  "systemd-nspawn",
+			files: map[string]string{"run/host/container-manager": "systemd-nspawn\n"},
+			dir:   "run/host/credentials",
+		},
+		{
+			name:      "podman quadlet",
+			container: "podman",
+			dir:       "run/secrets",
+		},
+		{
+			name:  "podman without $container",
+			files: map[string]string{"run/.containerenv": ""},
+			dir:   "run/secrets",
+		},
+		{
+			name:  "docker",
+			files: map[string]string{".dockerenv": ""},
+			dir:   "run/secrets",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			t.Setenv(containerEnv, tt.container)
+			for name, content := range tt.files {
+				require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0700))
+				require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(content), 0600))
+			}
+			withRoot := optionFunc(func(cfg *config) {
+				cfg.containerRoot = root
+			})
+
+			// Without the directory, there are no credentials to read.
+			prov := NewFactory(withRoot, WithContainerDetection(true)).Create(confmaptest.NewNopProviderSettings())
+			_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+			require.ErrorIs(t, err, ErrNoCredentialsDirectory)
+			assert.NoError(t, prov.Shutdown(context.Background()))
+
+			credDir := filepath.Join(root, tt.dir)
+			require.NoError(t, os.MkdirAll(credDir, 0700))
+			require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+			prov = NewFactory(withRoot, WithContainerDetection(true)).Create(confmaptest.NewNopProviderSettings())
+			ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+			require.NoError(t, err)
+			str, err := ret.AsString()
+			require.NoError(t, err)
+			assert.Equal(t, testCredValue, str)
+			assert.NoError(t, prov.Shutdown(context.Background()))
+
+			// Container detection is disabled by default.
+			prov = NewFactory(withRoot).Create(confmaptest.NewNopProviderSettings())
+			_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+			require.ErrorIs(t, err, ErrNoCredentialsDirectory)
+			assert.NoError(t, prov.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestDetectContainer(t *testing.T) {
+	root := t.TempDir()
+	p := &provider{cfg: newConfig([]Option{optionFunc(func(cfg *config) {
+		cfg.containerRoot = root
+	})})}
+	t.Setenv(containerEnv, "")
+	assert.Empty(t, p.detectContainer())
+	t.Setenv(containerEnv, "lxc")
+	assert.Equal(t, "lxc", p.detectContainer())
+	_, _, exists := p.discoverContainerCredentialsDirectory()
+	assert.False(t, exists)
+}
@@ -0,0 +1,39 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const (
+	containerSecretSchemeName = "containersecret"
+
+	// containerSecretsDirectory is where Docker and Podman mount secrets into containers.
+	containerSecretsDirectory = "/run/secrets"
+)
+
+// NewContainerSecretFactory returns a factory for a confmap.Provider that reads the configuration
+// from Docker and Podman secrets, so that the same configuration can reference its secrets
+// symmetrically when running in a container and under systemd.
+//
+// This Provider supports "containersecret" scheme, and can be called with a selector:
+// `containersecret:SECRET_NAME`
+//
+// The secret is read from /run/secrets/SECRET_NAME, or from the directory set with
+// WithCredentialsDirectory. Secret names are validated, and the URI options, fallback chains
+// and Option values work like for the provider returned by NewFactory, except that the
+// credentials directory is never taken from $CREDENTIALS_DIRECTORY or discovered from the unit.
+func NewContainerSecretFactory(opts ...Option) confmap.ProviderFactory {
+	defaults := optionFunc(func(cfg *config) {
+		cfg.scheme = containerSecretSchemeName
+		cfg.credentialsDirectory = containerSecretsDirectory
+		cfg.credentialsDirectoryEnv = nil
+		cfg.discoverUnitDirectory = false
+	})
+	cfg := newConfig(append([]Option{defaults}, opts...))
+	return confmap.NewProviderFactory(func(ps confmap.ProviderSettings) confmap.Provider {
+		return newProvider(ps, cfg)
+	})
+}
@@ -0,0 +1,40 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package credentialauthextension // import "bou.ke/systemdcredentialprovider/credentialauthextension"
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"bou.ke/systemdcredentialprovider/creds"
+)
+
+// Config configures the credentialauth extension.
+type Config struct {
+	// Directory is the credentials directory to read the token from, $CREDENTIALS_DIRECTORY by default.
+	Directory string `mapstructure:"directory"`
+	// Credential is the name of the credential holding the token.
+	Credential string `mapstructure:"credential"`
+	// Header is the header the token is sent in, "Authorization" by default.
+	Header string `mapstructure:"header"`
+	// Scheme prefixes the token in the header, "Bearer" by default. Empty sends the token as is.
+	Scheme string `mapstructure:"scheme"`
+	// RefreshInterval is how often the credential is read again to pick up a rotated token.
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+}
+
+// Validate checks the configuration.
+func (cfg *Config) Validate() error {
+	if cfg.RefreshInterval <= 0 {
+		return errors.New("refresh_interval must be positive")
+	}
+	if cfg.Header == "" {
+		return errors.New("header must be set")
+	}
+	if !creds.ValidName(cfg.Credential) {
+		return fmt.Errorf("invalid credential name %q", cfg.Credential)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package credentialauthextension // import "bou.ke/systemdcredentialprovider/credentialauthextension"
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+
+	"bou.ke/systemdcredentialprovider/creds"
+)
+
+// maxTokenSize is the maximum size of the token credential.
+const maxTokenSize = 64 << 10
+
+var errUnauthenticated = errors.New("missing or invalid token")
+
+type credentialAuthExtension struct {
+	cfg    *Config
+	logger *zap.Logger
+	// token holds the header value, the scheme followed by the token.
+	token atomic.Pointer[string]
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newExtension(cfg *Config, logger *zap.Logger) *credentialAuthExtension {
+	return &credentialAuthExtension{cfg: cfg, logger: logger}
+}
+
+func (e *credentialAuthExtension) Start(context.Context, component.Host) error {
+	dir := e.cfg.Directory
+	if dir == "" {
+		var err error
+		if dir, err = creds.Directory(); err != nil {
+			return err
+		}
+	}
+	if err := e.refresh(dir); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.watch(ctx, dir)
+	}()
+	return nil
+}
+
+func (e *credentialAuthExtension) watch(ctx context.Context, dir string) {
+	ticker := time.NewTicker(e.cfg.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// The previous token stays in use until the credential can be read again.
+		if err := e.refresh(dir); err != nil {
+			e.logger.Warn("Failed to refresh token", zap.String("credential", e.cfg.Credential), zap.Error(err))
+		}
+	}
+}
+
+// refresh reads the token from the credential in dir.
+func (e *credentialAuthExtension) refresh(dir string) error {
+	token, err := readToken(dir, e.cfg.Credential)
+	if err != nil {
+		return err
+	}
+	header := token
+	if e.cfg.Scheme != "" {
+		header = e.cfg.Scheme + " " + token
+	}
+	if old := e.token.Swap(&header); old != nil && *old != header {
+		e.logger.Info("Token rotated", zap.String("credential", e.cfg.Credential))
+	}
+	return nil
+}
+
+// readToken reads the credential called name in dir, without surrounding whitespace.
+func readToken(dir, name string) (string, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to open credentials directory %q: %w", dir, err)
+	}
+	defer root.Close()
+	f, err := root.Open(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to open credential %q in %q: %w", name, dir, err)
+	}
+	defer f.Close()
+	val, err := io.ReadAll(io.LimitReader(f, maxTokenSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read credential %q: %w", name, err)
+	}
+	if len(val) > maxTokenSize {
+		return "", fmt.Errorf("credential %q exceeds the maximum size of %d bytes", name, maxTokenSize)
+	}
+	token := bytes.TrimSpace(val)
+	if len(token) == 0 {
+		return "", fmt.Errorf("credential %q is empty", name)
+	}
+	if bytes.ContainsAny(token, "\r\n") {
+		return "", fmt.Errorf("credential %q must hold a single line", name)
+	}
+	return string(token), nil
+}
+
+// RoundTripper returns a round tripper adding the token to every request sent through base,
+// implementing the HTTP client authenticator interface of the collector.
+func (e *credentialAuthExtension) RoundTripper(base http.RoundTripper) (http.RoundTripper, error) {
+	return &roundTripper{base: base, ext: e}, nil
+}
+
+type roundTripper struct {
+	base http.RoundTripper
+	ext  *credentialAuthExtension
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	token := rt.ext.token.Load()
+	if token == nil {
+		return nil, errors.New("the credentialauth extension has not been started")
+	}
+	// A RoundTripper must not modify the request it is given.
+	req = req.Clone(req.Context())
+	req.Header.Set(rt.ext.cfg.Header, *token)
+	return rt.base.RoundTrip(req)
+}
+
+// Authenticate checks that the headers of an incoming request carry the token, implementing
+// the server authenticator interface of the collector.
+func (e *credentialAuthExtension) Authenticate(ctx context.Context, headers map[string][]string) (context.Context, error) {
+	token := e.token.Load()
+	if token == nil {
+		return ctx, errUnauthenticated
+	}
+	for name, values := range headers {
+		// gRPC metadata keys are lower case, HTTP headers canonicalized.
+		if !strings.EqualFold(name, e.cfg.Header) {
+			continue
+		}
+		for _, value := range values {
+			if subtle.ConstantTimeCompare([]byte(value), []byte(*token)) == 1 {
+				return ctx, nil
+			}
+		}
+	}
+	return ctx, errUnauthenticated
+}
+
+func (e *credentialAuthExtension) Shutdown(context.Context) error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package credentialauthextension
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+	"go.uber.org/zap"
+)
+
+func createExtension(t *testing.T, credDir string, configure func(*Config)) *credentialAuthExtension {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Directory = credDir
+	cfg.Credential = "api_token"
+	if configure != nil {
+		configure(cfg)
+	}
+	require.NoError(t, cfg.Validate())
+	set := extension.Settings{
+		ID:                component.NewID(factory.Type()),
+		TelemetrySettings: component.TelemetrySettings{Logger: zap.NewNop()},
+	}
+	ext, err := factory.Create(context.Background(), set, cfg)
+	require.NoError(t, err)
+	return ext.(*credentialAuthExtension)
+}
+
+func TestRoundTripper(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("token-1\n"), 0600))
+	headers := make(chan http.Header, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+	}))
+	defer server.Close()
+
+	ext := createExtension(t, credDir, func(cfg *Config) { cfg.RefreshInterval = 10 * time.Millisecond })
+	require.NoError(t, ext.Start(context.Background(), nil))
+	rt, err := ext.RoundTripper(http.DefaultTransport)
+	require.NoError(t, err)
+	client := &http.Client{Transport: rt}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, http.NoBody)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "Bearer token-1", (<-headers).Get("Authorization"))
+	assert.Empty(t, req.Header.Get("Authorization"), "the request must not be modified")
+
+	// A rotated token is picked up without restarting.
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("token-2\n"), 0600))
+	require.Eventually(t, func() bool {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return (<-headers).Get("Authorization") == "Bearer token-2"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The previous token stays in use while the credential can't be read.
+	require.NoError(t, os.Remove(filepath.Join(credDir, "api_token")))
+	time.Sleep(50 * time.Millisecond)
+	resp, err = client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "Bearer token-2", (<-headers).Get("Authorization"))
+
+	require.NoError(t, ext.Shutdown(context.Background()))
+}
+
+func TestAuthenticate(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("token-1"), 0600))
+	ext := createExtension(t, credDir, func(cfg *Config) {
+		cfg.Header = "X-Api-Key"
+		cfg.Scheme = ""
+	})
+
+	_, err := ext.Authenticate(context.Background(), map[string][]string{"X-Api-Key": {"token-1"}})
+	assert.ErrorIs(t, err, errUnauthenticated, "not started yet")
+
+	require.NoError(t, ext.Start(context.Background(), nil))
+	for _, headers := range []map[string][]string{
+		{"X-Api-Key": {"token-1"}},
+		{"x-api-key": {"other", "token-1"}},
+	} {
+		_, err = ext.Authenticate(context.Background(), headers)
+		assert.NoError(t, err, headers)
+	}
+	for _, headers := range []map[string][]string{
+		nil,
+		{"X-Api-Key": {"token-2"}},
+		{"Authorization": {"token-1"}},
+	} {
+		_, err = ext.Authenticate(context.Background(), headers)
+		assert.ErrorIs(t, err, errUnauthenticated, headers)
+	}
+	require.NoError(t, ext.Shutdown(context.Background()))
+}
+
+func TestStartErrors(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "empty"), []byte("\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "multiline"), []byte("a\nb\n"), 0600))
+
+	for name, wantErr := range map[string]string{
+		"missing":   `failed to open credential "missing"`,
+		"empty":     `credential "empty" is empty`,
+		"multiline": `credential "multiline" must hold a single line`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ext := createExtension(t, credDir, func(cfg *Config) { cfg.Credential = name })
+			assert.ErrorContains(t, ext.Start(context.Background(), nil), wantErr)
+			require.NoError(t, ext.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	assert.ErrorContains(t, cfg.Validate(), `invalid credential name ""`)
+	cfg.Credential = "api_token"
+	assert.NoError(t, cfg.Validate())
+	cfg.RefreshInterval = 0
+	assert.ErrorContains(t, cfg.Validate(), "refresh_interval must be positive")
+}
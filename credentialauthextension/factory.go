@@ -0,0 +1,47 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package credentialauthextension provides a collector authenticator extension that sends a
+// token read from a systemd credential as a bearer token, and checks that incoming requests
+// carry it. The credential is read again periodically, so that a rotated token is picked up
+// without restarting the collector.
+//
+// The extension implements the HTTP client and server authenticator interfaces of the
+// collector; gRPC clients aren't supported as that would make this module depend on gRPC.
+package credentialauthextension // import "bou.ke/systemdcredentialprovider/credentialauthextension"
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+)
+
+const (
+	defaultHeader          = "Authorization"
+	defaultScheme          = "Bearer"
+	defaultRefreshInterval = 10 * time.Second
+)
+
+var componentType = component.MustNewType("credentialauth")
+
+// NewFactory returns a factory for the credentialauth extension.
+func NewFactory() extension.Factory {
+	return extension.NewFactory(
+		componentType,
+		createDefaultConfig,
+		func(_ context.Context, set extension.Settings, cfg component.Config) (extension.Extension, error) {
+			return newExtension(cfg.(*Config), set.Logger), nil
+		},
+		component.StabilityLevelDevelopment,
+	)
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		Header:          defaultHeader,
+		Scheme:          defaultScheme,
+		RefreshInterval: defaultRefreshInterval,
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package credentialhealthextension // import "bou.ke/systemdcredentialprovider/credentialhealthextension"
+
+import (
+	"errors"
+	"time"
+)
+
+// Config configures the credentialhealth extension.
+type Config struct {
+	// CheckInterval is how often the credentials are checked.
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+	// Endpoint is the address the health of the credentials is served on, empty to not serve it.
+	Endpoint string `mapstructure:"endpoint"`
+}
+
+// Validate checks the configuration.
+func (cfg *Config) Validate() error {
+	if cfg.CheckInterval <= 0 {
+		return errors.New("check_interval must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package credentialhealthextension // import "bou.ke/systemdcredentialprovider/credentialhealthextension"
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+
+	"bou.ke/systemdcredentialprovider"
+	"bou.ke/systemdcredentialprovider/internal/sdnotify"
+)
+
+// healthPath is the path the health of the credentials is served on.
+const healthPath = "/health/credentials"
+
+// unavailableCredential describes a credential that is no longer available.
+type unavailableCredential struct {
+	// Name is the name of the credential.
+	Name string `json:"name"`
+	// Source is where the credential was read from.
+	Source string `json:"source"`
+	// Error describes why the credential is unavailable.
+	Error string `json:"error"`
+}
+
+// health is the response of the health endpoint.
+type health struct {
+	Healthy     bool                    `json:"healthy"`
+	Unavailable []unavailableCredential `json:"unavailable,omitempty"`
+}
+
+type credentialHealthExtension struct {
+	cfg    *Config
+	logger *zap.Logger
+	log    *systemdcredentialprovider.AccessLog
+
+	mu          sync.Mutex
+	unavailable []unavailableCredential
+
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	listener net.Listener
+	server   *http.Server
+}
+
+func newExtension(cfg *Config, logger *zap.Logger, log *systemdcredentialprovider.AccessLog) *credentialHealthExtension {
+	return &credentialHealthExtension{cfg: cfg, logger: logger, log: log}
+}
+
+func (e *credentialHealthExtension) Start(context.Context, component.Host) error {
+	if e.cfg.Endpoint != "" {
+		ln, err := net.Listen("tcp", e.cfg.Endpoint)
+		if err != nil {
+			return err
+		}
+		e.listener = ln
+		mux := http.NewServeMux()
+		mux.HandleFunc(healthPath, e.handle)
+		e.server = &http.Server{Handler: mux}
+		go func() {
+			if err := e.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				e.logger.Error("Failed to serve credential health", zap.Error(err))
+			}
+		}()
+		e.logger.Info("Serving credential health", zap.String("endpoint", ln.Addr().String()), zap.String("path", healthPath))
+	}
+	e.check()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(e.cfg.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				e.check()
+			}
+		}
+	}()
+	return nil
+}
+
+// check checks the credentials that were read successfully, and reports the ones that
+// became unavailable or available again.
+func (e *credentialHealthExtension) check() {
+	var unavailable []unavailableCredential
+	for _, rec := range e.log.Records() {
+		// Credentials that were never read successfully, such as optional ones, aren't part of the configuration.
+		if rec.LastSuccess.IsZero() {
+			continue
+		}
+		if err := checkSource(rec.Source); err != nil {
+			unavailable = append(unavailable, unavailableCredential{Name: rec.Name, Source: rec.Source, Error: err.Error()})
+		}
+	}
+
+	e.mu.Lock()
+	previous := e.unavailable
+	e.unavailable = unavailable
+	e.mu.Unlock()
+
+	changed := false
+	for _, u := range unavailable {
+		if !containsName(previous, u.Name) {
+			changed = true
+			e.logger.Error("Credential the configuration was resolved with is no longer available",
+				zap.String("credential", u.Name), zap.String("path", u.Source), zap.String("error", u.Error))
+		}
+	}
+	for _, u := range previous {
+		if !containsName(unavailable, u.Name) {
+			changed = true
+			e.logger.Info("Credential is available again", zap.String("credential", u.Name), zap.String("path", u.Source))
+		}
+	}
+	if changed {
+		e.notify(unavailable)
+	}
+}
+
+// checkSource checks that the credential read from source, as recorded in the access log, can
+// still be read. Sources that can't be checked, such as file descriptors, are assumed to be available.
+func checkSource(source string) error {
+	switch {
+	case strings.HasPrefix(source, "$"):
+		if _, ok := os.LookupEnv(source[1:]); !ok {
+			return errors.New("environment variable is not set")
+		}
+	case filepath.IsAbs(source):
+		f, err := os.Open(source)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+	return nil
+}
+
+func containsName(unavailable []unavailableCredential, name string) bool {
+	for _, u := range unavailable {
+		if u.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// notify reports the unavailable credentials to systemd, shown by `systemctl status`.
+func (e *credentialHealthExtension) notify(unavailable []unavailableCredential) {
+	status := "STATUS=All credentials are available"
+	if len(unavailable) > 0 {
+		names := make([]string, len(unavailable))
+		for i, u := range unavailable {
+			names[i] = u.Name
+		}
+		status = fmt.Sprintf("STATUS=Credentials unavailable: %s", strings.Join(names, ", "))
+	}
+	if _, err := sdnotify.Notify(status); err != nil {
+		e.logger.Warn("Failed to notify systemd", zap.Error(err))
+	}
+}
+
+// unavailableCredentials returns the credentials that were unavailable at the last check, sorted by name.
+func (e *credentialHealthExtension) unavailableCredentials() []unavailableCredential {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.unavailable
+}
+
+func (e *credentialHealthExtension) handle(w http.ResponseWriter, _ *http.Request) {
+	unavailable := e.unavailableCredentials()
+	w.Header().Set("Content-Type", "application/json")
+	if len(unavailable) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(health{Healthy: len(unavailable) == 0, Unavailable: unavailable})
+}
+
+func (e *credentialHealthExtension) Shutdown(ctx context.Context) error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+	}
+	if e.server == nil {
+		return nil
+	}
+	return e.server.Shutdown(ctx)
+}
@@ -0,0 +1,120 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package credentialhealthextension
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+	"go.opentelemetry.io/collector/extension"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"bou.ke/systemdcredentialprovider"
+	"bou.ke/systemdcredentialprovider/internal/sdnotify"
+)
+
+func listenNotify(t *testing.T) *net.UnixConn {
+	socket := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv(sdnotify.SocketEnv, socket)
+	return conn
+}
+
+func readNotify(t *testing.T, conn *net.UnixConn) string {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 256)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+func getHealth(t *testing.T, url string) (int, health) {
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var h health
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&h))
+	return resp.StatusCode, h
+}
+
+func TestCredentialHealth(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("my-secret-token"), 0600))
+	log := systemdcredentialprovider.NewAccessLog()
+	prov := systemdcredentialprovider.NewFactory(
+		systemdcredentialprovider.WithCredentialsDirectory(credDir),
+		systemdcredentialprovider.WithAccessLog(log),
+	).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), "systemdcredential:api_token", nil)
+	require.NoError(t, err)
+	// Credentials that never resolved aren't checked.
+	_, err = prov.Retrieve(context.Background(), "systemdcredential:optional?optional=true", nil)
+	require.NoError(t, err)
+	require.NoError(t, prov.Shutdown(context.Background()))
+	conn := listenNotify(t)
+
+	factory := NewFactory(log)
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Endpoint = "127.0.0.1:0"
+	cfg.CheckInterval = 10 * time.Millisecond
+	require.NoError(t, cfg.Validate())
+	core, logs := observer.New(zap.InfoLevel)
+	set := extension.Settings{
+		ID:                component.NewID(factory.Type()),
+		TelemetrySettings: component.TelemetrySettings{Logger: zap.New(core)},
+	}
+	ext, err := factory.Create(context.Background(), set, cfg)
+	require.NoError(t, err)
+	require.NoError(t, ext.Start(context.Background(), nil))
+	defer func() {
+		assert.NoError(t, ext.Shutdown(context.Background()))
+	}()
+	url := "http://" + ext.(*credentialHealthExtension).listener.Addr().String() + healthPath
+
+	status, h := getHealth(t, url)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, h.Healthy)
+
+	require.NoError(t, os.Remove(filepath.Join(credDir, "api_token")))
+	assert.Equal(t, "STATUS=Credentials unavailable: api_token", readNotify(t, conn))
+	status, h = getHealth(t, url)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.False(t, h.Healthy)
+	require.Len(t, h.Unavailable, 1)
+	assert.Equal(t, "api_token", h.Unavailable[0].Name)
+	assert.Equal(t, filepath.Join(credDir, "api_token"), h.Unavailable[0].Source)
+	assert.Equal(t, 1, logs.FilterMessage("Credential the configuration was resolved with is no longer available").Len())
+
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("my-secret-token"), 0600))
+	assert.Equal(t, "STATUS=All credentials are available", readNotify(t, conn))
+	status, _ = getHealth(t, url)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 1, logs.FilterMessage("Credential is available again").Len())
+}
+
+func TestCheckSource(t *testing.T) {
+	t.Setenv("HEALTH_TEST_TOKEN", "value")
+	assert.NoError(t, checkSource("$HEALTH_TEST_TOKEN"))
+	assert.Error(t, checkSource("$HEALTH_TEST_UNSET"))
+	assert.ErrorIs(t, checkSource(filepath.Join(t.TempDir(), "missing")), os.ErrNotExist)
+	assert.NoError(t, checkSource("fd 3"))
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.Error(t, (&Config{}).Validate())
+	assert.NoError(t, createDefaultConfig().(*Config).Validate())
+}
@@ -0,0 +1,43 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package credentialhealthextension provides a collector extension that periodically checks
+// that the credentials the configuration was resolved with are still present and readable,
+// e.g. to notice a credentials directory that was unmounted by a cleanup job long before the
+// next restart fails. Credentials that disappear are logged, reported to systemd with STATUS=
+// and make the health endpoint, if enabled, respond with 503 Service Unavailable.
+package credentialhealthextension // import "bou.ke/systemdcredentialprovider/credentialhealthextension"
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+
+	"bou.ke/systemdcredentialprovider"
+)
+
+const defaultCheckInterval = 30 * time.Second
+
+var componentType = component.MustNewType("credentialhealth")
+
+// NewFactory returns a factory for the credentialhealth extension, checking the credentials
+// whose reads succeeded according to log. Pass the same log to the provider with
+// systemdcredentialprovider.WithAccessLog.
+func NewFactory(log *systemdcredentialprovider.AccessLog) extension.Factory {
+	return extension.NewFactory(
+		componentType,
+		createDefaultConfig,
+		func(_ context.Context, set extension.Settings, cfg component.Config) (extension.Extension, error) {
+			return newExtension(cfg.(*Config), set.Logger, log), nil
+		},
+		component.StabilityLevelDevelopment,
+	)
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		CheckInterval: defaultCheckInterval,
+	}
+}
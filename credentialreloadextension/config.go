@@ -0,0 +1,37 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package credentialreloadextension // import "bou.ke/systemdcredentialprovider/credentialreloadextension"
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"bou.ke/systemdcredentialprovider/creds"
+)
+
+// Config configures the credentialreload extension.
+type Config struct {
+	// Directory is the credentials directory to watch, $CREDENTIALS_DIRECTORY by default.
+	Directory string `mapstructure:"directory"`
+	// Credentials are the names of the credentials to watch, all credentials in the directory
+	// if empty.
+	Credentials []string `mapstructure:"credentials"`
+	// PollInterval is how often the credentials are checked for changes.
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+}
+
+// Validate checks the configuration.
+func (cfg *Config) Validate() error {
+	if cfg.PollInterval <= 0 {
+		return errors.New("poll_interval must be positive")
+	}
+	var errs []error
+	for _, name := range cfg.Credentials {
+		if !creds.ValidName(name) {
+			errs = append(errs, fmt.Errorf("invalid credential name %q", name))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,176 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package credentialreloadextension // import "bou.ke/systemdcredentialprovider/credentialreloadextension"
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+
+	"bou.ke/systemdcredentialprovider/creds"
+	"bou.ke/systemdcredentialprovider/internal/sdnotify"
+)
+
+// maxCredentialSize is the maximum size of a credential, like the provider's default.
+const maxCredentialSize = 1 << 20
+
+type credentialReloadExtension struct {
+	cfg    *Config
+	logger *zap.Logger
+	// reload makes the collector reload its configuration, see reloadCollector.
+	reload func() error
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newExtension(cfg *Config, logger *zap.Logger) *credentialReloadExtension {
+	return &credentialReloadExtension{cfg: cfg, logger: logger, reload: reloadCollector}
+}
+
+func (e *credentialReloadExtension) Start(context.Context, component.Host) error {
+	dir := e.cfg.Directory
+	if dir == "" {
+		var err error
+		if dir, err = creds.Directory(); err != nil {
+			return err
+		}
+	}
+	snapshot, err := e.snapshot(dir)
+	if err != nil {
+		return err
+	}
+	// The collector restarts extensions once a reload completed, so starting also ends a reload
+	// announced by a previous instance, or by systemd for `systemctl reload`.
+	e.notify("READY=1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.watch(ctx, dir, snapshot)
+	}()
+	return nil
+}
+
+func (e *credentialReloadExtension) watch(ctx context.Context, dir string, snapshot map[string][sha256.Size]byte) {
+	ticker := time.NewTicker(e.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current, err := e.snapshot(dir)
+		if err != nil {
+			e.logger.Warn("Failed to check credentials for changes", zap.Error(err))
+			continue
+		}
+		changed := changedCredentials(snapshot, current)
+		if len(changed) == 0 {
+			continue
+		}
+		e.logger.Info("Credentials changed, reloading configuration", zap.Strings("credentials", changed))
+		e.notify(sdnotify.Reloading())
+		if err := e.reload(); err != nil {
+			e.logger.Error("Failed to reload configuration", zap.Error(err))
+			e.notify("READY=1")
+			continue
+		}
+		// Only report each change once, even if the reload doesn't restart this extension.
+		snapshot = current
+	}
+}
+
+// snapshot returns the hashes of the contents of the watched credentials in dir.
+func (e *credentialReloadExtension) snapshot(dir string) (map[string][sha256.Size]byte, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	names := e.cfg.Credentials
+	if len(names) == 0 {
+		entries, err := fs.ReadDir(root.FS(), ".")
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if creds.ValidName(entry.Name()) && entry.Type().IsRegular() {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	snapshot := make(map[string][sha256.Size]byte, len(names))
+	for _, name := range names {
+		sum, err := hashCredential(root, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			// A missing credential is recorded as absent, so that its creation is a change.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		snapshot[name] = sum
+	}
+	return snapshot, nil
+}
+
+// hashCredential returns the hash of the content of the credential called name in root. Only the
+// first maxCredentialSize+1 bytes are hashed, so that a huge file isn't read in full on every
+// check; the provider refuses credentials that large anyway.
+func hashCredential(root *os.Root, name string) ([sha256.Size]byte, error) {
+	f, err := root.Open(name)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, io.LimitReader(f, maxCredentialSize+1)); err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return [sha256.Size]byte(h.Sum(nil)), nil
+}
+
+// changedCredentials returns the sorted names of the credentials that differ between old and current.
+func changedCredentials(old, current map[string][sha256.Size]byte) []string {
+	var changed []string
+	for name, sum := range current {
+		if oldSum, ok := old[name]; !ok || oldSum != sum {
+			changed = append(changed, name)
+		}
+	}
+	for name := range old {
+		if _, ok := current[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+func (e *credentialReloadExtension) notify(state string) {
+	if _, err := sdnotify.Notify(state); err != nil {
+		e.logger.Warn("Failed to notify systemd", zap.Error(err))
+	}
+}
+
+func (e *credentialReloadExtension) Shutdown(context.Context) error {
+	if e.cancel != nil {
+		e.cancel()
+		e.wg.Wait()
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package credentialreloadextension
+
+import (
+	"context"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+	"go.uber.org/zap"
+
+	"bou.ke/systemdcredentialprovider/internal/sdnotify"
+)
+
+func listenNotify(t *testing.T) *net.UnixConn {
+	socket := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv(sdnotify.SocketEnv, socket)
+	return conn
+}
+
+func readNotify(t *testing.T, conn *net.UnixConn) string {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 256)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+func TestCredentialReload(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("token-1"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "unwatched"), []byte("value-1"), 0600))
+	conn := listenNotify(t)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Directory = credDir
+	cfg.Credentials = []string{"api_token", "tls.key"}
+	cfg.PollInterval = 10 * time.Millisecond
+	require.NoError(t, cfg.Validate())
+	set := extension.Settings{
+		ID:                component.NewID(factory.Type()),
+		TelemetrySettings: component.TelemetrySettings{Logger: zap.NewNop()},
+	}
+	ext, err := factory.Create(context.Background(), set, cfg)
+	require.NoError(t, err)
+	reloads := make(chan struct{}, 10)
+	ext.(*credentialReloadExtension).reload = func() error {
+		reloads <- struct{}{}
+		return nil
+	}
+
+	require.NoError(t, ext.Start(context.Background(), nil))
+	assert.Equal(t, "READY=1", readNotify(t, conn))
+
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "unwatched"), []byte("value-2"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("token-2"), 0600))
+	assert.True(t, strings.HasPrefix(readNotify(t, conn), "RELOADING=1\nMONOTONIC_USEC="))
+	<-reloads
+
+	// Creating a watched credential is a change as well.
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "tls.key"), []byte("key"), 0600))
+	assert.True(t, strings.HasPrefix(readNotify(t, conn), "RELOADING=1"))
+	<-reloads
+
+	require.NoError(t, ext.Shutdown(context.Background()))
+	assert.Empty(t, reloads)
+}
+
+func TestSnapshot(t *testing.T) {
+	credDir := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "outside")
+	require.NoError(t, os.WriteFile(outside, []byte("outside"), 0600))
+	require.NoError(t, os.Symlink(outside, filepath.Join(credDir, "escape")))
+	large := strings.Repeat("a", maxCredentialSize+1)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "large"), []byte(large), 0600))
+
+	// Symlinks aren't credentials, and only the start of large credentials is hashed.
+	e := &credentialReloadExtension{cfg: &Config{}}
+	snapshot, err := e.snapshot(credDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"large"}, slices.Sorted(maps.Keys(snapshot)))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "large"), []byte(large+"b"), 0600))
+	current, err := e.snapshot(credDir)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot, current)
+
+	// Named credentials can't be read through symlinks out of the directory.
+	e = &credentialReloadExtension{cfg: &Config{Credentials: []string{"escape"}}}
+	_, err = e.snapshot(credDir)
+	assert.Error(t, err)
+}
+
+func TestChangedCredentials(t *testing.T) {
+	old := map[string][32]byte{"same": {1}, "changed": {2}, "removed": {3}}
+	current := map[string][32]byte{"same": {1}, "changed": {4}, "added": {5}}
+	assert.Equal(t, []string{"added", "changed", "removed"}, changedCredentials(old, current))
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, createDefaultConfig().(*Config).Validate())
+	assert.Error(t, (&Config{}).Validate())
+	assert.ErrorContains(t, (&Config{PollInterval: time.Second, Credentials: []string{"../etc"}}).Validate(), `invalid credential name "../etc"`)
+}
@@ -0,0 +1,37 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package credentialreloadextension provides a collector extension that reloads the collector
+// configuration when the watched credentials change, notifying systemd with
+// RELOADING=1 and READY=1 like `systemctl reload` does for Type=notify-reload services.
+package credentialreloadextension // import "bou.ke/systemdcredentialprovider/credentialreloadextension"
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+)
+
+const defaultPollInterval = 10 * time.Second
+
+var componentType = component.MustNewType("credentialreload")
+
+// NewFactory returns a factory for the credentialreload extension.
+func NewFactory() extension.Factory {
+	return extension.NewFactory(
+		componentType,
+		createDefaultConfig,
+		func(_ context.Context, set extension.Settings, cfg component.Config) (extension.Extension, error) {
+			return newExtension(cfg.(*Config), set.Logger), nil
+		},
+		component.StabilityLevelDevelopment,
+	)
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		PollInterval: defaultPollInterval,
+	}
+}
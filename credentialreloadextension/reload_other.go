@@ -0,0 +1,15 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !unix
+
+package credentialreloadextension // import "bou.ke/systemdcredentialprovider/credentialreloadextension"
+
+import (
+	"errors"
+)
+
+// reloadCollector is only implemented on Unix systems, which have SIGHUP.
+func reloadCollector() error {
+	return errors.New("reloading the configuration is not supported on this platform")
+}
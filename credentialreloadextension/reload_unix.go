@@ -0,0 +1,17 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unix
+
+package credentialreloadextension // import "bou.ke/systemdcredentialprovider/credentialreloadextension"
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadCollector makes the collector reload its configuration by sending itself SIGHUP,
+// which is also what systemd sends on `systemctl reload`.
+func reloadCollector() error {
+	return syscall.Kill(os.Getpid(), syscall.SIGHUP)
+}
@@ -0,0 +1,22 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package credentialzextension // import "bou.ke/systemdcredentialprovider/credentialzextension"
+
+import (
+	"errors"
+)
+
+// Config configures the credentialz extension.
+type Config struct {
+	// Endpoint is the address the debug page is served on.
+	Endpoint string `mapstructure:"endpoint"`
+}
+
+// Validate checks the configuration.
+func (cfg *Config) Validate() error {
+	if cfg.Endpoint == "" {
+		return errors.New("endpoint must be set")
+	}
+	return nil
+}
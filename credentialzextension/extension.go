@@ -0,0 +1,86 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package credentialzextension // import "bou.ke/systemdcredentialprovider/credentialzextension"
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net"
+	"net/http"
+
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+
+	"bou.ke/systemdcredentialprovider"
+)
+
+// pagePath is the path of the debug page.
+const pagePath = "/debug/credentialz"
+
+var page = template.Must(template.New("credentialz").Parse(`<!DOCTYPE html>
+<html>
+<head><title>credentialz</title></head>
+<body>
+<h1>Credentials</h1>
+<table border="1" cellpadding="4">
+<tr><th>Name</th><th>Source</th><th>Reads</th><th>Failures</th><th>Last read</th><th>Last success</th><th>Last error</th></tr>
+{{- range .}}
+<tr><td>{{.Name}}</td><td>{{.Source}}</td><td>{{.Reads}}</td><td>{{.Failures}}</td><td>{{.LastRead.Format "2006-01-02T15:04:05Z07:00"}}</td><td>{{if not .LastSuccess.IsZero}}{{.LastSuccess.Format "2006-01-02T15:04:05Z07:00"}}{{end}}</td><td>{{.LastError}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+type credentialzExtension struct {
+	cfg      *Config
+	logger   *zap.Logger
+	log      *systemdcredentialprovider.AccessLog
+	listener net.Listener
+	server   *http.Server
+}
+
+func newExtension(cfg *Config, logger *zap.Logger, log *systemdcredentialprovider.AccessLog) *credentialzExtension {
+	return &credentialzExtension{cfg: cfg, logger: logger, log: log}
+}
+
+func (e *credentialzExtension) Start(context.Context, component.Host) error {
+	ln, err := net.Listen("tcp", e.cfg.Endpoint)
+	if err != nil {
+		return err
+	}
+	e.listener = ln
+	mux := http.NewServeMux()
+	mux.HandleFunc(pagePath, e.handle)
+	e.server = &http.Server{Handler: mux}
+	go func() {
+		if err := e.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.logger.Error("Failed to serve credentialz", zap.Error(err))
+		}
+	}()
+	e.logger.Info("Serving credentialz", zap.String("endpoint", ln.Addr().String()), zap.String("path", pagePath))
+	return nil
+}
+
+func (e *credentialzExtension) handle(w http.ResponseWriter, r *http.Request) {
+	records := e.log.Records()
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(records)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := page.Execute(w, records); err != nil {
+		e.logger.Warn("Failed to render credentialz", zap.Error(err))
+	}
+}
+
+func (e *credentialzExtension) Shutdown(ctx context.Context) error {
+	if e.server == nil {
+		return nil
+	}
+	return e.server.Shutdown(ctx)
+}
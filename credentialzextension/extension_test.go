@@ -0,0 +1,81 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package credentialzextension
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+	"go.opentelemetry.io/collector/extension"
+	"go.uber.org/zap"
+
+	"bou.ke/systemdcredentialprovider"
+)
+
+func TestCredentialz(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("my-secret-token"), 0600))
+	log := systemdcredentialprovider.NewAccessLog()
+	prov := systemdcredentialprovider.NewFactory(
+		systemdcredentialprovider.WithCredentialsDirectory(credDir),
+		systemdcredentialprovider.WithAccessLog(log),
+	).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), "systemdcredential:api_token", nil)
+	require.NoError(t, err)
+	_, err = prov.Retrieve(context.Background(), "systemdcredential:<missing>", nil)
+	require.Error(t, err)
+	require.NoError(t, prov.Shutdown(context.Background()))
+
+	factory := NewFactory(log)
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Endpoint = "127.0.0.1:0"
+	require.NoError(t, cfg.Validate())
+	set := extension.Settings{
+		ID:                component.NewID(factory.Type()),
+		TelemetrySettings: component.TelemetrySettings{Logger: zap.NewNop()},
+	}
+	ext, err := factory.Create(context.Background(), set, cfg)
+	require.NoError(t, err)
+	require.NoError(t, ext.Start(context.Background(), nil))
+	defer func() {
+		assert.NoError(t, ext.Shutdown(context.Background()))
+	}()
+	base := "http://" + ext.(*credentialzExtension).listener.Addr().String() + pagePath
+
+	resp, err := http.Get(base)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "<td>api_token</td>")
+	// Names are escaped, and values are never shown.
+	assert.Contains(t, string(body), "<td>&lt;missing&gt;</td>")
+	assert.NotContains(t, string(body), "my-secret-token")
+
+	resp, err = http.Get(base + "?format=json")
+	require.NoError(t, err)
+	var records []systemdcredentialprovider.AccessRecord
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&records))
+	require.NoError(t, resp.Body.Close())
+	require.Len(t, records, 2)
+	assert.Equal(t, "<missing>", records[0].Name)
+	assert.Equal(t, 1, records[0].Failures)
+	assert.Equal(t, "api_token", records[1].Name)
+	assert.Equal(t, 0, records[1].Failures)
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.Error(t, (&Config{}).Validate())
+	assert.NoError(t, createDefaultConfig().(*Config).Validate())
+}
@@ -0,0 +1,39 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package credentialzextension provides a collector extension serving a zpages-style debug
+// page that lists the credentials read by the systemdcredential provider, when they were read
+// and whether the reads succeeded. Credential values are never shown.
+package credentialzextension // import "bou.ke/systemdcredentialprovider/credentialzextension"
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+
+	"bou.ke/systemdcredentialprovider"
+)
+
+const defaultEndpoint = "localhost:55690"
+
+var componentType = component.MustNewType("credentialz")
+
+// NewFactory returns a factory for the credentialz extension, showing the reads recorded in
+// log. Pass the same log to the provider with systemdcredentialprovider.WithAccessLog.
+func NewFactory(log *systemdcredentialprovider.AccessLog) extension.Factory {
+	return extension.NewFactory(
+		componentType,
+		createDefaultConfig,
+		func(_ context.Context, set extension.Settings, cfg component.Config) (extension.Extension, error) {
+			return newExtension(cfg.(*Config), set.Logger, log), nil
+		},
+		component.StabilityLevelDevelopment,
+	)
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		Endpoint: defaultEndpoint,
+	}
+}
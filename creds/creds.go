@@ -0,0 +1,228 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package creds reads systemd credentials without depending on the OpenTelemetry confmap
+// machinery, see https://systemd.io/CREDENTIALS/. It applies the same name validation,
+// directory resolution and trimming as the systemdcredential provider.
+package creds // import "bou.ke/systemdcredentialprovider/creds"
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"bou.ke/systemdcredentialprovider/internal/unitcreds"
+)
+
+const (
+	// DirectoryEnv is the environment variable systemd sets to the credentials directory.
+	DirectoryEnv = "CREDENTIALS_DIRECTORY"
+
+	// maxNameLength is the maximum length of a credential name, NAME_MAX on Linux.
+	maxNameLength = 255
+	// maxSize is the maximum size of a credential read into memory, like the provider's default.
+	maxSize = 1 << 20
+)
+
+// ErrNoDirectory is returned when the process has no credentials directory.
+var ErrNoDirectory = errors.New(DirectoryEnv + " environment variable is not set")
+
+// ValidName reports whether name is a valid credential name according to systemd's own
+// rules: it has to be both a valid file name and a valid file descriptor name.
+func ValidName(name string) bool {
+	if name == "" || len(name) > maxNameLength || name == "." || name == ".." {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c < ' ' || c > '~' || c == '/' || c == ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// Directory returns the credentials directory of the current process, $CREDENTIALS_DIRECTORY.
+// Like the provider by default, it doesn't fall back to the directory of the service owning the
+// process when the variable is unset; use UnitDirectory for that.
+func Directory() (string, error) {
+	if dir, ok := os.LookupEnv(DirectoryEnv); ok {
+		return dir, nil
+	}
+	return "", ErrNoDirectory
+}
+
+// UnitDirectory returns the credentials directory of the systemd service the process belongs to,
+// for processes that run under systemd but lost $CREDENTIALS_DIRECTORY, such as sub-processes
+// spawned with a sanitized environment, like the provider does with WithUnitDirectoryDiscovery.
+// The directories of user services are looked up below $XDG_RUNTIME_DIR.
+func UnitDirectory() (string, error) {
+	unit, user := unitcreds.Service(unitcreds.ProcSelfCgroup)
+	if dir, ok := unitcreds.Directory(unitcreds.RunCredentialsDirectory, unit, user); ok {
+		return dir, nil
+	}
+	return "", ErrNoDirectory
+}
+
+// Get returns the credential called name as a string, with a single trailing newline removed.
+func Get(ctx context.Context, name string) (string, error) {
+	val, err := Bytes(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.TrimSuffix(val, []byte("\n"))), nil
+}
+
+// Bytes returns the untouched content of the credential called name. Credentials larger than
+// 1 MiB are rejected, use Open to stream them instead.
+func Bytes(ctx context.Context, name string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return readCredential(name)
+}
+
+// Exists reports whether the credential called name exists. Like Open, it doesn't follow
+// symlinks out of the credentials directory.
+func Exists(name string) bool {
+	_, err := Stat(name)
+	return err == nil
+}
+
+// CredentialInfo describes a credential without its content.
+type CredentialInfo struct {
+	// Name is the name of the credential.
+	Name string
+	// Size is the size of the credential in bytes.
+	Size int64
+	// ModTime is the time the credential was last modified.
+	ModTime time.Time
+	// Mode holds the permissions of the credential file.
+	Mode fs.FileMode
+}
+
+// List describes the credentials of the current process, sorted by name, without reading
+// their contents, e.g. for tooling that shows which credentials a service actually has.
+func List(ctx context.Context) ([]CredentialInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	dir, err := Directory()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credentials in %q: %w", dir, err)
+	}
+	var infos []CredentialInfo
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !ValidName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			// The credential was removed while listing.
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat credential %q in %q: %w", entry.Name(), dir, err)
+		}
+		infos = append(infos, credentialInfo(entry.Name(), info))
+	}
+	// os.ReadDir already sorts by file name.
+	return infos, nil
+}
+
+// Stat describes the credential called name without reading its content, e.g. to tell how long
+// ago a token that should be rotated regularly was last written.
+func Stat(name string) (CredentialInfo, error) {
+	if !ValidName(name) {
+		return CredentialInfo{}, fmt.Errorf("invalid credential name %q", name)
+	}
+	dir, err := Directory()
+	if err != nil {
+		return CredentialInfo{}, err
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return CredentialInfo{}, fmt.Errorf("failed to open credentials directory %q: %w", dir, err)
+	}
+	defer root.Close()
+	info, err := root.Stat(name)
+	if err != nil {
+		return CredentialInfo{}, fmt.Errorf("failed to stat credential %q in %q: %w", name, dir, err)
+	}
+	if !info.Mode().IsRegular() {
+		return CredentialInfo{}, fmt.Errorf("credential %q in %q is not a regular file", name, dir)
+	}
+	return credentialInfo(name, info), nil
+}
+
+// credentialInfo describes the credential called name, whose file has info.
+func credentialInfo(name string, info fs.FileInfo) CredentialInfo {
+	return CredentialInfo{
+		Name:    name,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Mode:    info.Mode().Perm(),
+	}
+}
+
+// Open opens the credential called name for reading, so that large credentials such as CA
+// bundles or keytabs can be streamed instead of buffered. Unlike Bytes, Open doesn't limit the
+// size of the credential. The caller must close the returned reader.
+func Open(name string) (io.ReadCloser, error) {
+	return open(name)
+}
+
+func open(name string) (*os.File, error) {
+	if !ValidName(name) {
+		return nil, fmt.Errorf("invalid credential name %q", name)
+	}
+	dir, err := Directory()
+	if err != nil {
+		return nil, err
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open credentials directory %q: %w", dir, err)
+	}
+	defer root.Close()
+	f, err := root.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open credential %q in %q: %w", name, dir, err)
+	}
+	return f, nil
+}
+
+// readCredential reads the untouched content of the credential called name.
+func readCredential(name string) ([]byte, error) {
+	f, err := open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	val, err := readAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential %q: %w", name, err)
+	}
+	return val, nil
+}
+
+// readAll reads r, failing if it is larger than maxSize.
+func readAll(r io.Reader) ([]byte, error) {
+	val, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(val) > maxSize {
+		return nil, fmt.Errorf("size exceeds the maximum of %d bytes", maxSize)
+	}
+	return val, nil
+}
@@ -0,0 +1,135 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package creds
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidName(t *testing.T) {
+	for _, name := range []string{"api_token", "myapp.api.token", "with space", strings.Repeat("a", 255)} {
+		assert.True(t, ValidName(name), "expected %q to be valid", name)
+	}
+	for _, name := range []string{"", ".", "..", "dir/cred", "my:cred", "café", strings.Repeat("a", 256)} {
+		assert.False(t, ValidName(name), "expected %q to be invalid", name)
+	}
+}
+
+func TestGet(t *testing.T) {
+	dir := writeCredentials(t, map[string]string{"api_token": "my-secret-token\n", "binary": "\x00\x01\n\n"})
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "subdir"), 0700))
+
+	val, err := Get(context.Background(), "api_token")
+	require.NoError(t, err)
+	assert.Equal(t, "my-secret-token", val)
+
+	raw, err := Bytes(context.Background(), "binary")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("\x00\x01\n\n"), raw)
+
+	_, err = Get(context.Background(), "missing")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = Get(context.Background(), "../api_token")
+	assert.ErrorContains(t, err, "invalid credential name")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Get(ctx, "api_token")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.True(t, Exists("api_token"))
+	assert.False(t, Exists("missing"))
+	assert.False(t, Exists("subdir"))
+
+	// A symlink out of the credentials directory can't be opened, so it doesn't count as existing.
+	outside := filepath.Join(t.TempDir(), "outside")
+	require.NoError(t, os.WriteFile(outside, []byte("outside"), 0600))
+	require.NoError(t, os.Symlink(outside, filepath.Join(dir, "escape")))
+	assert.False(t, Exists("escape"))
+	_, err = Open("escape")
+	assert.Error(t, err)
+}
+
+func TestList(t *testing.T) {
+	dir := writeCredentials(t, map[string]string{"api_token": "my-secret-token\n", "binary": "\x00"})
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "subdir"), 0700))
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "api_token"), modTime, modTime))
+	require.NoError(t, os.Chmod(filepath.Join(dir, "binary"), 0400))
+
+	infos, err := List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	assert.Equal(t, CredentialInfo{Name: "api_token", Size: 16, ModTime: modTime, Mode: 0600}, CredentialInfo{
+		Name: infos[0].Name, Size: infos[0].Size, ModTime: infos[0].ModTime.UTC(), Mode: infos[0].Mode,
+	})
+	assert.Equal(t, "binary", infos[1].Name)
+	assert.EqualValues(t, 1, infos[1].Size)
+	assert.Equal(t, fs.FileMode(0400), infos[1].Mode)
+}
+
+func TestStat(t *testing.T) {
+	dir := writeCredentials(t, map[string]string{"api_token": "my-secret-token\n"})
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "subdir"), 0700))
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "api_token"), modTime, modTime))
+
+	info, err := Stat("api_token")
+	require.NoError(t, err)
+	info.ModTime = info.ModTime.UTC()
+	assert.Equal(t, CredentialInfo{Name: "api_token", Size: 16, ModTime: modTime, Mode: 0600}, info)
+
+	_, err = Stat("missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = Stat("subdir")
+	assert.ErrorContains(t, err, "not a regular file")
+	_, err = Stat("../api_token")
+	assert.ErrorContains(t, err, "invalid credential name")
+}
+
+func TestGetTooLarge(t *testing.T) {
+	writeCredentials(t, map[string]string{"large": strings.Repeat("a", maxSize+1)})
+	_, err := Get(context.Background(), "large")
+	assert.ErrorContains(t, err, "exceeds the maximum")
+}
+
+func TestNoDirectory(t *testing.T) {
+	t.Setenv(DirectoryEnv, "")
+	os.Unsetenv(DirectoryEnv)
+
+	_, err := Directory()
+	assert.ErrorIs(t, err, ErrNoDirectory)
+	_, err = Get(context.Background(), "api_token")
+	assert.ErrorIs(t, err, ErrNoDirectory)
+	_, err = List(context.Background())
+	assert.ErrorIs(t, err, ErrNoDirectory)
+	assert.False(t, Exists("api_token"))
+}
+
+func TestOpen(t *testing.T) {
+	large := strings.Repeat("a", maxSize+1)
+	writeCredentials(t, map[string]string{"large": large})
+
+	f, err := Open("large")
+	require.NoError(t, err)
+	val, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, large, string(val))
+	require.NoError(t, f.Close())
+
+	_, err = Open("missing")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = Open("../large")
+	assert.ErrorContains(t, err, "invalid credential name")
+}
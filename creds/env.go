@@ -0,0 +1,52 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package creds // import "bou.ke/systemdcredentialprovider/creds"
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ExportEnv reads the credentials called names and returns them as KEY=VALUE pairs suitable for
+// exec.Cmd.Env, for tools that take their secrets from the environment. The key is the name
+// upper-cased, with every character other than a letter, digit or '_' replaced by '_', e.g.
+// API_TOKEN for `api_token` and MYAPP_DB_PASSWORD for `myapp.db-password`. A single trailing
+// newline is removed from the value. Credentials holding a NUL byte can't be exported.
+//
+// Note that the environment of a process is visible to every process of the same user; prefer
+// Forward for tools that can read credentials from $CREDENTIALS_DIRECTORY.
+func ExportEnv(names ...string) ([]string, error) {
+	env := make([]string, 0, len(names))
+	for _, name := range names {
+		val, err := readCredential(name)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.IndexByte(val, 0) >= 0 {
+			return nil, fmt.Errorf("credential %q contains a NUL byte and can't be exported to the environment", name)
+		}
+		val = bytes.TrimSuffix(val, []byte("\n"))
+		env = append(env, envName(name)+"="+string(val))
+	}
+	return env, nil
+}
+
+// envName returns the name of the environment variable the credential called name is exported as.
+func envName(name string) string {
+	key := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	if key[0] >= '0' && key[0] <= '9' {
+		key = "_" + key
+	}
+	return key
+}
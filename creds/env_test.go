@@ -0,0 +1,46 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package creds
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"api_token":         "API_TOKEN",
+		"myapp.db-password": "MYAPP_DB_PASSWORD",
+		"1password":         "_1PASSWORD",
+		"with space":        "WITH_SPACE",
+	} {
+		assert.Equal(t, expected, envName(name))
+	}
+}
+
+func TestExportEnv(t *testing.T) {
+	writeCredentials(t, map[string]string{
+		"api_token":    "my-secret-token\n",
+		"db.password":  "hunter2",
+		"multi_line":   "line1\nline2\n\n",
+		"binary_value": "a\x00b",
+	})
+
+	env, err := ExportEnv("api_token", "db.password", "multi_line")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"API_TOKEN=my-secret-token", "DB_PASSWORD=hunter2", "MULTI_LINE=line1\nline2\n"}, env)
+
+	_, err = ExportEnv("binary_value")
+	assert.ErrorContains(t, err, "NUL byte")
+	_, err = ExportEnv("api_token", "missing")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	t.Setenv(DirectoryEnv, "")
+	os.Unsetenv(DirectoryEnv)
+	_, err = ExportEnv("api_token")
+	assert.ErrorIs(t, err, ErrNoDirectory)
+}
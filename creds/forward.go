@@ -0,0 +1,99 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package creds // import "bou.ke/systemdcredentialprovider/creds"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// sharedMemoryDirectory is a tmpfs on practically every Linux system.
+const sharedMemoryDirectory = "/dev/shm"
+
+// Forwarded is a private directory holding credentials forwarded to a child process.
+type Forwarded struct {
+	dir string
+}
+
+// Forward copies the credentials called names into a new private directory and points
+// $CREDENTIALS_DIRECTORY of cmd at it, so that the child process can only read the credentials
+// it needs. The directory is created on a tmpfs ($XDG_RUNTIME_DIR or /dev/shm) when possible so
+// that the credentials never reach a disk. cmd must not have been started yet; if cmd.Env is nil
+// it is initialized from the current environment.
+//
+// The caller must call Close once the child has exited, or use Run.
+func Forward(cmd *exec.Cmd, names ...string) (*Forwarded, error) {
+	dir, err := os.MkdirTemp(tmpfsDirectory(), "credentials-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory for forwarded credentials: %w", err)
+	}
+	f := &Forwarded{dir: dir}
+	for _, name := range names {
+		val, err := readCredential(name)
+		if err == nil {
+			err = os.WriteFile(filepath.Join(dir, name), val, 0400)
+		}
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to forward credential %q: %w", name, err), f.Close())
+		}
+	}
+	// Like systemd, make the directory read-only once it is populated.
+	if err := os.Chmod(dir, 0500); err != nil {
+		return nil, errors.Join(err, f.Close())
+	}
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(removeEnv(cmd.Env, DirectoryEnv), DirectoryEnv+"="+dir)
+	return f, nil
+}
+
+// Run forwards the credentials called names to cmd like Forward, runs it and removes the
+// forwarded credentials once it has exited.
+func Run(cmd *exec.Cmd, names ...string) error {
+	f, err := Forward(cmd, names...)
+	if err != nil {
+		return err
+	}
+	return errors.Join(cmd.Run(), f.Close())
+}
+
+// Dir returns the directory holding the forwarded credentials.
+func (f *Forwarded) Dir() string {
+	return f.dir
+}
+
+// Close removes the forwarded credentials.
+func (f *Forwarded) Close() error {
+	if err := os.Chmod(f.dir, 0700); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.RemoveAll(f.dir)
+}
+
+// tmpfsDirectory returns a directory on a tmpfs to create private directories in.
+func tmpfsDirectory() string {
+	for _, dir := range []string{os.Getenv("XDG_RUNTIME_DIR"), sharedMemoryDirectory} {
+		if info, err := os.Stat(dir); dir != "" && err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return os.TempDir()
+}
+
+// removeEnv returns env without the assignments to the variable name.
+func removeEnv(env []string, name string) []string {
+	out := env[:0:0]
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, name+"=") {
+			out = append(out, kv)
+		}
+	}
+	return out
+}
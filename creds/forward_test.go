@@ -0,0 +1,65 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package creds
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCredentials(t *testing.T, creds map[string]string) string {
+	dir := t.TempDir()
+	for name, val := range creds {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(val), 0600))
+	}
+	t.Setenv(DirectoryEnv, dir)
+	return dir
+}
+
+func TestForward(t *testing.T) {
+	writeCredentials(t, map[string]string{"api_token": "my-secret-token", "password": "hunter2"})
+
+	cmd := exec.Command("sh", "-c", `ls "$CREDENTIALS_DIRECTORY" && cat "$CREDENTIALS_DIRECTORY/api_token"`)
+	cmd.Env = []string{DirectoryEnv + "=/somewhere/else"}
+	f, err := Forward(cmd, "api_token")
+	require.NoError(t, err)
+	assert.Equal(t, []string{DirectoryEnv + "=" + f.Dir()}, cmd.Env)
+
+	out, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "api_token\nmy-secret-token", string(out))
+
+	require.NoError(t, f.Close())
+	assert.NoDirExists(t, f.Dir())
+}
+
+func TestForwardMissing(t *testing.T) {
+	writeCredentials(t, map[string]string{"api_token": "my-secret-token"})
+
+	cmd := exec.Command("true")
+	_, err := Forward(cmd, "api_token", "missing")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Nil(t, cmd.Env)
+
+	_, err = Forward(cmd, "../api_token")
+	assert.ErrorContains(t, err, "invalid credential name")
+}
+
+func TestRun(t *testing.T) {
+	writeCredentials(t, map[string]string{"api_token": "my-secret-token"})
+
+	var out strings.Builder
+	cmd := exec.Command("sh", "-c", `echo "$CREDENTIALS_DIRECTORY" && cat "$CREDENTIALS_DIRECTORY/api_token"`)
+	cmd.Stdout = &out
+	require.NoError(t, Run(cmd, "api_token"))
+	dir, val, _ := strings.Cut(out.String(), "\n")
+	assert.Equal(t, "my-secret-token", val)
+	assert.NoDirExists(t, dir)
+}
@@ -0,0 +1,73 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package creds // import "bou.ke/systemdcredentialprovider/creds"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// LockedBuffer holds the content of a credential in memory that is locked into RAM, so that it
+// is never written to swap, and excluded from core dumps. Its memory is not managed by the
+// garbage collector: the caller must call Destroy once the credential is no longer needed.
+type LockedBuffer struct {
+	mem []byte
+	n   int
+}
+
+// Locked reads the credential called name into a LockedBuffer, without intermediate copies
+// on the heap. Credentials larger than 1 MiB are rejected. The size of locked memory is limited
+// by RLIMIT_MEMLOCK. Locked returns an error matching errors.ErrUnsupported on platforms other than Linux.
+func Locked(ctx context.Context, name string) (*LockedBuffer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat credential %q: %w", name, err)
+	}
+	if info.Size() > maxSize {
+		return nil, fmt.Errorf("failed to read credential %q: size of %d bytes exceeds the maximum of %d bytes", name, info.Size(), maxSize)
+	}
+	// One spare byte detects credentials that grew since they were stat'ed.
+	mem, err := lockedAlloc(int(info.Size()) + 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate locked memory for credential %q: %w", name, err)
+	}
+	buf := &LockedBuffer{mem: mem}
+	buf.n, err = io.ReadFull(f, mem)
+	switch {
+	case err == nil:
+		err = fmt.Errorf("credential %q changed while reading it", name)
+	case errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF):
+		return buf, nil
+	default:
+		err = fmt.Errorf("failed to read credential %q: %w", name, err)
+	}
+	return nil, errors.Join(err, buf.Destroy())
+}
+
+// Bytes returns the content of the credential. The returned slice is only valid until Destroy is
+// called; copying it, e.g. by converting it to a string, moves the credential out of locked memory.
+func (b *LockedBuffer) Bytes() []byte {
+	return b.mem[:b.n:b.n]
+}
+
+// Destroy wipes the credential and releases its memory. Calling Destroy more than once is a no-op.
+func (b *LockedBuffer) Destroy() error {
+	if b.mem == nil {
+		return nil
+	}
+	clear(b.mem)
+	err := lockedFree(b.mem)
+	b.mem, b.n = nil, 0
+	return err
+}
@@ -0,0 +1,35 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package creds // import "bou.ke/systemdcredentialprovider/creds"
+
+import (
+	"errors"
+	"syscall"
+)
+
+// madvDontDump is MADV_DONTDUMP, which the syscall package doesn't define.
+const madvDontDump = 0x10
+
+// lockedAlloc returns size bytes of anonymous memory outside of the Go heap that is locked into
+// RAM and excluded from core dumps.
+func lockedAlloc(size int) ([]byte, error) {
+	mem, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Mlock(mem); err != nil {
+		return nil, errors.Join(err, syscall.Munmap(mem))
+	}
+	if err := syscall.Madvise(mem, madvDontDump); err != nil {
+		return nil, errors.Join(err, syscall.Munlock(mem), syscall.Munmap(mem))
+	}
+	return mem, nil
+}
+
+// lockedFree releases memory returned by lockedAlloc.
+func lockedFree(mem []byte) error {
+	return errors.Join(syscall.Munlock(mem), syscall.Munmap(mem))
+}
@@ -0,0 +1,18 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package creds // import "bou.ke/systemdcredentialprovider/creds"
+
+import "errors"
+
+// lockedAlloc is only implemented on Linux.
+func lockedAlloc(int) ([]byte, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// lockedFree is never called, as lockedAlloc never succeeds.
+func lockedFree([]byte) error {
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package creds
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocked(t *testing.T) {
+	writeCredentials(t, map[string]string{"api_token": "my-secret-token\n", "empty": "", "large": strings.Repeat("a", maxSize+1)})
+
+	buf, err := Locked(context.Background(), "api_token")
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip("locked memory is not supported")
+	}
+	require.NoError(t, err)
+	assert.Equal(t, []byte("my-secret-token\n"), buf.Bytes())
+	require.NoError(t, buf.Destroy())
+	assert.Empty(t, buf.Bytes())
+	assert.NoError(t, buf.Destroy())
+
+	buf, err = Locked(context.Background(), "empty")
+	require.NoError(t, err)
+	assert.Empty(t, buf.Bytes())
+	require.NoError(t, buf.Destroy())
+
+	_, err = Locked(context.Background(), "missing")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = Locked(context.Background(), "large")
+	assert.ErrorContains(t, err, "exceeds the maximum")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Locked(ctx, "api_token")
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,57 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package creds // import "bou.ke/systemdcredentialprovider/creds"
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Validate checks up front that every credential called names exists, is a regular file that
+// can be read, is not accessible by other users and is not empty. It returns an error listing
+// every problem found, so that a service can fail at startup with the complete list instead
+// of discovering missing credentials one at a time.
+func Validate(ctx context.Context, names []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := Directory(); err != nil {
+		return err
+	}
+	var errs []error
+	for _, name := range names {
+		if err := validate(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validate(name string) error {
+	f, err := open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat credential %q: %w", name, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("credential %q is not a regular file", name)
+	}
+	if perm := info.Mode().Perm(); perm&0007 != 0 {
+		return fmt.Errorf("credential %q is accessible by other users (mode %#o)", name, perm)
+	}
+	val, err := readAll(f)
+	if err != nil {
+		return fmt.Errorf("failed to read credential %q: %w", name, err)
+	}
+	if len(bytes.TrimSpace(val)) == 0 {
+		return fmt.Errorf("credential %q is empty", name)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package creds
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	dir := writeCredentials(t, map[string]string{
+		"api_token":  "my-secret-token\n",
+		"password":   "hunter2",
+		"empty":      "",
+		"blank":      " \n",
+		"world_read": "visible",
+	})
+	require.NoError(t, os.Chmod(filepath.Join(dir, "world_read"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "subdir"), 0700))
+
+	require.NoError(t, Validate(context.Background(), []string{"api_token", "password"}))
+
+	err := Validate(context.Background(), []string{"api_token", "missing", "empty", "blank", "world_read", "subdir", "../api_token"})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	for _, msg := range []string{
+		`credential "missing"`,
+		`credential "empty" is empty`,
+		`credential "blank" is empty`,
+		`credential "world_read" is accessible by other users (mode 0644)`,
+		`credential "subdir" is not a regular file`,
+		`invalid credential name "../api_token"`,
+	} {
+		assert.ErrorContains(t, err, msg)
+	}
+	assert.NotContains(t, err.Error(), `"api_token"`)
+
+	t.Setenv(DirectoryEnv, "")
+	os.Unsetenv(DirectoryEnv)
+	assert.ErrorIs(t, Validate(context.Background(), []string{"api_token"}), ErrNoDirectory)
+}
@@ -0,0 +1,150 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package creds // import "bou.ke/systemdcredentialprovider/creds"
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// watchInterval is how often Watch checks the credentials for changes.
+var watchInterval = 5 * time.Second
+
+// EventType is the kind of change an Event reports.
+type EventType string
+
+const (
+	// EventCreated reports a credential that didn't exist before.
+	EventCreated EventType = "created"
+	// EventChanged reports a credential whose content changed.
+	EventChanged EventType = "changed"
+	// EventRemoved reports a credential that no longer exists.
+	EventRemoved EventType = "removed"
+)
+
+// Event reports a change of a watched credential, see Watch.
+type Event struct {
+	// Name is the name of the credential.
+	Name string
+	// Type is the kind of change.
+	Type EventType
+}
+
+// Watch watches the credentials called names for changes, e.g. to pick up rotated API keys
+// without restarting, and delivers an Event for every change on the returned channel until ctx
+// is done, when the channel is closed. Without names, all credentials are watched, including
+// the ones created later. The credentials are compared by hashes of their contents every few
+// seconds; changes that are undone before the next check are not reported.
+func Watch(ctx context.Context, names ...string) (<-chan Event, error) {
+	for _, name := range names {
+		if !ValidName(name) {
+			return nil, fmt.Errorf("invalid credential name %q", name)
+		}
+	}
+	dir, err := Directory()
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := hashCredentials(dir, names)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan Event)
+	ticker := time.NewTicker(watchInterval)
+	go func() {
+		defer close(events)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			current, err := hashCredentials(dir, names)
+			if err != nil {
+				// The check is retried, e.g. when a credential is replaced while reading it.
+				continue
+			}
+			for _, event := range diffCredentials(snapshot, current) {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			snapshot = current
+		}
+	}()
+	return events, nil
+}
+
+// hashCredentials returns the hashes of the contents of the credentials called names in dir,
+// or of all credentials in dir without names. Missing credentials are left out.
+func hashCredentials(dir string, names []string) (map[string][sha256.Size]byte, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open credentials directory %q: %w", dir, err)
+	}
+	defer root.Close()
+	if len(names) == 0 {
+		entries, err := fs.ReadDir(root.FS(), ".")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list credentials in %q: %w", dir, err)
+		}
+		for _, entry := range entries {
+			if entry.Type().IsRegular() && ValidName(entry.Name()) {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	hashes := make(map[string][sha256.Size]byte, len(names))
+	for _, name := range names {
+		f, err := root.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open credential %q in %q: %w", name, dir, err)
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credential %q in %q: %w", name, dir, err)
+		}
+		hashes[name] = [sha256.Size]byte(h.Sum(nil))
+	}
+	return hashes, nil
+}
+
+// diffCredentials returns the events turning the credentials hashed in old into current,
+// sorted by name.
+func diffCredentials(old, current map[string][sha256.Size]byte) []Event {
+	var events []Event
+	for name, sum := range current {
+		switch oldSum, ok := old[name]; {
+		case !ok:
+			events = append(events, Event{Name: name, Type: EventCreated})
+		case oldSum != sum:
+			events = append(events, Event{Name: name, Type: EventChanged})
+		}
+	}
+	for name := range old {
+		if _, ok := current[name]; !ok {
+			events = append(events, Event{Name: name, Type: EventRemoved})
+		}
+	}
+	slices.SortFunc(events, func(a, b Event) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return events
+}
@@ -0,0 +1,90 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package creds
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	interval := watchInterval
+	watchInterval = 10 * time.Millisecond
+	t.Cleanup(func() { watchInterval = interval })
+	dir := writeCredentials(t, map[string]string{"api_token": "v1", "password": "hunter2", "unwatched": "x"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := Watch(ctx, "api_token", "password", "endpoint")
+	require.NoError(t, err)
+
+	next := func() Event {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(10 * time.Second):
+			require.FailNow(t, "no event")
+			return Event{}
+		}
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "api_token"), []byte("v2"), 0600))
+	assert.Equal(t, Event{Name: "api_token", Type: EventChanged}, next())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "unwatched"), []byte("y"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "endpoint"), []byte("https://otlp.example.com"), 0600))
+	assert.Equal(t, Event{Name: "endpoint", Type: EventCreated}, next())
+	require.NoError(t, os.Remove(filepath.Join(dir, "password")))
+	assert.Equal(t, Event{Name: "password", Type: EventRemoved}, next())
+
+	cancel()
+	for range events {
+		// Drain events until the channel is closed.
+	}
+}
+
+func TestWatchAll(t *testing.T) {
+	interval := watchInterval
+	watchInterval = 10 * time.Millisecond
+	t.Cleanup(func() { watchInterval = interval })
+	dir := writeCredentials(t, map[string]string{"api_token": "v1"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := Watch(ctx)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "endpoint"), []byte("https://otlp.example.com"), 0600))
+	select {
+	case event := <-events:
+		assert.Equal(t, Event{Name: "endpoint", Type: EventCreated}, event)
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "no event")
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	writeCredentials(t, nil)
+	_, err := Watch(context.Background(), "../api_token")
+	assert.ErrorContains(t, err, "invalid credential name")
+
+	t.Setenv(DirectoryEnv, "")
+	require.NoError(t, os.Unsetenv(DirectoryEnv))
+	_, err = Watch(context.Background(), "api_token")
+	assert.ErrorIs(t, err, ErrNoDirectory)
+}
+
+func TestDiffCredentials(t *testing.T) {
+	old := map[string][32]byte{"a": {1}, "b": {2}, "c": {3}}
+	current := map[string][32]byte{"a": {1}, "b": {4}, "d": {5}}
+	assert.Equal(t, []Event{
+		{Name: "b", Type: EventChanged},
+		{Name: "c", Type: EventRemoved},
+		{Name: "d", Type: EventCreated},
+	}, diffCredentials(old, current))
+	assert.Empty(t, diffCredentials(current, current))
+}
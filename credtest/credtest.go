@@ -0,0 +1,87 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package credtest sets up credentials directories for tests of code that reads systemd
+// credentials, such as collectors embedding the systemdcredential provider:
+//
+//	func TestExporter(t *testing.T) {
+//		credtest.Set(t, "api_token", "s3cr3t")
+//		// ${systemdcredential:api_token} now resolves to "s3cr3t".
+//	}
+//
+// The directories are created like systemd creates them, with read-only files in a read-only
+// directory, and $CREDENTIALS_DIRECTORY points at them for the duration of the test. Like
+// testing.T.Setenv, the helpers can't be used in parallel tests or tests with parallel
+// ancestors; pass the directory returned by Dir to the provider with WithCredentialsDirectory
+// instead of relying on the environment when tests run in parallel.
+package credtest // import "bou.ke/systemdcredentialprovider/credtest"
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"bou.ke/systemdcredentialprovider/creds"
+)
+
+// dirs holds the directories created by Dir, so that Set never writes into a credentials
+// directory it didn't create, such as the one of a test running under systemd.
+var dirs sync.Map
+
+// Dir creates a credentials directory holding credentials, a map of names to values, and
+// points $CREDENTIALS_DIRECTORY at it until the test ends. It returns the directory.
+func Dir(t testing.TB, credentials map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, value := range credentials {
+		write(t, dir, name, value)
+	}
+	readOnly(t, dir)
+	dirs.Store(dir, true)
+	t.Cleanup(func() {
+		dirs.Delete(dir)
+		// Let the testing package remove the directory.
+		_ = os.Chmod(dir, 0700)
+	})
+	t.Setenv(creds.DirectoryEnv, dir)
+	return dir
+}
+
+// Set sets the credential called name to value in the credentials directory created by Dir,
+// replacing it if it exists. If $CREDENTIALS_DIRECTORY doesn't point at a directory created by
+// Dir, a new one is created first.
+func Set(t testing.TB, name, value string) {
+	t.Helper()
+	dir := os.Getenv(creds.DirectoryEnv)
+	if _, ok := dirs.Load(dir); !ok {
+		dir = Dir(t, nil)
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatalf("credtest: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("credtest: %v", err)
+	}
+	write(t, dir, name, value)
+	readOnly(t, dir)
+}
+
+// write writes the credential called name to dir.
+func write(t testing.TB, dir, name, value string) {
+	t.Helper()
+	if !creds.ValidName(name) {
+		t.Fatalf("credtest: invalid credential name %q", name)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0400); err != nil {
+		t.Fatalf("credtest: %v", err)
+	}
+}
+
+// readOnly makes dir read-only, like systemd does once it has populated a credentials directory.
+func readOnly(t testing.TB, dir string) {
+	t.Helper()
+	if err := os.Chmod(dir, 0500); err != nil {
+		t.Fatalf("credtest: %v", err)
+	}
+}
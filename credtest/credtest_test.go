@@ -0,0 +1,72 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package credtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+
+	"bou.ke/systemdcredentialprovider"
+	"bou.ke/systemdcredentialprovider/creds"
+)
+
+func TestDir(t *testing.T) {
+	dir := Dir(t, map[string]string{"api_token": "s3cr3t", "endpoint": "https://otlp.example.com\n"})
+	assert.Equal(t, dir, os.Getenv(creds.DirectoryEnv))
+
+	val, err := creds.Get(context.Background(), "endpoint")
+	require.NoError(t, err)
+	assert.Equal(t, "https://otlp.example.com", val)
+
+	prov := systemdcredentialprovider.NewFactory().Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), "systemdcredential:api_token", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0500), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(dir, "api_token"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0400), info.Mode().Perm())
+}
+
+func TestSet(t *testing.T) {
+	outer := t.TempDir()
+	t.Setenv(creds.DirectoryEnv, outer)
+
+	t.Run("new directory", func(t *testing.T) {
+		Set(t, "api_token", "s3cr3t")
+		Set(t, "api_token", "rotated")
+		Set(t, "endpoint", "https://otlp.example.com")
+		dir := os.Getenv(creds.DirectoryEnv)
+		assert.NotEqual(t, outer, dir, "a directory that wasn't created by Dir must not be written to")
+		for name, want := range map[string]string{"api_token": "rotated", "endpoint": "https://otlp.example.com"} {
+			val, err := creds.Get(context.Background(), name)
+			require.NoError(t, err)
+			assert.Equal(t, want, val)
+		}
+	})
+	assert.Equal(t, outer, os.Getenv(creds.DirectoryEnv), "the environment is restored after the test")
+	entries, err := os.ReadDir(outer)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	t.Run("existing directory", func(t *testing.T) {
+		dir := Dir(t, map[string]string{"api_token": "s3cr3t"})
+		Set(t, "endpoint", "https://otlp.example.com")
+		assert.Equal(t, dir, os.Getenv(creds.DirectoryEnv))
+		assert.FileExists(t, filepath.Join(dir, "api_token"))
+		assert.FileExists(t, filepath.Join(dir, "endpoint"))
+	})
+}
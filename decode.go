@@ -0,0 +1,50 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"unicode"
+)
+
+// decoders maps every supported value of the `decode` query parameter to its decoding function.
+var decoders = map[string]func([]byte) ([]byte, error){
+	"base64": decodeBase64,
+	"hex":    decodeHex,
+}
+
+// decodeBase64 decodes standard base64, ignoring whitespace such as the line
+// breaks added by `base64` or `systemd-creds --pretty`.
+func decodeBase64(val []byte) ([]byte, error) {
+	val = removeWhitespace(val)
+	out := make([]byte, base64.StdEncoding.DecodedLen(len(val)))
+	n, err := base64.StdEncoding.Decode(out, val)
+	if err != nil {
+		return nil, err
+	}
+	return out[:n], nil
+}
+
+// decodeHex decodes a hex string, ignoring whitespace such as the trailing
+// newline written by `openssl rand -hex`.
+func decodeHex(val []byte) ([]byte, error) {
+	val = removeWhitespace(val)
+	out := make([]byte, hex.DecodedLen(len(val)))
+	n, err := hex.Decode(out, val)
+	if err != nil {
+		return nil, err
+	}
+	return out[:n], nil
+}
+
+func removeWhitespace(val []byte) []byte {
+	return bytes.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, val)
+}
@@ -0,0 +1,73 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"go.uber.org/zap"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	// devModeEnv enables the development shim when set to a true value, see WithDevMode.
+	devModeEnv = "SYSTEMDCREDENTIAL_DEV"
+	// devCredentialsDirectory is the directory in the working directory the development shim
+	// reads credentials from.
+	devCredentialsDirectory = ".credentials"
+	// devCredentialsFile is the file in the working directory the development shim reads
+	// credentials from when devCredentialsDirectory doesn't exist.
+	devCredentialsFile = ".credentials.yaml"
+)
+
+// devModeFromEnv reports whether the development shim is enabled through $SYSTEMDCREDENTIAL_DEV.
+func devModeFromEnv() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(devModeEnv))
+	return enabled
+}
+
+// devCredentialsFS returns the credentials of the development shim, read from the
+// .credentials directory or the .credentials.yaml file in the working directory, see WithDevMode.
+func (p *provider) devCredentialsFS() (fs.FS, string, bool) {
+	credDir := filepath.Join(p.cfg.devDirectory, devCredentialsDirectory)
+	if info, err := os.Stat(credDir); err == nil && info.IsDir() {
+		p.warnDevMode(credDir)
+		return credentialsDirFS(credDir, p.cfg.symlinkPolicy), credDir, true
+	}
+	credFile := filepath.Join(p.cfg.devDirectory, devCredentialsFile)
+	data, err := os.ReadFile(credFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		p.cfg.logger.Debug("Development mode is enabled, but there are no development credentials",
+			zap.String("directory", credDir), zap.String("file", credFile))
+		return nil, "", false
+	}
+	var credentials map[string]string
+	if err == nil {
+		err = yaml.Unmarshal(data, &credentials)
+	}
+	if err != nil {
+		p.cfg.logger.Error("Failed to read development credentials, expected a mapping of credential names to values",
+			zap.String("file", credFile), zap.Error(err))
+		return nil, "", false
+	}
+	p.warnDevMode(credFile)
+	fsys := make(memFS, len(credentials))
+	for name, val := range credentials {
+		fsys[name] = []byte(val)
+	}
+	return fsys, credFile, true
+}
+
+// warnDevMode logs, once, that credentials are read from path by the development shim.
+func (p *provider) warnDevMode(path string) {
+	p.devModeWarning.Do(func() {
+		p.cfg.logger.Warn("INSECURE: the development credentials shim is active, credentials are read from "+
+			"an unprotected local path instead of systemd. Never enable development mode in production",
+			zap.String("path", path), zap.String("variable", devModeEnv))
+	})
+}
@@ -0,0 +1,115 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestDevMode(t *testing.T) {
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+	require.NoError(t, os.Unsetenv("CREDENTIALS_DIRECTORY"))
+	t.Setenv(devModeEnv, "")
+
+	newDevProvider := func(t *testing.T, dir string, opts ...Option) (*observer.ObservedLogs, func(uri string) (any, error)) {
+		core, logs := observer.New(zap.DebugLevel)
+		opts = append([]Option{
+			WithLogger(zap.New(core)),
+			WithContainerDetection(false),
+			optionFunc(func(cfg *config) {
+				cfg.devDirectory = dir
+			}),
+		}, opts...)
+		prov := NewFactory(opts...).Create(confmaptest.NewNopProviderSettings())
+		t.Cleanup(func() {
+			assert.NoError(t, prov.Shutdown(context.Background()))
+		})
+		return logs, func(uri string) (any, error) {
+			ret, err := prov.Retrieve(context.Background(), credSchemePrefix+uri, nil)
+			if err != nil {
+				return nil, err
+			}
+			return ret.AsRaw()
+		}
+	}
+
+	t.Run("directory", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(dir, devCredentialsDirectory), 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, devCredentialsDirectory, "api_token"), []byte(testCredValue), 0600))
+		// The directory takes precedence over the file.
+		require.NoError(t, os.WriteFile(filepath.Join(dir, devCredentialsFile), []byte("api_token: other\n"), 0600))
+
+		logs, retrieve := newDevProvider(t, dir, WithDevMode(true))
+		for range 2 {
+			val, err := retrieve("api_token")
+			require.NoError(t, err)
+			assert.Equal(t, testCredValue, val)
+		}
+		warnings := logs.FilterLevelExact(zap.WarnLevel).All()
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0].Message, "INSECURE")
+		assert.Equal(t, filepath.Join(dir, devCredentialsDirectory), warnings[0].ContextMap()["path"])
+	})
+
+	t.Run("file", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, devCredentialsFile), []byte("api_token: "+testCredValue+"\nport: 4317\n"), 0600))
+
+		t.Setenv(devModeEnv, "1")
+		logs, retrieve := newDevProvider(t, dir)
+		val, err := retrieve("api_token")
+		require.NoError(t, err)
+		assert.Equal(t, testCredValue, val)
+		val, err = retrieve("port?parse=yaml")
+		require.NoError(t, err)
+		assert.Equal(t, 4317, val)
+		_, err = retrieve("missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Equal(t, 1, logs.FilterLevelExact(zap.WarnLevel).Len())
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, devCredentialsFile), []byte("nested:\n  key: value\n"), 0600))
+
+		logs, retrieve := newDevProvider(t, dir, WithDevMode(true))
+		_, err := retrieve("nested")
+		assert.ErrorIs(t, err, ErrNoCredentialsDirectory)
+		assert.NotZero(t, logs.FilterMessage("Failed to read development credentials, expected a mapping of credential names to values").Len())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, devCredentialsFile), []byte("api_token: "+testCredValue+"\n"), 0600))
+
+		t.Setenv(devModeEnv, "true")
+		logs, retrieve := newDevProvider(t, dir, WithDevMode(false))
+		_, err := retrieve("api_token")
+		assert.ErrorIs(t, err, ErrNoCredentialsDirectory)
+		assert.Zero(t, logs.FilterLevelExact(zap.WarnLevel).Len())
+	})
+
+	t.Run("credentials directory takes precedence", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, devCredentialsFile), []byte("api_token: other\n"), 0600))
+		credDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+
+		logs, retrieve := newDevProvider(t, dir, WithDevMode(true), WithCredentialsDirectory(credDir))
+		val, err := retrieve("api_token")
+		require.NoError(t, err)
+		assert.Equal(t, testCredValue, val)
+		assert.Zero(t, logs.FilterLevelExact(zap.WarnLevel).Len())
+	})
+}
@@ -0,0 +1,24 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import "bou.ke/systemdcredentialprovider/internal/unitcreds"
+
+const (
+	// invocationIDEnv is set by systemd for every process it starts as part of a unit.
+	invocationIDEnv = unitcreds.InvocationIDEnv
+	// procSelfCgroup lists the control groups of the current process.
+	procSelfCgroup = unitcreds.ProcSelfCgroup
+	// runCredentialsDirectory is where systemd places the credentials directories of system units.
+	runCredentialsDirectory = unitcreds.RunCredentialsDirectory
+)
+
+// discoverUnitCredentialsDirectory locates the credentials directory of the unit the process
+// belongs to, for processes that run under systemd but lost $CREDENTIALS_DIRECTORY, such as
+// sub-processes spawned by a service with a sanitized environment. The directories of user
+// services are looked up below $XDG_RUNTIME_DIR, see WithServiceManager.
+func (p *provider) discoverUnitCredentialsDirectory() (string, bool) {
+	unit, user := p.serviceUnit()
+	return unitcreds.Directory(p.cfg.runCredentialsDirectory, unit, user)
+}
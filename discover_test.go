@@ -0,0 +1,49 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestUnitDirectoryDiscovery(t *testing.T) {
+	runDir := t.TempDir()
+	credDir := filepath.Join(runDir, "otelcol.service")
+	require.NoError(t, os.Mkdir(credDir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	cgroupFile := filepath.Join(t.TempDir(), "cgroup")
+	require.NoError(t, os.WriteFile(cgroupFile, []byte("0::/system.slice/otelcol.service\n"), 0600))
+	withPaths := optionFunc(func(cfg *config) {
+		cfg.procSelfCgroup = cgroupFile
+		cfg.runCredentialsDirectory = runDir
+	})
+
+	// Without $INVOCATION_ID the process is not considered to run under systemd.
+	t.Setenv(invocationIDEnv, "")
+	require.NoError(t, os.Unsetenv(invocationIDEnv))
+	prov := NewFactory(withPaths, WithUnitDirectoryDiscovery(true)).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.Error(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	t.Setenv(invocationIDEnv, "0123456789abcdef0123456789abcdef")
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+
+	// Discovery is disabled by default.
+	prov = NewFactory(withPaths).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.Error(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
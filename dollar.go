@@ -0,0 +1,57 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+// escapeDollarSigns returns ret with every '$' in its strings doubled. The resolver expands the
+// values returned by providers again, and replaces `$$` with `$` once it is done, so escaping
+// makes values containing `${...}` or `$$` resolve byte-for-byte instead of being expanded or
+// mangled. ret is returned as is when it holds no '$'.
+func escapeDollarSigns(ret *confmap.Retrieved) (*confmap.Retrieved, error) {
+	raw, err := ret.AsRaw()
+	if err != nil {
+		return nil, err
+	}
+	escaped, changed := escapeDollarSignsIn(raw)
+	if !changed {
+		return ret, nil
+	}
+	return confmap.NewRetrieved(escaped, confmap.WithRetrievedClose(ret.Close))
+}
+
+// escapeDollarSignsIn doubles every '$' in the strings of raw, a value of a Retrieved, and
+// reports whether there were any.
+func escapeDollarSignsIn(raw any) (any, bool) {
+	switch v := raw.(type) {
+	case string:
+		if !strings.Contains(v, "$") {
+			return v, false
+		}
+		return strings.ReplaceAll(v, "$", "$$"), true
+	case map[string]any:
+		escaped := make(map[string]any, len(v))
+		changed := false
+		for key, val := range v {
+			var c bool
+			escaped[key], c = escapeDollarSignsIn(val)
+			changed = changed || c
+		}
+		return escaped, changed
+	case []any:
+		escaped := make([]any, len(v))
+		changed := false
+		for i, val := range v {
+			var c bool
+			escaped[i], c = escapeDollarSignsIn(val)
+			changed = changed || c
+		}
+		return escaped, changed
+	}
+	return raw, false
+}
@@ -0,0 +1,90 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestDollarSignEscaping(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("DOLLAR_TEST", "expanded")
+	for name, content := range map[string]string{
+		"reference": "${env:DOLLAR_TEST}",
+		"escaped":   "pa$$word",
+		"plain":     "pa$word$",
+		"map":       "user: ${env:DOLLAR_TEST}\nlist: [$a, b]\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(credDir, name), []byte(content), 0600))
+	}
+	conf := map[string]any{
+		"reference": "${systemdcredential:reference}",
+		"escaped":   "${systemdcredential:escaped}",
+		"inline":    "Bearer ${systemdcredential:plain} and ${env:DOLLAR_TEST}",
+		"map":       "${systemdcredential:map?format=map}",
+		"expanded":  "${systemdcredential:reference?expand=true}",
+	}
+	resolve := func(opts ...Option) map[string]any {
+		t.Helper()
+		resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+			URIs: []string{"static:config"},
+			ProviderFactories: []confmap.ProviderFactory{
+				confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider { return staticProvider{conf: conf} }),
+				confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider { return envProvider{} }),
+				NewFactory(append([]Option{WithCredentialsDirectory(credDir)}, opts...)...),
+			},
+		})
+		require.NoError(t, err)
+		resolved, err := resolver.Resolve(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, resolver.Shutdown(context.Background()))
+		return resolved.ToStringMap()
+	}
+
+	assert.Equal(t, map[string]any{
+		"reference": "${env:DOLLAR_TEST}",
+		"escaped":   "pa$$word",
+		"inline":    "Bearer pa$word$ and expanded",
+		"map":       map[string]any{"user": "${env:DOLLAR_TEST}", "list": []any{"$a", "b"}},
+		"expanded":  "expanded",
+	}, resolve(WithDollarSignEscaping(true)))
+
+	// Without escaping, the resolver expands references in credentials and unescapes `$$`.
+	resolved := resolve()
+	assert.Equal(t, "expanded", resolved["reference"])
+	assert.Equal(t, "pa$word", resolved["escaped"])
+}
+
+func TestEscapeDollarSigns(t *testing.T) {
+	closed := false
+	ret, err := confmap.NewRetrieved("no dollar signs", confmap.WithRetrievedClose(func(context.Context) error {
+		closed = true
+		return nil
+	}))
+	require.NoError(t, err)
+	escaped, err := escapeDollarSigns(ret)
+	require.NoError(t, err)
+	assert.Same(t, ret, escaped)
+
+	ret, err = confmap.NewRetrieved("$1", confmap.WithRetrievedClose(func(context.Context) error {
+		closed = true
+		return nil
+	}))
+	require.NoError(t, err)
+	escaped, err = escapeDollarSigns(ret)
+	require.NoError(t, err)
+	str, err := escaped.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "$$1", str)
+	// Closing the escaped value closes the original one, which wipes the credential.
+	require.NoError(t, escaped.Close(context.Background()))
+	assert.True(t, closed)
+}
@@ -0,0 +1,73 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+// dropInInfix separates the name of a credential from the suffix of its drop-ins. Credential
+// names can't contain '/', so unlike systemd's NAME.d/ directories, drop-ins are credentials
+// named NAME.d-SUFFIX, e.g. `otlp.d-10-override`.
+const dropInInfix = ".d-"
+
+// withDropIns returns credNames with the drop-ins of each credential following it in lexical
+// order, and the credentials that have drop-ins, which may be missing.
+func (p *provider) withDropIns(credNames []string) ([]string, map[string]bool, error) {
+	fsys, credDir, exists := p.credentialsFS()
+	if !exists {
+		return credNames, nil, nil
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list drop-ins in %q: %w", credDir, err)
+	}
+	var names []string
+	overridden := map[string]bool{}
+	for _, credName := range credNames {
+		names = append(names, credName)
+		// The entries are sorted by name already.
+		for _, entry := range entries {
+			name := entry.Name()
+			if !strings.HasPrefix(name, credName+dropInInfix) || entry.IsDir() {
+				continue
+			}
+			if err := p.checkAllowed(name); err != nil {
+				return nil, nil, err
+			}
+			names = append(names, name)
+			overridden[credName] = true
+		}
+	}
+	return names, overridden, nil
+}
+
+// mergeDropIns returns vals, the contents of a credential and its drop-ins, each converted
+// with content and parsed as a JSON or YAML mapping, deep-merged in order: later maps override
+// the values of earlier ones, nested maps are merged and lists are replaced.
+func mergeDropIns(vals [][]byte, content func([]byte) (string, error), opts ...confmap.RetrievedOption) (*confmap.Retrieved, error) {
+	merged := confmap.New()
+	for _, val := range vals {
+		str, err := content(val)
+		if err != nil {
+			return nil, err
+		}
+		ret, err := retrievedMap(str)
+		if err != nil {
+			return nil, err
+		}
+		conf, err := ret.AsConf()
+		if err != nil {
+			return nil, err
+		}
+		if err := merged.Merge(conf); err != nil {
+			return nil, err
+		}
+	}
+	return confmap.NewRetrieved(merged.ToStringMap(), opts...)
+}
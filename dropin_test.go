@@ -0,0 +1,61 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestDropIns(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	for name, content := range map[string]string{
+		"otlp":                 "endpoint: https://otlp.example.com\nheaders:\n  x-tenant: a\ntls:\n  insecure: false\n",
+		"otlp.d-10-override":   "headers:\n  x-tenant: b\n  x-team: c\n",
+		"otlp.d-00-base":       "tls:\n  insecure: true\n  ca_file: /etc/ca.pem\nattributes: [a, b]\n",
+		"otlp.d-20-lists":      "attributes: [c]\n",
+		"otlpx.d-00-unrelated": "unrelated: true\n",
+		"scopes.d-00-read":     "read",
+		"scopes.d-10-write":    "write\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(credDir, name), []byte(content), 0600))
+	}
+
+	prov := NewFactory().Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"otlp?dropins=true&format=map", nil)
+	require.NoError(t, err)
+	raw, err := ret.AsRaw()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"endpoint":   "https://otlp.example.com",
+		"headers":    map[string]any{"x-tenant": "b", "x-team": "c"},
+		"tls":        map[string]any{"insecure": true, "ca_file": "/etc/ca.pem"},
+		"attributes": []any{"c"},
+	}, raw)
+
+	// The credential itself may be missing when it has drop-ins.
+	ret, err = prov.Retrieve(context.Background(), credSchemePrefix+"scopes?dropins=true", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "read\nwrite", str)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"scopes", nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"missing?dropins=true", nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	prov = NewFactory(WithDeniedCredentials("*.d-10-*")).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"scopes?dropins=true", nil)
+	assert.ErrorIs(t, err, ErrNotAllowed)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
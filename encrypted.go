@@ -0,0 +1,160 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultSystemdCredsCommand is the command used to decrypt encrypted credentials, see WithSystemdCredsCommand.
+	defaultSystemdCredsCommand = "systemd-creds"
+	// defaultCredentialSecretPath is where systemd stores the host key used to encrypt credentials.
+	defaultCredentialSecretPath = "/var/lib/systemd/credential.secret"
+	// credentialSecretEnv overrides the path of the host key, like it does for systemd-creds.
+	credentialSecretEnv = "SYSTEMD_CREDENTIAL_SECRET"
+)
+
+// hostKeyCredentialID identifies credentials encrypted with the host key only,
+// i.e. with `systemd-creds encrypt --with-key=host`.
+var hostKeyCredentialID = []byte{0x5a, 0x1c, 0x6a, 0x86, 0xdf, 0x9d, 0x40, 0x96, 0xb1, 0xd5, 0xa6, 0x5e, 0x08, 0x62, 0xf1, 0x9a}
+
+// decrypt decrypts val, the content of an encrypted credential called name. This allows resolving
+// credentials that are normally passed with `LoadCredentialEncrypted=` when the collector is run
+// outside of its unit. Credentials encrypted with the host key are decrypted natively, others
+// (such as TPM2-bound ones) through the io.systemd.Credentials varlink service if configured,
+// or with `systemd-creds decrypt`.
+func (p *provider) decrypt(ctx context.Context, name string, val []byte) ([]byte, error) {
+	blob := val
+	if !bytes.HasPrefix(blob, hostKeyCredentialID) {
+		// Encrypted credentials are usually stored base64-encoded.
+		if decoded, err := decodeBase64(val); err == nil {
+			blob = decoded
+		}
+	}
+	if bytes.HasPrefix(blob, hostKeyCredentialID) {
+		return p.decryptWithHostKey(name, blob)
+	}
+	if p.cfg.varlinkSocket != "" {
+		return p.decryptWithVarlink(ctx, name, blob)
+	}
+	return p.decryptWithSystemdCreds(ctx, name, val)
+}
+
+// decryptWithHostKey decrypts blob, a credential encrypted with the host key, following the format
+// used by systemd: a header, the AES-256-GCM encrypted metadata and data, and the GCM tag. The header
+// is authenticated as additional data and the key is the SHA-256 of the host key.
+func (p *provider) decryptWithHostKey(name string, blob []byte) ([]byte, error) {
+	const headerFixedSize = 32 // id, key size, block size, IV size and tag size
+	if len(blob) < headerFixedSize {
+		return nil, errors.New("encrypted credential is truncated")
+	}
+	keySize := binary.LittleEndian.Uint32(blob[16:])
+	blockSize := binary.LittleEndian.Uint32(blob[20:])
+	ivSize := binary.LittleEndian.Uint32(blob[24:])
+	tagSize := binary.LittleEndian.Uint32(blob[28:])
+	if keySize != sha256.Size || blockSize != 1 || ivSize == 0 || ivSize > 4096 || tagSize != 16 {
+		return nil, errors.New("encrypted credential has an unsupported header")
+	}
+	headerSize := align8(headerFixedSize + int(ivSize))
+	if len(blob) < headerSize+int(tagSize) {
+		return nil, errors.New("encrypted credential is truncated")
+	}
+
+	secret, err := os.ReadFile(p.credentialSecretPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read host key: %w", err)
+	}
+	// The host key file starts with a 16 byte machine ID, followed by the secret itself.
+	if len(secret) <= 16 {
+		return nil, errors.New("host key is too short")
+	}
+	key := sha256.Sum256(secret[16:])
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, int(ivSize))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, blob[headerFixedSize:headerFixedSize+ivSize], blob[headerSize:], blob[:headerSize])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt with host key: %w", err)
+	}
+
+	// The plaintext starts with the metadata: timestamp, expiry and the embedded credential name.
+	const metadataFixedSize = 20
+	if len(plaintext) < metadataFixedSize {
+		return nil, errors.New("encrypted credential metadata is truncated")
+	}
+	notAfter := binary.LittleEndian.Uint64(plaintext[8:])
+	nameSize := binary.LittleEndian.Uint32(plaintext[16:])
+	if uint64(nameSize) > uint64(len(plaintext)-metadataFixedSize) {
+		return nil, errors.New("encrypted credential metadata is truncated")
+	}
+	metadataSize := align8(metadataFixedSize + int(nameSize))
+	if metadataSize > len(plaintext) {
+		return nil, errors.New("encrypted credential metadata is truncated")
+	}
+	if embedded := string(plaintext[metadataFixedSize : metadataFixedSize+nameSize]); embedded != "" && embedded != name {
+		return nil, fmt.Errorf("embedded credential name %q does not match %q", embedded, name)
+	}
+	if notAfter != math.MaxUint64 && uint64(time.Now().UnixMicro()) > notAfter {
+		return nil, fmt.Errorf("credential expired at %s", time.UnixMicro(int64(notAfter)).UTC().Format(time.RFC3339))
+	}
+	return plaintext[metadataSize:], nil
+}
+
+// credentialSecretPath returns the path of the host key.
+func (p *provider) credentialSecretPath() string {
+	if p.cfg.credentialSecretPath != "" {
+		return p.cfg.credentialSecretPath
+	}
+	if path, ok := os.LookupEnv(credentialSecretEnv); ok {
+		return path
+	}
+	return defaultCredentialSecretPath
+}
+
+func align8(n int) int {
+	return (n + 7) &^ 7
+}
+
+// decryptWithSystemdCreds decrypts val by running `systemd-creds decrypt`.
+func (p *provider) decryptWithSystemdCreds(ctx context.Context, name string, val []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"decrypt", "--name=" + name, "-", "-"}
+	if _, user := p.serviceUnit(); user {
+		// Credentials of user services are encrypted with `systemd-creds encrypt --user`.
+		args = append([]string{"--user"}, args...)
+	}
+	cmd := exec.CommandContext(ctx, p.cfg.systemdCredsCommand, args...)
+	cmd.Stdin = bytes.NewReader(val)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("%s is not available, it is needed to decrypt encrypted credentials: %w", p.cfg.systemdCredsCommand, err)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			// systemd-creds reports a missing host key or TPM on stderr.
+			return nil, fmt.Errorf("%s decrypt failed: %s: %w", p.cfg.systemdCredsCommand, msg, err)
+		}
+		return nil, fmt.Errorf("%s decrypt failed: %w", p.cfg.systemdCredsCommand, err)
+	}
+	return stdout.Bytes(), nil
+}
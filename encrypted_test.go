@@ -0,0 +1,120 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+// fakeSystemdCreds writes a script standing in for systemd-creds, which "decrypts" by
+// reversing its input and fails for credentials named "nokey".
+func fakeSystemdCreds(t *testing.T) string {
+	script := filepath.Join(t.TempDir(), "systemd-creds")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+[ "$1" = decrypt ] || exit 2
+if [ "$2" = --name=nokey ]; then
+	echo "Failed to determine local credential key: No such file or directory" >&2
+	exit 1
+fi
+rev
+`), 0700))
+	return script
+}
+
+func TestEncryptedCredential(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("54321-nekot-terces-ym\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "nokey"), []byte("blob"), 0600))
+
+	prov := NewFactory(WithSystemdCredsCommand(fakeSystemdCreds(t))).Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token?encrypted=true", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"nokey?encrypted=true", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed to determine local credential key")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestEncryptedCredentialWithoutSystemdCreds(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("blob"), 0600))
+
+	prov := NewFactory(WithSystemdCredsCommand("systemd-creds-does-not-exist")).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token?encrypted=true", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "systemd-creds-does-not-exist is not available")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestHostKeyEncryptedCredential(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	encoded, err := os.ReadFile(filepath.Join("testdata", "api_token.cred"))
+	require.NoError(t, err)
+	binaryBlob, err := decodeBase64(encoded)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), encoded, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "binary_token"), binaryBlob, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "renamed_token"), encoded, 0600))
+	expired, err := os.ReadFile(filepath.Join("testdata", "expired_token.cred"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "expired_token"), expired, 0600))
+
+	// The command must not be needed for credentials encrypted with the host key.
+	prov := NewFactory(
+		WithCredentialSecretPath(filepath.Join("testdata", "credential.secret")),
+		WithSystemdCredsCommand("systemd-creds-does-not-exist"),
+	).Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token?encrypted=true", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+
+	// The embedded name is checked against the name of the credential being read.
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"renamed_token?encrypted=true", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `embedded credential name "api_token" does not match "renamed_token"`)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"expired_token?encrypted=true", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "credential expired at 2021-01-01T00:00:00Z")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	t.Setenv(credentialSecretEnv, filepath.Join(credDir, "missing.secret"))
+	prov = createProvider()
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token?encrypted=true", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read host key")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestHostKeyEncryptedCredentialWrongKey(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	encoded, err := os.ReadFile(filepath.Join("testdata", "api_token.cred"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), encoded, 0600))
+	secretPath := filepath.Join(credDir, "credential.secret")
+	require.NoError(t, os.WriteFile(secretPath, make([]byte, 4112), 0600))
+
+	prov := NewFactory(WithCredentialSecretPath(secretPath)).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token?encrypted=true", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decrypt with host key")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
@@ -0,0 +1,47 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"fmt"
+	"io/fs"
+
+	"go.opentelemetry.io/collector/confmap"
+
+	"bou.ke/systemdcredentialprovider/internal/envfile"
+)
+
+const envFileSchemeName = "systemdenvfile"
+
+// NewEnvFileFactory returns a factory for a confmap.Provider that reads individual variables
+// from a file in the format of systemd's EnvironmentFile=, for deployments that ship env files
+// and can't switch to one credential per secret at once.
+//
+// This Provider supports "systemdenvfile" scheme, and can be called with a selector:
+// `systemdenvfile:CREDENTIAL_NAME#VARIABLE`
+//
+// The env file is the credential called CREDENTIAL_NAME, read like the provider returned by
+// NewFactory does, or the file registered for CREDENTIAL_NAME with WithEnvFilePath. URI options
+// go before the '#', e.g. `systemdenvfile:app_env?optional=true#DATABASE_URL`, and apply to the
+// variable; `encrypted=true` decrypts the whole file. A variable that isn't set is handled
+// like a missing credential.
+func NewEnvFileFactory(opts ...Option) confmap.ProviderFactory {
+	defaults := optionFunc(func(cfg *config) {
+		cfg.scheme = envFileSchemeName
+		cfg.envFile = true
+	})
+	cfg := newConfig(append([]Option{defaults}, opts...))
+	return confmap.NewProviderFactory(func(ps confmap.ProviderSettings) confmap.Provider {
+		return newProvider(ps, cfg)
+	})
+}
+
+// envFileValue returns the value of the variable key in the env file content.
+func envFileValue(content []byte, key string) ([]byte, error) {
+	val, ok := envfile.Parse(string(content))[key]
+	if !ok {
+		return nil, fmt.Errorf("variable %q is not set: %w", key, fs.ErrNotExist)
+	}
+	return []byte(val), nil
+}
@@ -0,0 +1,65 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+const envFileSchemePrefix = envFileSchemeName + ":"
+
+func TestEnvFile(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "app_env"), []byte("DATABASE_URL=postgres://db/app\nAPI_TOKEN="+testCredValue+"\n"), 0600))
+	fixedPath := filepath.Join(t.TempDir(), "otelcol")
+	require.NoError(t, os.WriteFile(fixedPath, []byte("export_endpoint=https://example.com\n"), 0600))
+
+	prov := NewEnvFileFactory(WithCredentialsDirectory(credDir), WithEnvFilePath("defaults", fixedPath)).Create(confmaptest.NewNopProviderSettings())
+	assert.Equal(t, "systemdenvfile", prov.Scheme())
+	for uri, expected := range map[string]string{
+		"app_env#DATABASE_URL":               "postgres://db/app",
+		"app_env#API_TOKEN":                  testCredValue,
+		"defaults#export_endpoint":           "https://example.com",
+		"app_env?default=none#MISSING":       "none",
+		"app_env:-none#MISSING":              "none",
+		"missing?optional=true#DATABASE_URL": "",
+	} {
+		ret, err := prov.Retrieve(context.Background(), envFileSchemePrefix+uri, nil)
+		require.NoError(t, err, uri)
+		str, err := ret.AsString()
+		require.NoError(t, err)
+		assert.Equal(t, expected, str, uri)
+	}
+
+	_, err := prov.Retrieve(context.Background(), envFileSchemePrefix+"app_env#MISSING", nil)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.ErrorContains(t, err, `variable "MISSING" is not set`)
+	_, err = prov.Retrieve(context.Background(), envFileSchemePrefix+"app_env", nil)
+	assert.ErrorContains(t, err, "must select a valid variable name")
+	_, err = prov.Retrieve(context.Background(), envFileSchemePrefix+"app_env#NOT-VALID", nil)
+	assert.ErrorContains(t, err, "must select a valid variable name")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestEnvFileCustomScheme(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "app_env"), []byte("DATABASE_URL=postgres://db/app\n"), 0600))
+
+	prov := NewEnvFileFactory(WithCredentialsDirectory(credDir), WithScheme("envf")).Create(confmaptest.NewNopProviderSettings())
+	assert.Equal(t, "envf", prov.Scheme())
+	ret, err := prov.Retrieve(context.Background(), "envf:app_env#DATABASE_URL", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://db/app", str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
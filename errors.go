@@ -0,0 +1,57 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import "errors"
+
+var (
+	// ErrNoCredentialsDirectory is returned when no credentials directory is configured and none
+	// could be discovered.
+	ErrNoCredentialsDirectory = errors.New("CREDENTIALS_DIRECTORY environment variable is not set")
+	// ErrInvalidName is returned when a credential name is invalid or reserved.
+	ErrInvalidName = errors.New("invalid credential name")
+	// ErrNotFound is returned when a credential, or every source of a fallback chain, doesn't exist.
+	// The error also wraps the cause, e.g. fs.ErrNotExist or ErrNoCredentialsDirectory.
+	ErrNotFound = errors.New("credential not found")
+	// ErrTooLarge is returned when a credential exceeds the maximum size, see WithMaxSize.
+	ErrTooLarge = errors.New("credential is too large")
+	// ErrInsecurePermissions is returned when a credential file can be accessed by other users,
+	// see WithPermissionCheck.
+	ErrInsecurePermissions = errors.New("credential file has insecure permissions")
+	// ErrNotAllowed is returned when a credential may not be read, see WithAllowedCredentials
+	// and WithDeniedCredentials.
+	ErrNotAllowed = errors.New("credential is not allowed")
+	// ErrChecksumMismatch is returned when a credential doesn't match its checksum sidecar, or
+	// the sidecar is missing, see WithChecksumVerification.
+	ErrChecksumMismatch = errors.New("credential doesn't match its checksum")
+	// ErrInvalidSignature is returned when a credential isn't signed, or not by a trusted key,
+	// see WithSignatureVerification.
+	ErrInvalidSignature = errors.New("credential has no valid signature")
+	// ErrInvalidContent is returned when a credential contains control characters, NUL bytes or
+	// invalid UTF-8, see WithStrictContent.
+	ErrInvalidContent = errors.New("credential has invalid content")
+)
+
+// kindError marks err as being of kind, one of the exported errors, without changing its message.
+type kindError struct {
+	kind error
+	err  error
+}
+
+// withKind returns err marked as kind, so that both errors.Is(err, kind) and errors.Is on the
+// errors wrapped by err succeed.
+func withKind(kind, err error) error {
+	if err == nil || errors.Is(err, kind) {
+		return err
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
@@ -0,0 +1,78 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "large"), []byte("0123456789"), 0600))
+	t.Setenv(credentialsDirectoryEnv, credDir)
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		uri     string
+		wantErr []error
+	}{
+		{
+			name:    "not found",
+			uri:     credSchemePrefix + "missing",
+			wantErr: []error{ErrNotFound, fs.ErrNotExist},
+		},
+		{
+			name:    "no credentials directory",
+			opts:    []Option{WithCredentialsDirectoryEnv("UNSET_CREDENTIALS_DIRECTORY")},
+			uri:     credSchemePrefix + "missing",
+			wantErr: []error{ErrNotFound, ErrNoCredentialsDirectory},
+		},
+		{
+			name:    "fallback chain",
+			uri:     credSchemePrefix + "missing|env:UNSET_CREDENTIAL_VARIABLE",
+			wantErr: []error{ErrNotFound, fs.ErrNotExist},
+		},
+		{
+			name:    "invalid name",
+			uri:     credSchemePrefix + "../name",
+			wantErr: []error{ErrInvalidName},
+		},
+		{
+			name:    "too large",
+			opts:    []Option{WithMaxSize(4)},
+			uri:     credSchemePrefix + "large",
+			wantErr: []error{ErrTooLarge},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prov := NewFactory(tt.opts...).Create(confmaptest.NewNopProviderSettings())
+			_, err := prov.Retrieve(context.Background(), tt.uri, nil)
+			require.Error(t, err)
+			for _, want := range tt.wantErr {
+				assert.ErrorIs(t, err, want)
+			}
+			assert.NoError(t, prov.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestWithKind(t *testing.T) {
+	err := withKind(ErrNotFound, fs.ErrNotExist)
+	assert.Equal(t, fs.ErrNotExist.Error(), err.Error())
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.NotErrorIs(t, err, ErrTooLarge)
+	assert.Same(t, err, withKind(ErrNotFound, err))
+	assert.NoError(t, withKind(ErrNotFound, nil))
+}
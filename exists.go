@@ -0,0 +1,107 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.uber.org/zap"
+)
+
+// retrieveExists resolves ref, a `?exists=true` reference, to whether all of its credentials
+// exist. The credentials are looked up in the same places they are read from, but only
+// credentials in the SMBIOS OEM strings have to be read to be found.
+func (p *provider) retrieveExists(uri string, ref *reference) (*confmap.Retrieved, error) {
+	if ref.opts != (uriOptions{exists: true}) || ref.key != "" || ref.pem != nil {
+		return nil, fmt.Errorf("uri %q must not combine exists with other options", uri)
+	}
+	exists := true
+	for _, name := range ref.names {
+		if strings.Contains(name, bundleSeparator) {
+			return nil, fmt.Errorf("uri %q must not combine exists with bundle members", uri)
+		}
+		credName, err := p.credentialName(name)
+		if err != nil {
+			return nil, err
+		}
+		source, err := p.statCredential(credName)
+		p.cfg.logger.Debug("Looked up credential",
+			zap.String("credential", credName), zap.String("path", source), zap.Bool("found", err == nil))
+		if isMissing(err) {
+			exists = false
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return confmap.NewRetrieved(exists)
+}
+
+// lookupMode selects what lookupCredential does with the credential it finds.
+type lookupMode int
+
+const (
+	// lookupRead reads the credential.
+	lookupRead lookupMode = iota
+	// lookupStat only checks that the credential exists and is a regular file, without reading it.
+	lookupStat
+)
+
+// errNotRegularFile is returned when looking up credentials that aren't regular files.
+var errNotRegularFile = errors.New("credential is not a regular file")
+
+// statCredential looks up the credential called name like readCredential does, without reading
+// it, and returns where it was found.
+func (p *provider) statCredential(name string) (string, error) {
+	_, source, err := p.lookupCredential(name, lookupStat)
+	return source, err
+}
+
+// lookupFile reads the credential file called name from fsys with lookupRead, see
+// readCredentialFile, and checks that it is a regular file with lookupStat.
+func (p *provider) lookupFile(fsys fs.FS, name, credPath string, mode lookupMode) ([]byte, error) {
+	if mode == lookupRead {
+		return p.readCredentialFile(fsys, name, credPath)
+	}
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, &fs.PathError{Op: "stat", Path: credPath, Err: errNotRegularFile}
+	}
+	return nil, nil
+}
+
+// lookupOSFile is like lookupFile for the file at path.
+func (p *provider) lookupOSFile(path string, mode lookupMode) ([]byte, error) {
+	if mode == lookupRead {
+		return readOSFile(path, p.cfg.maxSize)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, &fs.PathError{Op: "stat", Path: path, Err: errNotRegularFile}
+	}
+	return nil, nil
+}
+
+// statFDCredential checks that a file descriptor named name was passed, see readFDCredential,
+// and returns which one.
+func (p *provider) statFDCredential(name string) (string, error) {
+	fd, ok := p.listenFD(name)
+	if !ok {
+		return "", fmt.Errorf("no file descriptor named %q was passed in $%s: %w", name, listenFDNamesEnv, fs.ErrNotExist)
+	}
+	return "fd " + strconv.Itoa(fd), nil
+}
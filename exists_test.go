@@ -0,0 +1,86 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestExists(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	// An unreadable credential exists even though it can't be read.
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "unreadable"), []byte(testCredValue), 0))
+
+	prov := NewFactory(WithAliases(map[string]string{"token": "api_token"})).Create(confmaptest.NewNopProviderSettings())
+	for _, tt := range []struct {
+		uri    string
+		exists bool
+	}{
+		{uri: "api_token?exists=true", exists: true},
+		{uri: "token?exists=true", exists: true},
+		{uri: "unreadable?exists=true", exists: true},
+		{uri: "missing?exists=true", exists: false},
+		{uri: "api_token+missing?exists=true", exists: false},
+	} {
+		t.Run(tt.uri, func(t *testing.T) {
+			ret, err := prov.Retrieve(context.Background(), credSchemePrefix+tt.uri, nil)
+			require.NoError(t, err)
+			raw, err := ret.AsRaw()
+			require.NoError(t, err)
+			assert.Equal(t, tt.exists, raw)
+		})
+	}
+
+	for _, uri := range []string{"api_token?exists=true&optional=true", "api_token?exists=true&raw=true", "api_token?exists=true#index=0"} {
+		_, err := prov.Retrieve(context.Background(), credSchemePrefix+uri, nil)
+		assert.ErrorContains(t, err, "must not combine exists with other options", uri)
+	}
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"bad:name?exists=true", nil)
+	assert.ErrorIs(t, err, ErrInvalidName)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestExistsDirectory(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.Mkdir(filepath.Join(credDir, "api_token"), 0700))
+
+	prov := NewFactory().Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token?exists=true", nil)
+	assert.ErrorContains(t, err, "not a regular file")
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	assert.Error(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestExistsWithoutCredentialsDirectory(t *testing.T) {
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+	require.NoError(t, os.Unsetenv("CREDENTIALS_DIRECTORY"))
+	t.Setenv("OTEL_api_token", testCredValue)
+
+	prov := NewFactory().Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token?exists=true", nil)
+	require.NoError(t, err)
+	raw, err := ret.AsRaw()
+	require.NoError(t, err)
+	assert.Equal(t, false, raw)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	prov = NewFactory(WithEnvFallback("OTEL_")).Create(confmaptest.NewNopProviderSettings())
+	ret, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token?exists=true", nil)
+	require.NoError(t, err)
+	raw, err = ret.AsRaw()
+	require.NoError(t, err)
+	assert.Equal(t, true, raw)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
@@ -0,0 +1,92 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// maxExpandDepth is the maximum number of nested references expanded from credential contents.
+const maxExpandDepth = 8
+
+// expand resolves the `${env:NAME}` and `${systemdcredential:...}` references in val.
+// stack holds the URIs whose contents are currently being expanded, to detect cycles.
+// `$$` is an escaped `$`, like in the collector configuration.
+// Errors only report the byte offset of the offending reference, as val holds credential contents.
+func (p *provider) expand(ctx context.Context, val string, stack []string) (string, error) {
+	var b strings.Builder
+	// offset is the offset of val in the original value.
+	offset := 0
+	for {
+		i := strings.IndexByte(val, '$')
+		if i < 0 || i == len(val)-1 {
+			b.WriteString(val)
+			return b.String(), nil
+		}
+		b.WriteString(val[:i])
+		switch val[i+1] {
+		case '$':
+			b.WriteByte('$')
+			val = val[i+2:]
+			offset += i + 2
+		case '{':
+			end := strings.IndexByte(val[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated reference at byte %d", offset+i)
+			}
+			resolved, err := p.expandReference(ctx, val[i+2:i+end], stack)
+			if err != nil {
+				return "", fmt.Errorf("reference at byte %d: %w", offset+i, err)
+			}
+			b.WriteString(resolved)
+			val = val[i+end+1:]
+			offset += i + end + 1
+		default:
+			b.WriteByte('$')
+			val = val[i+1:]
+			offset += i + 1
+		}
+	}
+}
+
+// expandReference resolves a single reference found in a credential, without the surrounding `${` and `}`.
+func (p *provider) expandReference(ctx context.Context, uri string, stack []string) (string, error) {
+	scheme, rest, ok := strings.Cut(uri, ":")
+	if !ok {
+		return "", errors.New("reference does not have a scheme")
+	}
+	switch scheme {
+	case "env":
+		if err := p.checkUnrestricted("env"); err != nil {
+			return "", err
+		}
+		// Like the env provider, unset variables resolve to their default value or an empty string.
+		name, defaultValue, _ := strings.Cut(rest, ":-")
+		if val, ok := os.LookupEnv(name); ok {
+			return val, nil
+		}
+		return defaultValue, nil
+	case p.cfg.scheme:
+		if slices.Contains(stack, uri) {
+			return "", fmt.Errorf("reference cycle detected: %s -> %s", strings.Join(stack, " -> "), uri)
+		}
+		if len(stack) >= maxExpandDepth {
+			return "", fmt.Errorf("references nested deeper than %d levels: %s -> %s", maxExpandDepth, strings.Join(stack, " -> "), uri)
+		}
+		ret, err := p.retrieve(ctx, uri, stack)
+		if err != nil {
+			return "", err
+		}
+		// The value is copied into the expanded credential, so its buffers can be wiped right away.
+		val, err := ret.AsString()
+		return val, errors.Join(err, ret.Close(ctx))
+	default:
+		return "", fmt.Errorf("reference uses an unsupported scheme, only %q and %q can be expanded", "env", p.cfg.scheme)
+	}
+}
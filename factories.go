@@ -0,0 +1,69 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"context"
+	"slices"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.uber.org/zap"
+)
+
+// defaultResolverScheme is the scheme the collector uses for references without a scheme.
+const defaultResolverScheme = "env"
+
+// NewFactories returns the factories of every provider in this module that works without
+// deployment-specific settings, so that a collector distribution can register them at once and
+// picks up schemes added later:
+//
+//	ProviderFactories: append(defaultProviders, systemdcredentialprovider.NewFactories()...)
+//
+// These are the "systemdcredential", "systemdenvfile", "systemdfd", "containersecret" and
+// "systemdmeta" schemes. opts apply to all of them; note that WithCredentialsDirectory also moves the
+// directory container secrets are read from. WithScheme is ignored, as it would register all of
+// them under the same scheme; create aliases with the individual factories instead, e.g.
+// NewFactory(WithScheme("cred")). The "filecredential" and "kubernetessecret"
+// schemes need the paths to read from, add them with NewFileCredentialFactory and
+// NewKubernetesSecretFactory.
+func NewFactories(opts ...Option) []confmap.ProviderFactory {
+	opts = slices.DeleteFunc(slices.Clone(opts), func(opt Option) bool {
+		_, ok := opt.(schemeOption)
+		return ok
+	})
+	return []confmap.ProviderFactory{
+		NewFactory(opts...),
+		NewEnvFileFactory(opts...),
+		NewFDFactory(opts...),
+		NewContainerSecretFactory(opts...),
+		NewMetaFactory(opts...),
+	}
+}
+
+// NewResolverSettings returns the settings of a confmap.Resolver for the configurations at
+// uris, using the providers of defaults followed by the ones returned by NewFactories(opts...).
+// defaults are the providers a distribution registers anyway, such as the env, file, http,
+// https and yaml providers of the collector. Like the collector does, the "env" scheme is
+// the default scheme if one of defaults provides it. The settings can be used as the
+// ResolverSettings of an otelcol.ConfigProviderSettings:
+//
+//	ConfigProviderSettings: otelcol.ConfigProviderSettings{
+//		ResolverSettings: systemdcredentialprovider.NewResolverSettings(uris, defaultProviders),
+//	}
+func NewResolverSettings(uris []string, defaults []confmap.ProviderFactory, opts ...Option) confmap.ResolverSettings {
+	set := confmap.ResolverSettings{
+		URIs:              slices.Clone(uris),
+		ProviderFactories: append(slices.Clone(defaults), NewFactories(opts...)...),
+	}
+	for _, factory := range defaults {
+		prov := factory.Create(confmap.ProviderSettings{Logger: zap.NewNop()})
+		scheme := prov.Scheme()
+		_ = prov.Shutdown(context.Background())
+		if scheme == defaultResolverScheme {
+			set.DefaultScheme = defaultResolverScheme
+			break
+		}
+	}
+	return set
+}
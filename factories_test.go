@@ -0,0 +1,118 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestNewFactories(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "app_env"), []byte("TOKEN="+testCredValue+"\n"), 0600))
+
+	var schemes []string
+	providers := map[string]confmap.Provider{}
+	// WithScheme would register every provider under the same scheme, so it is ignored.
+	for _, factory := range NewFactories(WithCredentialsDirectory(credDir), WithScheme("cred")) {
+		prov := factory.Create(confmaptest.NewNopProviderSettings())
+		schemes = append(schemes, prov.Scheme())
+		providers[prov.Scheme()] = prov
+	}
+	assert.Equal(t, []string{"systemdcredential", "systemdenvfile", "systemdfd", "containersecret", "systemdmeta"}, schemes)
+
+	for uri, prov := range map[string]confmap.Provider{
+		"systemdcredential:api_token":  providers["systemdcredential"],
+		"systemdenvfile:app_env#TOKEN": providers["systemdenvfile"],
+		"containersecret:api_token":    providers["containersecret"],
+	} {
+		ret, err := prov.Retrieve(context.Background(), uri, nil)
+		require.NoError(t, err, uri)
+		str, err := ret.AsString()
+		require.NoError(t, err)
+		assert.Equal(t, testCredValue, str, uri)
+	}
+	for _, prov := range providers {
+		assert.NoError(t, prov.Shutdown(context.Background()))
+	}
+}
+
+func TestNewResolverSettings(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	t.Setenv("ENDPOINT", "https://otlp.example.com")
+	conf := map[string]any{
+		"endpoint": "${ENDPOINT}",
+		"token":    "${systemdcredential:api_token}",
+	}
+	defaults := []confmap.ProviderFactory{
+		confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider { return staticProvider{conf: conf} }),
+		confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider { return envProvider{} }),
+	}
+
+	set := NewResolverSettings([]string{"static:config"}, defaults, WithCredentialsDirectory(credDir))
+	assert.Equal(t, "env", set.DefaultScheme)
+	assert.Len(t, set.ProviderFactories, 2+len(NewFactories()))
+	resolver, err := confmap.NewResolver(set)
+	require.NoError(t, err)
+	resolved, err := resolver.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"endpoint": "https://otlp.example.com", "token": testCredValue}, resolved.ToStringMap())
+	require.NoError(t, resolver.Shutdown(context.Background()))
+
+	assert.Empty(t, NewResolverSettings(nil, defaults[:1]).DefaultScheme)
+}
+
+// envProvider stands in for the collector's env provider.
+type envProvider struct{}
+
+func (envProvider) Retrieve(_ context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	return confmap.NewRetrieved(os.Getenv(strings.TrimPrefix(uri, "env:")))
+}
+
+func (envProvider) Scheme() string {
+	return "env"
+}
+
+func (envProvider) Shutdown(context.Context) error {
+	return nil
+}
+
+func TestWithScheme(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	conf := map[string]any{
+		"a": "${systemdcredential:api_token}",
+		"b": "${cred:api_token}",
+		"c": "${systemd-credential:missing|systemd-credential:api_token}",
+	}
+	resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+		URIs: []string{"static:config"},
+		ProviderFactories: []confmap.ProviderFactory{
+			confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider { return staticProvider{conf: conf} }),
+			NewFactory(WithCredentialsDirectory(credDir)),
+			NewFactory(WithCredentialsDirectory(credDir), WithScheme("cred")),
+			NewFactory(WithCredentialsDirectory(credDir), WithScheme("systemd-credential")),
+		},
+	})
+	require.NoError(t, err)
+	resolved, err := resolver.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": testCredValue, "b": testCredValue, "c": testCredValue}, resolved.ToStringMap())
+	require.NoError(t, resolver.Shutdown(context.Background()))
+
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithScheme("cred")).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), "systemdcredential:api_token", nil)
+	assert.Error(t, err, "the default scheme isn't accepted by a provider registered under an alias")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
@@ -0,0 +1,116 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.uber.org/zap"
+)
+
+// fallbackSeparator separates the sources of a fallback chain.
+const fallbackSeparator = "|"
+
+// errEnvNotSet is returned for `env:` sources of a fallback chain whose variable is not set.
+var errEnvNotSet = errors.New("environment variable is not set")
+
+// retrieveFallback retrieves the sources of a fallback chain such as
+// `systemdcredential:TOKEN|env:TOKEN|file:/etc/otel/token` in order, returning the
+// first one that exists. Any other error stops the chain.
+func (p *provider) retrieveFallback(ctx context.Context, uri string, stack []string) (*confmap.Retrieved, error) {
+	var errs []error
+	for _, source := range strings.Split(uri, fallbackSeparator) {
+		ret, err := p.retrieveSource(ctx, source, stack)
+		if err == nil {
+			return ret, nil
+		}
+		if !isMissing(err) {
+			return nil, err
+		}
+		p.cfg.logger.Debug("Fallback source does not exist, trying the next one", zap.String("source", source))
+		errs = append(errs, err)
+	}
+	return nil, withKind(ErrNotFound, fmt.Errorf("none of the sources in %q exist: %w", uri, errors.Join(errs...)))
+}
+
+// retrieveSource retrieves a single source of a fallback chain.
+func (p *provider) retrieveSource(ctx context.Context, source string, stack []string) (*confmap.Retrieved, error) {
+	scheme, rest, _ := strings.Cut(source, ":")
+	switch scheme {
+	case p.cfg.scheme:
+		return p.retrieveCredential(ctx, source, stack)
+	case "env":
+		if err := p.checkUnrestricted("env"); err != nil {
+			return nil, err
+		}
+		val, ok := os.LookupEnv(rest)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", errEnvNotSet, rest)
+		}
+		return confmap.NewRetrieved(val)
+	case "file":
+		if !p.cfg.fileFallback {
+			return nil, fmt.Errorf("source %q in fallback chain is not allowed: file sources have to be enabled with WithFileFallback", source)
+		}
+		if err := p.checkUnrestricted("file"); err != nil {
+			return nil, err
+		}
+		return p.retrieveFile(ctx, filepath.Clean(rest))
+	default:
+		return nil, fmt.Errorf("source %q in fallback chain is not supported: must use the %q, %q or %q scheme", source, p.cfg.scheme, "env", "file")
+	}
+}
+
+// retrieveFile retrieves the file at path for a `file:` source of a fallback chain. The file is
+// read like a credential in its directory, under the same symlink policy, permission check, size
+// limit and checksum and signature verification, with the sidecars next to it.
+func (p *provider) retrieveFile(ctx context.Context, path string) (*confmap.Retrieved, error) {
+	val, err := p.readFileSource(path)
+	recordWatchedFile(ctx, path, val, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %q: %w", path, err)
+	}
+	bufs := &credentialBuffers{}
+	bufs.add(val)
+	readSidecar := func(ctx context.Context, name string) ([]byte, string, error) {
+		val, err := p.readFileSource(name)
+		recordWatchedFile(ctx, name, val, err)
+		return val, name, err
+	}
+	if err := p.verifySidecars(ctx, "file:"+path, path, val, readSidecar); err != nil {
+		bufs.wipe()
+		return nil, err
+	}
+	if val, err = p.transform(&reference{}, path, path, val, bufs); err != nil {
+		bufs.wipe()
+		return nil, err
+	}
+	return confmap.NewRetrieved(string(val), p.retrievedClose(bufs, nil))
+}
+
+// readFileSource reads the file at path relative to its directory, see readCredentialFile.
+func (p *provider) readFileSource(path string) ([]byte, error) {
+	return p.readCredentialFile(credentialsDirFS(filepath.Dir(path), p.cfg.symlinkPolicy), filepath.Base(path), path)
+}
+
+// isMissing reports whether err is caused by a credential or other source that doesn't exist.
+func isMissing(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, fs.ErrNotExist) || errors.Is(err, ErrNoCredentialsDirectory) || errors.Is(err, errEnvNotSet)
+}
+
+func readOSFile(path string, maxSize int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readAll(f, maxSize)
+}
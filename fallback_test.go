@@ -0,0 +1,127 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestFallbackChain(t *testing.T) {
+	credDir := t.TempDir()
+	fileDir := t.TempDir()
+	tokenFile := filepath.Join(fileDir, "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("from-file\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "TOKEN"), []byte("from-credential\n"), 0600))
+	chain := credSchemePrefix + "TOKEN|env:FALLBACK_TOKEN|file:" + tokenFile
+
+	tests := []struct {
+		name     string
+		setup    func(t *testing.T)
+		expected string
+	}{
+		{
+			name: "credential",
+			setup: func(t *testing.T) {
+				t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+				t.Setenv("FALLBACK_TOKEN", "from-env")
+			},
+			expected: "from-credential",
+		},
+		{
+			name: "env",
+			setup: func(t *testing.T) {
+				t.Setenv("FALLBACK_TOKEN", "from-env")
+			},
+			expected: "from-env",
+		},
+		{
+			name:     "file",
+			setup:    func(*testing.T) {},
+			expected: "from-file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup(t)
+			prov := NewFactory(WithFileFallback(true)).Create(confmaptest.NewNopProviderSettings())
+			ret, err := prov.Retrieve(context.Background(), chain, nil)
+			require.NoError(t, err)
+			str, err := ret.AsString()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, str)
+			assert.NoError(t, prov.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestFallbackChainErrors(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+
+	prov := NewFactory(WithFileFallback(true)).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"TOKEN|env:MISSING_FALLBACK_TOKEN|file:"+filepath.Join(credDir, "missing"), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "none of the sources")
+
+	// Errors other than missing sources stop the chain.
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"..|env:HOME", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid name")
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"TOKEN|https://example.com", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not supported")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestFallbackChainFileSource(t *testing.T) {
+	t.Setenv("CREDENTIALS_DIRECTORY", t.TempDir())
+	fileDir := t.TempDir()
+	tokenFile := filepath.Join(fileDir, "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("from-file\n"), 0600))
+	outside := filepath.Join(t.TempDir(), "outside")
+	require.NoError(t, os.WriteFile(outside, []byte("outside\n"), 0600))
+	require.NoError(t, os.Symlink(outside, filepath.Join(fileDir, "link")))
+
+	// File sources are disabled by default.
+	prov := createProvider()
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"TOKEN|file:"+tokenFile, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "WithFileFallback")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	prov = NewFactory(WithFileFallback(true), WithChecksumVerification(ChecksumRequired)).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"TOKEN|file:"+tokenFile, nil)
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+
+	sum := sha256.Sum256([]byte("from-file\n"))
+	require.NoError(t, os.WriteFile(tokenFile+".sha256", []byte(hex.EncodeToString(sum[:])+"  token\n"), 0600))
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"TOKEN|file:"+tokenFile, nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	// Symlinks out of the directory of the file are refused like in credentials directories.
+	prov = NewFactory(WithFileFallback(true)).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"TOKEN|file:"+filepath.Join(fileDir, "link"), nil)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "none of the sources")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	prov = NewFactory(WithFileFallback(true), WithMaxSize(4)).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"TOKEN|file:"+tokenFile, nil)
+	require.ErrorIs(t, err, ErrTooLarge)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
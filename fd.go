@@ -0,0 +1,131 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const (
+	fdSchemeName = "systemdfd"
+
+	// listenPIDEnv, listenFDsEnv and listenFDNamesEnv describe the file descriptors passed by
+	// systemd, see sd_listen_fds(3).
+	listenPIDEnv     = "LISTEN_PID"
+	listenFDsEnv     = "LISTEN_FDS"
+	listenFDNamesEnv = "LISTEN_FDNAMES"
+	// listenFDsStart is the first file descriptor passed by systemd.
+	listenFDsStart = 3
+	// unknownFDName is the name systemd gives to file descriptors without FileDescriptorName=.
+	unknownFDName = "unknown"
+)
+
+// passedFDs caches the contents of the file descriptors read by systemdfd providers. A file
+// descriptor belongs to the process and, for pipes, can only be read once, so its content is
+// read on first use and shared by all providers. The contents are wiped when a systemdfd
+// provider is shut down; wiped holds the file descriptors that can't be read anymore.
+var passedFDs = struct {
+	sync.Mutex
+	contents map[int][]byte
+	wiped    map[int]bool
+}{contents: map[int][]byte{}, wiped: map[int]bool{}}
+
+// NewFDFactory returns a factory for a confmap.Provider that reads the configuration from file
+// descriptors passed by systemd socket or file descriptor activation, for tokens that should
+// never be written to a file system.
+//
+// This Provider supports "systemdfd" scheme, and can be called with a selector:
+// `systemdfd:FD_NAME`
+//
+// FD_NAME is the name set with FileDescriptorName= on the socket unit, or with
+// `systemd-run --pipe`/sd_pid_notify_with_fds(3) FDNAME=, as listed in $LISTEN_FDNAMES. The
+// file descriptor is read to its end and closed the first time it is used; later references
+// to it resolve to the same content, until a systemdfd provider is shut down, which wipes it.
+// The URI options and Option values work like for the provider returned by NewFactory.
+func NewFDFactory(opts ...Option) confmap.ProviderFactory {
+	defaults := optionFunc(func(cfg *config) {
+		cfg.scheme = fdSchemeName
+		cfg.listenFDs = true
+	})
+	cfg := newConfig(append([]Option{defaults}, opts...))
+	return confmap.NewProviderFactory(func(ps confmap.ProviderSettings) confmap.Provider {
+		return newProvider(ps, cfg)
+	})
+}
+
+// readFDCredential reads the file descriptor passed by systemd with the name name.
+func (p *provider) readFDCredential(name string) ([]byte, string, error) {
+	fd, ok := p.listenFD(name)
+	if !ok {
+		return nil, "", fmt.Errorf("no file descriptor named %q was passed in $%s: %w", name, listenFDNamesEnv, fs.ErrNotExist)
+	}
+	source := "fd " + strconv.Itoa(fd)
+
+	passedFDs.Lock()
+	defer passedFDs.Unlock()
+	// The cached content is cloned, as the buffers returned to the provider are wiped after use.
+	if val, ok := passedFDs.contents[fd]; ok {
+		return bytes.Clone(val), source, nil
+	}
+	if passedFDs.wiped[fd] {
+		// The file descriptor was closed, its number may have been reused for another file since.
+		return nil, source, fmt.Errorf("credential %q from %s was wiped when the provider was shut down: %w", name, source, fs.ErrClosed)
+	}
+	f := os.NewFile(uintptr(fd), name)
+	defer f.Close()
+	// Regular files and memfds may have been written without rewinding.
+	_, _ = f.Seek(0, io.SeekStart)
+	val, err := readAll(f, p.cfg.maxSize)
+	if err != nil {
+		return nil, source, fmt.Errorf("failed to read credential %q from %s: %w", name, source, err)
+	}
+	passedFDs.contents[fd] = val
+	return bytes.Clone(val), source, nil
+}
+
+// wipePassedFDs overwrites the cached contents of the file descriptors with zeroes and drops them.
+func wipePassedFDs() {
+	passedFDs.Lock()
+	defer passedFDs.Unlock()
+	for fd, val := range passedFDs.contents {
+		clear(val)
+		passedFDs.wiped[fd] = true
+	}
+	clear(passedFDs.contents)
+}
+
+// listenFD returns the file descriptor passed by systemd with the name name. Like
+// sd_listen_fds(3), the file descriptors are ignored if they were meant for another process.
+func (p *provider) listenFD(name string) (int, bool) {
+	if os.Getenv(listenPIDEnv) != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(os.Getenv(listenFDsEnv))
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	var names []string
+	if fdNames, ok := os.LookupEnv(listenFDNamesEnv); ok {
+		names = strings.Split(fdNames, ":")
+	}
+	for i := range n {
+		fdName := unknownFDName
+		if i < len(names) {
+			fdName = names[i]
+		}
+		if fdName == name {
+			return p.cfg.listenFDsStart + i, true
+		}
+	}
+	return 0, false
+}
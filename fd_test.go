@@ -0,0 +1,80 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+const fdSchemePrefix = fdSchemeName + ":"
+
+// passFDs places a pipe holding each of contents at consecutive file descriptors starting at
+// start, the way systemd passes them.
+func passFDs(t *testing.T, start int, contents ...string) {
+	for i, content := range contents {
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		_, err = w.WriteString(content)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		require.NoError(t, syscall.Dup3(int(r.Fd()), start+i, 0))
+		require.NoError(t, r.Close())
+	}
+}
+
+func TestFD(t *testing.T) {
+	const start = 100
+	passFDs(t, start, testCredValue+"\n", "ignored", "unnamed")
+	t.Setenv(listenPIDEnv, strconv.Itoa(os.Getpid()))
+	t.Setenv(listenFDsEnv, "3")
+	t.Setenv(listenFDNamesEnv, "token_fd:other_fd")
+	withStart := optionFunc(func(cfg *config) {
+		cfg.listenFDsStart = start
+	})
+
+	prov := NewFDFactory(withStart).Create(confmaptest.NewNopProviderSettings())
+	assert.Equal(t, "systemdfd", prov.Scheme())
+	// The pipe can only be read once, the second retrieval is served from the cache.
+	for range 2 {
+		ret, err := prov.Retrieve(context.Background(), fdSchemePrefix+"token_fd", nil)
+		require.NoError(t, err)
+		str, err := ret.AsString()
+		require.NoError(t, err)
+		assert.Equal(t, testCredValue, str)
+	}
+	ret, err := prov.Retrieve(context.Background(), fdSchemePrefix+"unknown", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "unnamed", str)
+
+	_, err = prov.Retrieve(context.Background(), fdSchemePrefix+"missing", nil)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	// The contents are wiped on shutdown, and can't be read again.
+	assert.Empty(t, passedFDs.contents)
+	prov = NewFDFactory(withStart).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), fdSchemePrefix+"token_fd", nil)
+	assert.ErrorIs(t, err, fs.ErrClosed)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	// File descriptors passed to another process are ignored.
+	t.Setenv(listenPIDEnv, strconv.Itoa(os.Getpid()+1))
+	prov = NewFDFactory(withStart).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), fdSchemePrefix+"other_fd", nil)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
@@ -0,0 +1,65 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const fileCredentialSchemeName = "filecredential"
+
+// NewFileCredentialFactory returns a factory for a confmap.Provider that reads the configuration
+// from secrets in the allow-listed roots, such as Vault agent sinks or custom tmpfs mounts.
+//
+// This Provider supports "filecredential" scheme, and can be called with a selector:
+// `filecredential:NAME`
+//
+// The secret is read from the first of roots containing NAME. Because names must not contain
+// '/', and symlinks are not followed outside of the root they are in, even with SymlinkFollow, a
+// configuration can only read the files directly inside roots. SymlinkReject refuses all symlinks. Roots are fixed when the factory is created, so
+// configuration files cannot widen them. For the same reason, fallback chains can't contain `file:`
+// sources, even with WithFileFallback. The URI options, other fallback sources and Option values
+// work like for the provider returned by NewFactory.
+func NewFileCredentialFactory(roots []string, opts ...Option) confmap.ProviderFactory {
+	fileRoots := make([]string, len(roots))
+	for i, root := range roots {
+		fileRoots[i] = filepath.Clean(root)
+	}
+	defaults := optionFunc(func(cfg *config) {
+		cfg.scheme = fileCredentialSchemeName
+		cfg.credentialsDirectoryEnv = nil
+		cfg.discoverUnitDirectory = false
+		cfg.fileRoots = fileRoots
+	})
+	cfg := newConfig(append([]Option{defaults}, opts...))
+	cfg.fileFallback = false
+	return confmap.NewProviderFactory(func(ps confmap.ProviderSettings) confmap.Provider {
+		return newProvider(ps, cfg)
+	})
+}
+
+// lookupInRoots looks up the credential called name in the first allow-listed root containing it.
+func (p *provider) lookupInRoots(name string, mode lookupMode) ([]byte, string, error) {
+	for _, root := range p.cfg.fileRoots {
+		val, source, err := p.lookupInRoot(root, name, mode)
+		if !isMissing(err) {
+			return val, source, err
+		}
+	}
+	return nil, "", fmt.Errorf("credential %q not found in any of %q: %w", name, p.cfg.fileRoots, fs.ErrNotExist)
+}
+
+// lookupInRoot looks up the credential called name in root under the symlink policy. Symlinks
+// are never followed outside of root, so SymlinkFollow is applied like SymlinkFollowWithinDirectory.
+func (p *provider) lookupInRoot(root, name string, mode lookupMode) ([]byte, string, error) {
+	policy := p.cfg.symlinkPolicy
+	if policy == SymlinkFollow {
+		policy = SymlinkFollowWithinDirectory
+	}
+	return p.lookupInFS(credentialsDirFS(root, policy), root, name, mode)
+}
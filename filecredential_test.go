@@ -0,0 +1,95 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+const fileCredentialSchemePrefix = fileCredentialSchemeName + ":"
+
+func TestFileCredential(t *testing.T) {
+	vaultDir := t.TempDir()
+	tmpfsDir := t.TempDir()
+	outsideDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(vaultDir, "api_token"), []byte(testCredValue+"\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpfsDir, "api_token"), []byte("shadowed"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpfsDir, "password"), []byte("hunter2"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(outsideDir, "shadow"), []byte("root:x"), 0600))
+	require.NoError(t, os.Symlink(filepath.Join(outsideDir, "shadow"), filepath.Join(tmpfsDir, "escape")))
+
+	missingDir := filepath.Join(t.TempDir(), "missing")
+	prov := NewFileCredentialFactory([]string{missingDir, vaultDir, tmpfsDir}).Create(confmaptest.NewNopProviderSettings())
+	assert.Equal(t, "filecredential", prov.Scheme())
+	for credName, expected := range map[string]string{"api_token": testCredValue, "password": "hunter2"} {
+		ret, err := prov.Retrieve(context.Background(), fileCredentialSchemePrefix+credName, nil)
+		require.NoError(t, err)
+		str, err := ret.AsString()
+		require.NoError(t, err)
+		assert.Equal(t, expected, str)
+	}
+
+	_, err := prov.Retrieve(context.Background(), fileCredentialSchemePrefix+"missing", nil)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = prov.Retrieve(context.Background(), fileCredentialSchemePrefix+"escape", nil)
+	assert.ErrorContains(t, err, "escapes")
+	_, err = prov.Retrieve(context.Background(), fileCredentialSchemePrefix+"..%2F"+filepath.Base(outsideDir)+"%2Fshadow", nil)
+	assert.ErrorContains(t, err, "has invalid name")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	// File sources could read files outside of the roots, so they can't be enabled.
+	prov = NewFileCredentialFactory([]string{vaultDir}, WithFileFallback(true)).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), fileCredentialSchemePrefix+"missing|file:"+filepath.Join(outsideDir, "shadow"), nil)
+	assert.ErrorContains(t, err, "is not allowed")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestFileCredentialIgnoresCredentialsDirectory(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	t.Setenv(credentialsDirectoryEnv, credDir)
+
+	prov := NewFileCredentialFactory(nil).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), fileCredentialSchemePrefix+"api_token", nil)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestFileCredentialSymlinkPolicy(t *testing.T) {
+	root := t.TempDir()
+	outsideDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "api_token"), []byte(testCredValue), 0600))
+	require.NoError(t, os.Symlink("api_token", filepath.Join(root, "token")))
+	require.NoError(t, os.WriteFile(filepath.Join(outsideDir, "shadow"), []byte("root:x"), 0600))
+	require.NoError(t, os.Symlink(filepath.Join(outsideDir, "shadow"), filepath.Join(root, "escape")))
+
+	prov := NewFileCredentialFactory([]string{root}).Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), fileCredentialSchemePrefix+"token", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	prov = NewFileCredentialFactory([]string{root}, WithSymlinkPolicy(SymlinkReject)).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), fileCredentialSchemePrefix+"token", nil)
+	assert.ErrorContains(t, err, "symlink")
+	_, err = prov.Retrieve(context.Background(), fileCredentialSchemePrefix+"token?exists=true", nil)
+	assert.ErrorContains(t, err, "symlink")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	// Symlinks are never followed outside of the roots.
+	prov = NewFileCredentialFactory([]string{root}, WithSymlinkPolicy(SymlinkFollow)).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), fileCredentialSchemePrefix+"escape", nil)
+	assert.ErrorContains(t, err, "escapes")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
@@ -0,0 +1,108 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// smbiosEntriesDirectory holds the raw SMBIOS structures exposed by the kernel.
+	smbiosEntriesDirectory = "/sys/firmware/dmi/entries"
+	// fwCfgCredentialsDirectory holds the credentials passed with
+	// `-fw_cfg name=opt/io.systemd.credentials/NAME,...` to qemu.
+	fwCfgCredentialsDirectory = "/sys/firmware/qemu_fw_cfg/by_name/opt/io.systemd.credentials"
+
+	// smbiosOEMStringsType is the SMBIOS structure type of OEM strings.
+	smbiosOEMStringsType = 11
+
+	smbiosCredentialPrefix       = "io.systemd.credential:"
+	smbiosBinaryCredentialPrefix = "io.systemd.credential.binary:"
+)
+
+// readFirmwareCredential reads the credential called name directly from qemu fw_cfg or the
+// SMBIOS OEM strings, the way systemd imports them into /run/credentials/@system at boot.
+func (p *provider) readFirmwareCredential(name string) ([]byte, string, error) {
+	fwCfgPath := filepath.Join(p.cfg.fwCfgCredentialsDirectory, name, "raw")
+	val, err := readOSFile(fwCfgPath, p.cfg.maxSize)
+	if err == nil {
+		return val, fwCfgPath, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, "", fmt.Errorf("failed to read credential %q from %q: %w", name, fwCfgPath, err)
+	}
+
+	entries, err := filepath.Glob(filepath.Join(p.cfg.smbiosEntriesDirectory, fmt.Sprintf("%d-*", smbiosOEMStringsType), "raw"))
+	if err != nil {
+		return nil, "", err
+	}
+	for _, entry := range entries {
+		raw, err := os.ReadFile(entry)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read SMBIOS OEM strings from %q: %w", entry, err)
+		}
+		oemStrings, err := parseSMBIOSStrings(raw)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse SMBIOS OEM strings from %q: %w", entry, err)
+		}
+		val, ok, err := smbiosCredential(oemStrings, name)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read credential %q from SMBIOS OEM strings in %q: %w", name, entry, err)
+		}
+		if ok {
+			if p.cfg.maxSize > 0 && int64(len(val)) > p.cfg.maxSize {
+				return nil, "", withKind(ErrTooLarge, fmt.Errorf("credential %q in %q exceeds the maximum of %d bytes", name, entry, p.cfg.maxSize))
+			}
+			return val, entry, nil
+		}
+	}
+	return nil, "", fmt.Errorf("credential %q not found in qemu fw_cfg or SMBIOS OEM strings: %w", name, fs.ErrNotExist)
+}
+
+// parseSMBIOSStrings returns the strings of the raw SMBIOS structure raw, which follow its
+// formatted area as NUL-terminated strings, ending with an empty string.
+func parseSMBIOSStrings(raw []byte) ([]string, error) {
+	if len(raw) < 4 || int(raw[1]) > len(raw) {
+		return nil, errors.New("structure is truncated")
+	}
+	if raw[0] != smbiosOEMStringsType {
+		return nil, fmt.Errorf("structure has type %d, expected %d", raw[0], smbiosOEMStringsType)
+	}
+	var strs []string
+	for rest := raw[raw[1]:]; ; {
+		end := bytes.IndexByte(rest, 0)
+		if end < 0 {
+			return nil, errors.New("string set is not terminated")
+		}
+		if end == 0 {
+			return strs, nil
+		}
+		strs = append(strs, string(rest[:end]))
+		rest = rest[end+1:]
+	}
+}
+
+// smbiosCredential returns the value of the credential called name from the OEM strings
+// `io.systemd.credential:NAME=VALUE` or `io.systemd.credential.binary:NAME=BASE64`.
+func smbiosCredential(oemStrings []string, name string) ([]byte, bool, error) {
+	for _, s := range oemStrings {
+		if val, ok := strings.CutPrefix(s, smbiosCredentialPrefix+name+"="); ok {
+			return []byte(val), true, nil
+		}
+		if val, ok := strings.CutPrefix(s, smbiosBinaryCredentialPrefix+name+"="); ok {
+			decoded, err := decodeBase64([]byte(val))
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to decode base64: %w", err)
+			}
+			return decoded, true, nil
+		}
+	}
+	return nil, false, nil
+}
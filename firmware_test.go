@@ -0,0 +1,85 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+// smbiosOEMStrings returns a raw SMBIOS Type 11 structure holding strs.
+func smbiosOEMStrings(strs ...string) []byte {
+	raw := []byte{smbiosOEMStringsType, 5, 0, 0, byte(len(strs))}
+	for _, s := range strs {
+		raw = append(raw, s...)
+		raw = append(raw, 0)
+	}
+	return append(raw, 0)
+}
+
+func TestParseSMBIOSStrings(t *testing.T) {
+	strs, err := parseSMBIOSStrings(smbiosOEMStrings("io.systemd.credential:a=b", "other"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"io.systemd.credential:a=b", "other"}, strs)
+
+	_, err = parseSMBIOSStrings([]byte{smbiosOEMStringsType, 5, 0, 0, 1, 'a'})
+	assert.ErrorContains(t, err, "not terminated")
+	_, err = parseSMBIOSStrings([]byte{1, 5})
+	assert.ErrorContains(t, err, "truncated")
+	_, err = parseSMBIOSStrings([]byte{1, 5, 0, 0, 0, 0, 0})
+	assert.ErrorContains(t, err, "type 1")
+}
+
+func TestFirmwareCredentialsFallback(t *testing.T) {
+	smbiosDir := t.TempDir()
+	for entry, raw := range map[string][]byte{
+		"11-0": smbiosOEMStrings("vendor string", "io.systemd.credential:vm.hostname=vm-01"),
+		"11-1": smbiosOEMStrings("io.systemd.credential.binary:otelcol.config=ZXhwb3J0ZXJzOiB7fQo=", "io.systemd.credential.binary:broken=!!"),
+	} {
+		require.NoError(t, os.Mkdir(filepath.Join(smbiosDir, entry), 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(smbiosDir, entry, "raw"), raw, 0600))
+	}
+	fwCfgDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(fwCfgDir, "api_token"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(fwCfgDir, "api_token", "raw"), []byte(testCredValue+"\n"), 0600))
+	withFirmwareDirs := optionFunc(func(cfg *config) {
+		cfg.smbiosEntriesDirectory = smbiosDir
+		cfg.fwCfgCredentialsDirectory = fwCfgDir
+	})
+
+	prov := NewFactory(WithCredentialsDirectory(t.TempDir()), withFirmwareDirs).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"vm.hostname", nil)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	prov = NewFactory(WithCredentialsDirectory(t.TempDir()), WithFirmwareCredentialsFallback(true), withFirmwareDirs).Create(confmaptest.NewNopProviderSettings())
+	for credName, expected := range map[string]string{
+		"api_token":      testCredValue,
+		"vm.hostname":    "vm-01",
+		"otelcol.config": "exporters: {}",
+	} {
+		ret, err := prov.Retrieve(context.Background(), credSchemePrefix+credName, nil)
+		require.NoError(t, err)
+		str, err := ret.AsString()
+		require.NoError(t, err)
+		assert.Equal(t, expected, str)
+	}
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"broken", nil)
+	assert.ErrorContains(t, err, "failed to decode base64")
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"missing", nil)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"missing:-fallback", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
@@ -0,0 +1,96 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RetrieveEvent describes a single call to Retrieve, see WithRetrieveHook.
+type RetrieveEvent struct {
+	// URI is the retrieved URI, including the scheme.
+	URI string
+	// Names are the names of the credentials referenced by URI, as written in the configuration.
+	Names []string
+	// Duration is how long the retrieval took.
+	Duration time.Duration
+	// Bytes is the size of the resolved value, zero if the retrieval failed.
+	Bytes int
+	// Err is the error the retrieval failed with, nil if it succeeded.
+	Err error
+	// Sources describes the credentials that were read, only set with WithSourceMetadata.
+	Sources []CredentialSource
+}
+
+// CredentialSource describes where a credential was read from, without its content.
+type CredentialSource struct {
+	// Name is the name of the credential that was read.
+	Name string
+	// Path is where the credential was read from, e.g. a file or `fd 3`.
+	Path string
+	// ModTime is the time the credential was last modified, zero if unknown.
+	ModTime time.Time
+}
+
+// sourcesKey is the context key of the *sourceRecorder of a Retrieve call.
+type sourcesKey struct{}
+
+// sourceRecorder collects the sources of the credentials read by a Retrieve call.
+type sourceRecorder struct {
+	sources []CredentialSource
+}
+
+// recordSource adds the credential called name, read from path, to the sources of the Retrieve
+// call ctx belongs to, if they are collected.
+func (p *provider) recordSource(ctx context.Context, name, path string) {
+	r, ok := ctx.Value(sourcesKey{}).(*sourceRecorder)
+	if !ok {
+		return
+	}
+	source := CredentialSource{Name: name, Path: path}
+	if modTime, err := p.modTime(path); err == nil {
+		source.ModTime = modTime
+	}
+	r.sources = append(r.sources, source)
+}
+
+// modTime returns the modification time of the credential read from path.
+func (p *provider) modTime(path string) (time.Time, error) {
+	var info fs.FileInfo
+	var err error
+	switch {
+	case filepath.IsAbs(path):
+		info, err = os.Stat(path)
+	case p.cfg.fsys != nil:
+		info, err = fs.Stat(p.cfg.fsys, path)
+	default:
+		// Credentials read from file descriptors or environment variables have no modification time.
+		err = errors.ErrUnsupported
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// uriNames returns the names of the credentials referenced by uri, which may be a fallback chain.
+// References that fail to parse are skipped.
+func (p *provider) uriNames(uri string) []string {
+	var names []string
+	for _, source := range strings.Split(uri, fallbackSeparator) {
+		if !strings.HasPrefix(source, p.cfg.scheme+":") {
+			continue
+		}
+		if ref, err := parseURI(source, p.cfg.scheme, p.cfg.envFile); err == nil {
+			names = append(names, ref.names...)
+		}
+	}
+	return names
+}
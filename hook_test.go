@@ -0,0 +1,91 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestRetrieveHook(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue+"\n"), 0600))
+	var events []RetrieveEvent
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithRetrieveHook(func(event RetrieveEvent) {
+		events = append(events, event)
+	})).Create(confmaptest.NewNopProviderSettings())
+
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"missing", nil)
+	require.Error(t, err)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"missing|env:UNSET_VARIABLE|"+credSchemePrefix+"api_token+api_token", nil)
+	require.NoError(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	require.Len(t, events, 3)
+	assert.Equal(t, credSchemePrefix+"api_token", events[0].URI)
+	assert.Equal(t, []string{"api_token"}, events[0].Names)
+	assert.Equal(t, len(testCredValue), events[0].Bytes)
+	assert.Positive(t, events[0].Duration)
+	assert.NoError(t, events[0].Err)
+
+	assert.Equal(t, []string{"missing"}, events[1].Names)
+	assert.Zero(t, events[1].Bytes)
+	assert.ErrorIs(t, events[1].Err, fs.ErrNotExist)
+
+	assert.Equal(t, []string{"missing", "api_token", "api_token"}, events[2].Names)
+	assert.Equal(t, 2*len(testCredValue)+1, events[2].Bytes)
+}
+
+func TestSourceMetadata(t *testing.T) {
+	credDir := t.TempDir()
+	credPath := filepath.Join(credDir, "api_token")
+	require.NoError(t, os.WriteFile(credPath, []byte(testCredValue), 0600))
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(credPath, modTime, modTime))
+	var events []RetrieveEvent
+	hook := WithRetrieveHook(func(event RetrieveEvent) {
+		events = append(events, event)
+	})
+
+	prov := NewFactory(WithCredentialsDirectory(credDir), hook).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+	require.Len(t, events, 1)
+	assert.Nil(t, events[0].Sources)
+
+	events = nil
+	prov = NewFactory(WithCredentialsDirectory(credDir), hook, WithSourceMetadata(true)).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"missing", nil)
+	require.Error(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+	require.Len(t, events, 2)
+	require.Len(t, events[0].Sources, 1)
+	assert.Equal(t, "api_token", events[0].Sources[0].Name)
+	assert.Equal(t, credPath, events[0].Sources[0].Path)
+	assert.True(t, modTime.Equal(events[0].Sources[0].ModTime))
+	assert.Empty(t, events[1].Sources)
+
+	events = nil
+	fsys := fstest.MapFS{"api_token": &fstest.MapFile{Data: []byte(testCredValue), ModTime: modTime}}
+	prov = NewFactory(WithFS(fsys), hook, WithSourceMetadata(true)).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+	require.Len(t, events, 1)
+	assert.Equal(t, []CredentialSource{{Name: "api_token", Path: "api_token", ModTime: modTime}}, events[0].Sources)
+}
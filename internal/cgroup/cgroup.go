@@ -0,0 +1,61 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cgroup finds the systemd unit owning a process from its control groups.
+package cgroup // import "bou.ke/systemdcredentialprovider/internal/cgroup"
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// Unit returns the name of the service owning the process, given the contents of
+// /proc/self/cgroup. The unified (cgroup v2) hierarchy is preferred over the legacy
+// name=systemd one.
+func Unit(cgroups []byte) (string, bool) {
+	unit, _, ok := lookup(cgroups)
+	return unit, ok
+}
+
+// UserUnit reports whether the service returned by Unit is run by a user service manager,
+// i.e. it is part of the user@UID.service of a user, rather than by the system manager.
+func UserUnit(cgroups []byte) bool {
+	_, user, ok := lookup(cgroups)
+	return ok && user
+}
+
+// lookup returns the innermost service in the control groups of the process, and whether
+// it runs under a user service manager.
+func lookup(cgroups []byte) (unit string, user bool, ok bool) {
+	var unifiedPath, legacyPath string
+	scanner := bufio.NewScanner(bytes.NewReader(cgroups))
+	for scanner.Scan() {
+		// Each line has the format hierarchy-ID:controller-list:cgroup-path.
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		switch {
+		case fields[0] == "0" && fields[1] == "":
+			unifiedPath = fields[2]
+		case fields[1] == "name=systemd":
+			legacyPath = fields[2]
+		}
+	}
+	for _, cgroupPath := range []string{unifiedPath, legacyPath} {
+		components := strings.Split(cgroupPath, "/")
+		// Services may create sub-cgroups, so the innermost service is looked up.
+		for i := len(components) - 1; i >= 0; i-- {
+			if strings.HasSuffix(components[i], ".service") {
+				for _, parent := range components[:i] {
+					if strings.HasPrefix(parent, "user@") && strings.HasSuffix(parent, ".service") {
+						user = true
+					}
+				}
+				return components[i], user, true
+			}
+		}
+	}
+	return "", false, false
+}
@@ -0,0 +1,35 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cgroup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnit(t *testing.T) {
+	tests := []struct {
+		name     string
+		cgroups  string
+		expected string
+		user     bool
+	}{
+		{name: "unified", cgroups: "0::/system.slice/otelcol.service\n", expected: "otelcol.service"},
+		{name: "sub-cgroup", cgroups: "0::/system.slice/otelcol.service/helper\n", expected: "otelcol.service"},
+		{name: "template", cgroups: "0::/system.slice/system-otelcol.slice/otelcol@main.service\n", expected: "otelcol@main.service"},
+		{name: "legacy", cgroups: "2:cpu:/\n1:name=systemd:/system.slice/otelcol.service\n", expected: "otelcol.service"},
+		{name: "user", cgroups: "0::/user.slice/user-1000.slice/user@1000.service/app.slice/otelcol.service\n", expected: "otelcol.service", user: true},
+		{name: "user manager", cgroups: "0::/user.slice/user-1000.slice/user@1000.service/init.scope\n", expected: "user@1000.service"},
+		{name: "none", cgroups: "0::/\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unit, ok := Unit([]byte(tt.cgroups))
+			assert.Equal(t, tt.expected != "", ok)
+			assert.Equal(t, tt.expected, unit)
+			assert.Equal(t, tt.user, UserUnit([]byte(tt.cgroups)))
+		})
+	}
+}
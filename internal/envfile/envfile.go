@@ -0,0 +1,94 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package envfile parses files in the format of systemd's EnvironmentFile=.
+package envfile // import "bou.ke/systemdcredentialprovider/internal/envfile"
+
+import "strings"
+
+// ValidName reports whether name can be used as the name of an environment variable.
+func ValidName(name string) bool {
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return false
+	}
+	for _, c := range []byte(name) {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Parse parses content like systemd parses EnvironmentFile=: empty lines and lines
+// starting with '#' or ';' are ignored, values may be single-quoted (literal) or double-quoted
+// (with backslash escapes), a backslash at the end of a line continues the value on the next
+// one, and whitespace around unquoted values is removed. Later assignments override earlier ones,
+// and lines that aren't valid assignments are ignored.
+func Parse(content string) map[string]string {
+	vars := map[string]string{}
+	for len(content) > 0 {
+		line, rest, _ := strings.Cut(content, "\n")
+		line = strings.TrimLeft(line, " \t\r")
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			content = rest
+			continue
+		}
+		key, _, ok := strings.Cut(line, "=")
+		key = strings.TrimRight(key, " \t")
+		if !ok || !ValidName(key) {
+			content = rest
+			continue
+		}
+		vars[key], content = parseValue(content[strings.IndexByte(content, '=')+1:])
+	}
+	return vars
+}
+
+// parseValue parses the value at the start of content, returning it and the content
+// following its line. Like systemd, unterminated quotes extend to the end of the content.
+func parseValue(content string) (string, string) {
+	content = strings.TrimLeft(content, " \t")
+	var b strings.Builder
+	// trailing is the length of b up to the last unquoted non-whitespace character.
+	trailing := 0
+	for i := 0; i < len(content); i++ {
+		switch c := content[i]; c {
+		case '\n':
+			return b.String()[:trailing], content[i+1:]
+		case '\\':
+			i++
+			if i == len(content) {
+				return b.String()[:trailing], ""
+			}
+			if content[i] != '\n' {
+				b.WriteByte(content[i])
+				trailing = b.Len()
+			}
+		case '\'':
+			end := strings.IndexByte(content[i+1:], '\'')
+			if end < 0 {
+				end = len(content) - i - 1
+			}
+			b.WriteString(content[i+1 : i+1+end])
+			trailing = b.Len()
+			i += end + 1
+		case '"':
+			for i++; i < len(content) && content[i] != '"'; i++ {
+				if content[i] == '\\' && i+1 < len(content) && strings.IndexByte("\"\\`$\n", content[i+1]) >= 0 {
+					i++
+					if content[i] == '\n' {
+						continue
+					}
+				}
+				b.WriteByte(content[i])
+			}
+			trailing = b.Len()
+		case ' ', '\t', '\r':
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+			trailing = b.Len()
+		}
+	}
+	return b.String()[:trailing], ""
+}
@@ -0,0 +1,50 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package envfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	content := `# comment
+; another comment
+
+PLAIN=value
+  SPACED  =   value with spaces   
+SINGLE='it''s $literal \n'
+DOUBLE="line \"one\"\
+ continued \$HOME"
+ESCAPED=a\ b\\c
+CONTINUED=first \
+second
+MIXED=pre"quoted "post
+EMPTY=
+not an assignment
+1INVALID=ignored
+PLAIN=override
+UNTERMINATED="to the end`
+	assert.Equal(t, map[string]string{
+		"PLAIN":        "override",
+		"SPACED":       "value with spaces",
+		"SINGLE":       `its $literal \n`,
+		"DOUBLE":       `line "one" continued $HOME`,
+		"ESCAPED":      `a b\c`,
+		"CONTINUED":    "first second",
+		"MIXED":        "prequoted post",
+		"EMPTY":        "",
+		"UNTERMINATED": "to the end",
+	}, Parse(content))
+}
+
+func TestValidName(t *testing.T) {
+	for _, name := range []string{"A", "_", "DATABASE_URL", "api_token2"} {
+		assert.True(t, ValidName(name), name)
+	}
+	for _, name := range []string{"", "1A", "tls.key", "A-B", "A B"} {
+		assert.False(t, ValidName(name), name)
+	}
+}
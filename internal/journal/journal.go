@@ -0,0 +1,51 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package journal writes structured entries to the systemd journal using its native protocol,
+// see https://systemd.io/JOURNAL_NATIVE_PROTOCOL/.
+package journal // import "bou.ke/systemdcredentialprovider/internal/journal"
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+)
+
+// DefaultSocket is the socket journald listens on for native protocol messages.
+const DefaultSocket = "/run/systemd/journal/socket"
+
+// Field is a single field of a journal entry.
+type Field struct {
+	// Name is the name of the field: uppercase letters, digits and underscores, not starting
+	// with an underscore, which is reserved for fields added by journald.
+	Name  string
+	Value string
+}
+
+// Send writes an entry with fields to the journal listening on socket.
+func Send(socket string, fields ...Field) error {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write(Encode(fields...))
+	return err
+}
+
+// Encode returns fields encoded in the native protocol.
+func Encode(fields ...Field) []byte {
+	var b bytes.Buffer
+	for _, f := range fields {
+		if !strings.Contains(f.Value, "\n") {
+			b.WriteString(f.Name + "=" + f.Value + "\n")
+			continue
+		}
+		// Values containing newlines are length-prefixed.
+		b.WriteString(f.Name + "\n")
+		_ = binary.Write(&b, binary.LittleEndian, uint64(len(f.Value)))
+		b.WriteString(f.Value + "\n")
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,34 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package journal
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	assert.Equal(t,
+		"MESSAGE=hello\nDETAIL\n\x0b\x00\x00\x00\x00\x00\x00\x00line1\nline2\nPRIORITY=6\n",
+		string(Encode(Field{"MESSAGE", "hello"}, Field{"DETAIL", "line1\nline2"}, Field{"PRIORITY", "6"})))
+}
+
+func TestSend(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, Send(socket, Field{"MESSAGE", "hello"}))
+	buf := make([]byte, 256)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "MESSAGE=hello\n", string(buf[:n]))
+
+	assert.Error(t, Send(filepath.Join(t.TempDir(), "missing"), Field{"MESSAGE", "hello"}))
+}
@@ -0,0 +1,22 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sdnotify // import "bou.ke/systemdcredentialprovider/internal/sdnotify"
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// clockMonotonic is CLOCK_MONOTONIC, which systemd compares MONOTONIC_USEC against.
+const clockMonotonic = 1
+
+// monotonicUsec returns the current CLOCK_MONOTONIC time in microseconds.
+func monotonicUsec() (int64, bool) {
+	var ts syscall.Timespec
+	_, _, errno := syscall.Syscall(syscall.SYS_CLOCK_GETTIME, clockMonotonic, uintptr(unsafe.Pointer(&ts)), 0)
+	if errno != 0 {
+		return 0, false
+	}
+	return ts.Nano() / 1000, true
+}
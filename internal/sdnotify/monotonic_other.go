@@ -0,0 +1,11 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package sdnotify // import "bou.ke/systemdcredentialprovider/internal/sdnotify"
+
+// monotonicUsec is only implemented on Linux, where systemd runs.
+func monotonicUsec() (int64, bool) {
+	return 0, false
+}
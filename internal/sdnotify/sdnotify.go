@@ -0,0 +1,47 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sdnotify sends service state notifications to systemd, see sd_notify(3).
+package sdnotify // import "bou.ke/systemdcredentialprovider/internal/sdnotify"
+
+import (
+	"net"
+	"os"
+	"strconv"
+)
+
+// SocketEnv is the environment variable systemd sets to the notification socket.
+const SocketEnv = "NOTIFY_SOCKET"
+
+// Notify sends state, newline-separated VARIABLE=VALUE assignments such as "READY=1", to the
+// service manager. It returns false without error if the process wasn't started with a
+// notification socket.
+func Notify(state string) (bool, error) {
+	socket := os.Getenv(SocketEnv)
+	if socket == "" {
+		return false, nil
+	}
+	// A leading '@' refers to the abstract namespace.
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Reloading returns the state announcing that the service is reloading its configuration,
+// including the timestamp required by Type=notify-reload services.
+func Reloading() string {
+	usec, ok := monotonicUsec()
+	if !ok {
+		return "RELOADING=1"
+	}
+	return "RELOADING=1\nMONOTONIC_USEC=" + strconv.FormatInt(usec, 10)
+}
@@ -0,0 +1,39 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sdnotify
+
+import (
+	"net"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotify(t *testing.T) {
+	t.Setenv(SocketEnv, "")
+	sent, err := Notify("READY=1")
+	require.NoError(t, err)
+	assert.False(t, sent)
+
+	socket := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+	t.Setenv(SocketEnv, socket)
+
+	sent, err = Notify("READY=1\nSTATUS=ok")
+	require.NoError(t, err)
+	assert.True(t, sent)
+	buf := make([]byte, 256)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "READY=1\nSTATUS=ok", string(buf[:n]))
+}
+
+func TestReloading(t *testing.T) {
+	assert.Regexp(t, regexp.MustCompile(`^RELOADING=1\nMONOTONIC_USEC=[0-9]+$`), Reloading())
+}
@@ -0,0 +1,66 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package unitcreds locates the credentials directory of the systemd service owning a process,
+// for processes that run under systemd but lost $CREDENTIALS_DIRECTORY.
+package unitcreds // import "bou.ke/systemdcredentialprovider/internal/unitcreds"
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"bou.ke/systemdcredentialprovider/internal/cgroup"
+)
+
+const (
+	// InvocationIDEnv is set by systemd for every process it starts as part of a unit.
+	InvocationIDEnv = "INVOCATION_ID"
+	// ProcSelfCgroup lists the control groups of the current process.
+	ProcSelfCgroup = "/proc/self/cgroup"
+	// RunCredentialsDirectory is where systemd places the credentials directories of system units.
+	RunCredentialsDirectory = "/run/credentials"
+)
+
+// Service returns the service the process runs in, given procSelfCgroup, the path of its
+// /proc/self/cgroup, or an empty string when it doesn't run under systemd, and whether the
+// service is run by a user service manager.
+func Service(procSelfCgroup string) (string, bool) {
+	// Only processes started by systemd have $INVOCATION_ID; other processes in the cgroup
+	// of a user manager, such as a terminal, would otherwise be taken for the manager itself.
+	if _, ok := os.LookupEnv(InvocationIDEnv); !ok {
+		return "", false
+	}
+	cgroups, err := os.ReadFile(procSelfCgroup)
+	if err != nil {
+		return "", false
+	}
+	unit, _ := cgroup.Unit(cgroups)
+	return unit, cgroup.UserUnit(cgroups)
+}
+
+// Directory returns the credentials directory of unit, a service in runDir or, if it is run by
+// a user service manager, below UserRunCredentialsDirectory, if it exists.
+func Directory(runDir, unit string, user bool) (string, bool) {
+	if unit == "" {
+		return "", false
+	}
+	if user {
+		runDir = UserRunCredentialsDirectory()
+	}
+	credDir := filepath.Join(runDir, unit)
+	if info, err := os.Stat(credDir); err != nil || !info.IsDir() {
+		return "", false
+	}
+	return credDir, true
+}
+
+// UserRunCredentialsDirectory returns where a user service manager places the credentials
+// directories of its services: below $XDG_RUNTIME_DIR, which it sets for its services.
+func UserRunCredentialsDirectory() string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = filepath.Join("/run/user", strconv.Itoa(os.Getuid()))
+	}
+	return filepath.Join(runtimeDir, "credentials")
+}
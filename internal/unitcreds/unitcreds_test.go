@@ -0,0 +1,43 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package unitcreds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectory(t *testing.T) {
+	runDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(runDir, "otelcol.service"), 0700))
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	require.NoError(t, os.MkdirAll(filepath.Join(runtimeDir, "credentials", "app.service"), 0700))
+	cgroupFile := filepath.Join(t.TempDir(), "cgroup")
+	require.NoError(t, os.WriteFile(cgroupFile, []byte("0::/system.slice/otelcol.service\n"), 0600))
+
+	t.Setenv(InvocationIDEnv, "")
+	require.NoError(t, os.Unsetenv(InvocationIDEnv))
+	unit, _ := Service(cgroupFile)
+	assert.Empty(t, unit, "without $INVOCATION_ID the process doesn't run under systemd")
+	t.Setenv(InvocationIDEnv, "0123456789abcdef0123456789abcdef")
+	unit, user := Service(cgroupFile)
+	assert.Equal(t, "otelcol.service", unit)
+	assert.False(t, user)
+
+	dir, ok := Directory(runDir, unit, user)
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(runDir, "otelcol.service"), dir)
+	dir, ok = Directory(runDir, "app.service", true)
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(runtimeDir, "credentials", "app.service"), dir)
+	_, ok = Directory(runDir, "missing.service", false)
+	assert.False(t, ok)
+	_, ok = Directory(runDir, "", false)
+	assert.False(t, ok)
+}
@@ -0,0 +1,61 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const (
+	kubernetesSecretSchemeName = "kubernetessecret"
+
+	// projectedDataDirectory is the symlink through which the kubelet atomically swaps the
+	// timestamped directory holding the current contents of a secret or projected volume.
+	projectedDataDirectory = "..data"
+	// projectedReservedPrefix prefixes the entries the kubelet uses internally.
+	projectedReservedPrefix = ".."
+)
+
+// NewKubernetesSecretFactory returns a factory for a confmap.Provider that reads the configuration
+// from a Kubernetes Secret or projected volume mounted at dir, so that the same code covers
+// systemd credentials on nodes and mounted secrets in pods.
+//
+// This Provider supports "kubernetessecret" scheme, and can be called with a selector:
+// `kubernetessecret:KEY`
+//
+// Every read resolves the `..data` symlink of the volume first, so that keys are read from the
+// snapshot the kubelet currently publishes, and errors name the snapshot directory that was read.
+// Keys starting with ".." are reserved by the kubelet and rejected. The URI options,
+// fallback chains and Option values work like for the provider returned by NewFactory.
+func NewKubernetesSecretFactory(dir string, opts ...Option) confmap.ProviderFactory {
+	defaults := optionFunc(func(cfg *config) {
+		cfg.scheme = kubernetesSecretSchemeName
+		cfg.credentialsDirectory = dir
+		cfg.credentialsDirectoryEnv = nil
+		cfg.discoverUnitDirectory = false
+		cfg.projectedVolume = true
+	})
+	cfg := newConfig(append([]Option{defaults}, opts...))
+	return confmap.NewProviderFactory(func(ps confmap.ProviderSettings) confmap.Provider {
+		return newProvider(ps, cfg)
+	})
+}
+
+// resolveProjectedVolume returns the directory the `..data` symlink of the projected volume
+// mounted at dir currently points to, or dir itself if it is a plain directory.
+func resolveProjectedVolume(dir string) string {
+	snapshot, err := filepath.EvalSymlinks(filepath.Join(dir, projectedDataDirectory))
+	if err != nil {
+		return dir
+	}
+	return snapshot
+}
+
+// reservedProjectedName reports whether name refers to one of the kubelet's internal entries.
+func reservedProjectedName(name string) bool {
+	return strings.HasPrefix(name, projectedReservedPrefix)
+}
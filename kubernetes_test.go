@@ -0,0 +1,80 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+const kubernetesSchemePrefix = kubernetesSecretSchemeName + ":"
+
+// writeProjectedVolume lays out files in dir the way the kubelet does, returning a function
+// that atomically publishes new contents.
+func writeProjectedVolume(t *testing.T, dir string, files map[string]string) func(files map[string]string) {
+	generation := 0
+	publish := func(files map[string]string) {
+		generation++
+		snapshot := filepath.Join(dir, fmt.Sprintf("..2024_01_01_00_00_%02d", generation))
+		require.NoError(t, os.Mkdir(snapshot, 0755))
+		for name, val := range files {
+			require.NoError(t, os.WriteFile(filepath.Join(snapshot, name), []byte(val), 0644))
+			link := filepath.Join(dir, name)
+			if _, err := os.Lstat(link); os.IsNotExist(err) {
+				require.NoError(t, os.Symlink(filepath.Join(projectedDataDirectory, name), link))
+			}
+		}
+		tmp := filepath.Join(dir, "..data_tmp")
+		require.NoError(t, os.Symlink(filepath.Base(snapshot), tmp))
+		require.NoError(t, os.Rename(tmp, filepath.Join(dir, projectedDataDirectory)))
+	}
+	publish(files)
+	return publish
+}
+
+func TestKubernetesSecret(t *testing.T) {
+	dir := t.TempDir()
+	publish := writeProjectedVolume(t, dir, map[string]string{"api_token": testCredValue + "\n"})
+
+	prov := NewKubernetesSecretFactory(dir).Create(confmaptest.NewNopProviderSettings())
+	assert.Equal(t, "kubernetessecret", prov.Scheme())
+	ret, err := prov.Retrieve(context.Background(), kubernetesSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+
+	publish(map[string]string{"api_token": "rotated"})
+	ret, err = prov.Retrieve(context.Background(), kubernetesSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	str, err = ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "rotated", str)
+
+	_, err = prov.Retrieve(context.Background(), kubernetesSchemePrefix+"missing", nil)
+	assert.ErrorContains(t, err, "..2024_01_01_00_00_02")
+	_, err = prov.Retrieve(context.Background(), kubernetesSchemePrefix+"..data", nil)
+	assert.ErrorContains(t, err, "reserved")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestKubernetesSecretPlainDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "api_token"), []byte(testCredValue), 0600))
+
+	prov := NewKubernetesSecretFactory(dir).Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), kubernetesSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
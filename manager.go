@@ -0,0 +1,32 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import "bou.ke/systemdcredentialprovider/internal/unitcreds"
+
+// ServiceManager selects the kind of systemd service manager the collector runs under.
+type ServiceManager string
+
+const (
+	// ServiceManagerAuto detects the service manager from the control group of the process.
+	ServiceManagerAuto ServiceManager = "auto"
+	// ServiceManagerSystem is the system service manager, running system services.
+	ServiceManagerSystem ServiceManager = "system"
+	// ServiceManagerUser is the service manager of a user, started with `systemd --user`, running
+	// user services.
+	ServiceManagerUser ServiceManager = "user"
+)
+
+// serviceUnit returns the service the process runs in, or an empty string when it doesn't run
+// under systemd, and whether the service is run by a user service manager.
+func (p *provider) serviceUnit() (string, bool) {
+	unit, user := unitcreds.Service(p.cfg.procSelfCgroup)
+	switch p.cfg.serviceManager {
+	case ServiceManagerSystem:
+		user = false
+	case ServiceManagerUser:
+		user = true
+	}
+	return unit, user
+}
@@ -0,0 +1,92 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+// userServiceCgroup is the control group of a service run by the service manager of user 1000.
+const userServiceCgroup = "0::/user.slice/user-1000.slice/user@1000.service/app.slice/otelcol.service\n"
+
+func TestUserUnitDirectoryDiscovery(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	credDir := filepath.Join(runtimeDir, "credentials", "otelcol.service")
+	require.NoError(t, os.MkdirAll(credDir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	cgroupFile := filepath.Join(t.TempDir(), "cgroup")
+	require.NoError(t, os.WriteFile(cgroupFile, []byte(userServiceCgroup), 0600))
+	withPaths := optionFunc(func(cfg *config) {
+		cfg.procSelfCgroup = cgroupFile
+		cfg.runCredentialsDirectory = t.TempDir()
+	})
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+	require.NoError(t, os.Unsetenv("CREDENTIALS_DIRECTORY"))
+	t.Setenv(invocationIDEnv, "0123456789abcdef0123456789abcdef")
+
+	prov := NewFactory(withPaths, WithUnitDirectoryDiscovery(true)).Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	// Forcing the system service manager looks in the system credentials directories instead.
+	prov = NewFactory(withPaths, WithUnitDirectoryDiscovery(true), WithServiceManager(ServiceManagerSystem)).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.Error(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestServiceUnit(t *testing.T) {
+	cgroupFile := filepath.Join(t.TempDir(), "cgroup")
+	require.NoError(t, os.WriteFile(cgroupFile, []byte(userServiceCgroup), 0600))
+	t.Setenv(invocationIDEnv, "0123456789abcdef0123456789abcdef")
+
+	for _, tt := range []struct {
+		manager ServiceManager
+		user    bool
+	}{
+		{manager: ServiceManagerAuto, user: true},
+		{manager: ServiceManagerSystem, user: false},
+		{manager: ServiceManagerUser, user: true},
+	} {
+		t.Run(string(tt.manager), func(t *testing.T) {
+			p := &provider{cfg: newConfig([]Option{WithServiceManager(tt.manager), optionFunc(func(cfg *config) {
+				cfg.procSelfCgroup = cgroupFile
+			})})}
+			unit, user := p.serviceUnit()
+			assert.Equal(t, "otelcol.service", unit)
+			assert.Equal(t, tt.user, user)
+		})
+	}
+}
+
+func TestEncryptedUserCredential(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("blob"), 0600))
+	script := filepath.Join(t.TempDir(), "systemd-creds")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+[ "$1" = --user ] && [ "$2" = decrypt ] || exit 2
+echo user
+`), 0700))
+
+	prov := NewFactory(WithSystemdCredsCommand(script), WithServiceManager(ServiceManagerUser)).Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token?encrypted=true", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "user", str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
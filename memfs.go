@@ -0,0 +1,59 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"bytes"
+	"io/fs"
+	"maps"
+	"slices"
+	"time"
+)
+
+// memFS is a read-only file system holding credentials in memory, by name, such as the ones of
+// the development shim read from .credentials.yaml.
+type memFS map[string][]byte
+
+func (fsys memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		names := slices.Sorted(maps.Keys(fsys))
+		dirEntries := make([]fs.DirEntry, len(names))
+		for i, name := range names {
+			dirEntries[i] = fs.FileInfoToDirEntry(memFileInfo{name: name, size: int64(len(fsys[name])), mode: 0400})
+		}
+		return &snapshotDir{info: memFileInfo{name: ".", mode: fs.ModeDir | 0500}, entries: dirEntries}, nil
+	}
+	val, ok := fsys[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memFile{Reader: bytes.NewReader(val), info: memFileInfo{name: name, size: int64(len(val)), mode: 0400}}, nil
+}
+
+// memFile is an opened credential of a memFS.
+type memFile struct {
+	*bytes.Reader
+	info memFileInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (*memFile) Close() error { return nil }
+
+// memFileInfo describes a credential of a memFS, or its root directory.
+type memFileInfo struct {
+	name string
+	size int64
+	mode fs.FileMode
+}
+
+func (i memFileInfo) Name() string      { return i.name }
+func (i memFileInfo) Size() int64       { return i.size }
+func (i memFileInfo) Mode() fs.FileMode { return i.mode }
+func (memFileInfo) ModTime() time.Time  { return time.Time{} }
+func (i memFileInfo) IsDir() bool       { return i.mode.IsDir() }
+func (memFileInfo) Sys() any            { return nil }
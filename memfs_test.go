@@ -0,0 +1,16 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemFS(t *testing.T) {
+	fsys := memFS{"api_token": []byte(testCredValue), "empty": nil}
+	assert.NoError(t, fstest.TestFS(fsys, "api_token", "empty"))
+}
@@ -0,0 +1,124 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.uber.org/zap"
+)
+
+const (
+	metaSchemeName = "systemdmeta"
+
+	// machineIDPath holds the ID of the machine, see machine-id(5).
+	machineIDPath = "/etc/machine-id"
+	// bootIDPath holds the ID of the current boot, generated by the kernel.
+	bootIDPath = "/proc/sys/kernel/random/boot_id"
+)
+
+// metaKeys lists the keys supported by the systemdmeta provider.
+var metaKeys = []string{"boot_id", "invocation_id", "machine_id", "unit"}
+
+// NewMetaFactory returns a factory for a confmap.Provider that resolves the runtime identity of
+// the collector as managed by systemd, so that it can be set as resource attributes such as
+// service.instance.id without a wrapper script exporting it. None of these are secrets.
+//
+// This Provider supports "systemdmeta" scheme, or the one set with WithScheme, and can be called
+// with a key: `systemdmeta:KEY`
+//
+// Supported keys:
+//   - `unit`: the name of the service the collector runs in, e.g. `otelcol.service`, see
+//     WithServiceManager.
+//   - `invocation_id`: the ID of the current invocation of the service, $INVOCATION_ID.
+//   - `machine_id`: the ID of the machine, from /etc/machine-id.
+//   - `boot_id`: the ID of the current boot, from /proc/sys/kernel/random/boot_id.
+//
+// Like for the env provider, a default value can follow the key after ":-", used when the value
+// isn't available, e.g. when the collector doesn't run under systemd: `systemdmeta:unit:-otelcol`.
+// Otherwise, an unavailable value fails with ErrNotFound.
+func NewMetaFactory(opts ...Option) confmap.ProviderFactory {
+	defaults := optionFunc(func(cfg *config) {
+		cfg.scheme = metaSchemeName
+	})
+	cfg := newConfig(append([]Option{defaults}, opts...))
+	return confmap.NewProviderFactory(func(ps confmap.ProviderSettings) confmap.Provider {
+		return &metaProvider{p: newProvider(ps, cfg).(*provider)}
+	})
+}
+
+// metaProvider resolves the runtime identity of the collector, see NewMetaFactory.
+type metaProvider struct {
+	// p looks up the unit of the collector like the credentials provider does.
+	p *provider
+}
+
+func (m *metaProvider) Retrieve(_ context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	selector, ok := strings.CutPrefix(uri, m.Scheme()+":")
+	if !ok {
+		return nil, fmt.Errorf("%q uri is not supported by %q provider", uri, m.Scheme())
+	}
+	key, defaultValue, hasDefault := strings.Cut(selector, ":-")
+	if !slices.Contains(metaKeys, key) {
+		return nil, fmt.Errorf("uri %q selects unsupported key %q, supported keys are: %s", uri, key, strings.Join(metaKeys, ", "))
+	}
+	val, err := m.lookup(key)
+	if err != nil && hasDefault {
+		m.p.cfg.logger.Debug("Using default value of unavailable metadata", zap.String("key", key), zap.Error(err))
+		return confmap.NewRetrieved(defaultValue)
+	}
+	if err != nil {
+		return nil, withKind(ErrNotFound, fmt.Errorf("failed to resolve uri %q: %w", uri, err))
+	}
+	return confmap.NewRetrieved(val)
+}
+
+// lookup returns the value of key, one of metaKeys.
+func (m *metaProvider) lookup(key string) (string, error) {
+	switch key {
+	case "unit":
+		unit, _ := m.p.serviceUnit()
+		if unit == "" {
+			return "", errors.New("the process doesn't run as part of a systemd service")
+		}
+		return unit, nil
+	case "invocation_id":
+		id, ok := os.LookupEnv(invocationIDEnv)
+		if !ok || id == "" {
+			return "", fmt.Errorf("$%s is not set", invocationIDEnv)
+		}
+		return id, nil
+	case "machine_id":
+		return readID(m.p.cfg.machineIDPath)
+	default:
+		return readID(m.p.cfg.bootIDPath)
+	}
+}
+
+// readID reads the ID stored in the file at path.
+func readID(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	id := strings.TrimSpace(string(data))
+	if id == "" {
+		return "", fmt.Errorf("%q is empty", path)
+	}
+	return id, nil
+}
+
+func (m *metaProvider) Scheme() string {
+	return m.p.cfg.scheme
+}
+
+func (m *metaProvider) Shutdown(ctx context.Context) error {
+	return m.p.Shutdown(ctx)
+}
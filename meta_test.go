@@ -0,0 +1,85 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestMetaProvider(t *testing.T) {
+	dir := t.TempDir()
+	cgroupFile := filepath.Join(dir, "cgroup")
+	require.NoError(t, os.WriteFile(cgroupFile, []byte("0::/system.slice/otelcol.service\n"), 0600))
+	machineIDFile := filepath.Join(dir, "machine-id")
+	require.NoError(t, os.WriteFile(machineIDFile, []byte("4f8e3a2b1c0d4e5f8a9b0c1d2e3f4a5b\n"), 0600))
+	bootIDFile := filepath.Join(dir, "boot_id")
+	require.NoError(t, os.WriteFile(bootIDFile, []byte("0b5e7c3a-9d1f-4e2a-8b6c-1d2e3f4a5b6c\n"), 0600))
+	withPaths := optionFunc(func(cfg *config) {
+		cfg.procSelfCgroup = cgroupFile
+		cfg.machineIDPath = machineIDFile
+		cfg.bootIDPath = bootIDFile
+	})
+	t.Setenv(invocationIDEnv, "0123456789abcdef0123456789abcdef")
+
+	prov := NewMetaFactory(withPaths).Create(confmaptest.NewNopProviderSettings())
+	assert.Equal(t, "systemdmeta", prov.Scheme())
+	for uri, want := range map[string]string{
+		"systemdmeta:unit":                  "otelcol.service",
+		"systemdmeta:invocation_id":         "0123456789abcdef0123456789abcdef",
+		"systemdmeta:machine_id":            "4f8e3a2b1c0d4e5f8a9b0c1d2e3f4a5b",
+		"systemdmeta:boot_id":               "0b5e7c3a-9d1f-4e2a-8b6c-1d2e3f4a5b6c",
+		"systemdmeta:unit:-unused":          "otelcol.service",
+		"systemdmeta:machine_id:-123456789": "4f8e3a2b1c0d4e5f8a9b0c1d2e3f4a5b",
+	} {
+		ret, err := prov.Retrieve(context.Background(), uri, nil)
+		require.NoError(t, err, uri)
+		raw, err := ret.AsRaw()
+		require.NoError(t, err)
+		assert.Equal(t, want, raw, uri)
+	}
+
+	_, err := prov.Retrieve(context.Background(), "systemdmeta:hostname", nil)
+	assert.ErrorContains(t, err, `unsupported key "hostname", supported keys are: boot_id, invocation_id, machine_id, unit`)
+	_, err = prov.Retrieve(context.Background(), "systemdcredential:unit", nil)
+	assert.ErrorContains(t, err, "is not supported")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	prov = NewMetaFactory(withPaths, WithScheme("meta")).Create(confmaptest.NewNopProviderSettings())
+	assert.Equal(t, "meta", prov.Scheme())
+	ret, err := prov.Retrieve(context.Background(), "meta:unit", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "otelcol.service", str)
+	_, err = prov.Retrieve(context.Background(), "systemdmeta:unit", nil)
+	assert.ErrorContains(t, err, "is not supported")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestMetaProviderOutsideSystemd(t *testing.T) {
+	t.Setenv(invocationIDEnv, "")
+	require.NoError(t, os.Unsetenv(invocationIDEnv))
+	withPaths := optionFunc(func(cfg *config) {
+		cfg.machineIDPath = filepath.Join(t.TempDir(), "missing")
+	})
+
+	prov := NewMetaFactory(withPaths).Create(confmaptest.NewNopProviderSettings())
+	for _, uri := range []string{"systemdmeta:unit", "systemdmeta:invocation_id", "systemdmeta:machine_id"} {
+		_, err := prov.Retrieve(context.Background(), uri, nil)
+		assert.ErrorIs(t, err, ErrNotFound, uri)
+	}
+	ret, err := prov.Retrieve(context.Background(), "systemdmeta:unit:-otelcol", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "otelcol", str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
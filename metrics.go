@@ -0,0 +1,140 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// meterName is the instrumentation scope of the provider's metrics.
+const meterName = "bou.ke/systemdcredentialprovider"
+
+// providerMetrics holds the instruments the provider reports its retrievals with.
+type providerMetrics struct {
+	retrievals metric.Int64Counter
+	failures   metric.Int64Counter
+	duration   metric.Float64Histogram
+	size       metric.Int64Histogram
+	expiry     metric.Float64Gauge
+	staleness  metric.Float64ObservableGauge
+
+	// modTime returns the modification time of the credential at a path, see provider.modTime.
+	modTime      func(path string) (time.Time, error)
+	registration metric.Registration
+	mu           sync.Mutex
+	// paths maps the names of the retrieved credentials to the paths they were read from.
+	paths map[string]string
+}
+
+func newProviderMetrics(mp metric.MeterProvider, scheme string, modTime func(string) (time.Time, error)) (*providerMetrics, error) {
+	meter := mp.Meter(meterName)
+	m := providerMetrics{modTime: modTime, paths: map[string]string{}}
+	var err, errs error
+	m.retrievals, err = meter.Int64Counter("systemdcredential.retrievals",
+		metric.WithDescription("Number of credential references retrieved."),
+		metric.WithUnit("{retrieval}"))
+	errs = errors.Join(errs, err)
+	m.failures, err = meter.Int64Counter("systemdcredential.retrieval.failures",
+		metric.WithDescription("Number of credential references that failed to resolve, by reason."),
+		metric.WithUnit("{failure}"))
+	errs = errors.Join(errs, err)
+	m.duration, err = meter.Float64Histogram("systemdcredential.retrieval.duration",
+		metric.WithDescription("Time taken to retrieve a credential reference."),
+		metric.WithUnit("s"))
+	errs = errors.Join(errs, err)
+	m.size, err = meter.Int64Histogram("systemdcredential.credential.size",
+		metric.WithDescription("Size of the values credential references resolved to."),
+		metric.WithUnit("By"))
+	errs = errors.Join(errs, err)
+	m.expiry, err = meter.Float64Gauge("systemdcredential.certificate.time_to_expiry",
+		metric.WithDescription("Time until the earliest-expiring certificate in a credential expires, negative once it expired."),
+		metric.WithUnit("s"))
+	errs = errors.Join(errs, err)
+	m.staleness, err = meter.Float64ObservableGauge("systemdcredential.credential.staleness",
+		metric.WithDescription("Time since the file a retrieved credential was read from was last modified."),
+		metric.WithUnit("s"))
+	errs = errors.Join(errs, err)
+	if errs != nil {
+		return nil, errs
+	}
+	m.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		m.observeStaleness(o, scheme)
+		return nil
+	}, m.staleness)
+	return &m, err
+}
+
+// trackStaleness reports the staleness of the credential called name, read from path, from now on.
+func (m *providerMetrics) trackStaleness(name, path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.paths[name] = path
+}
+
+// observeStaleness observes the time since every retrieved credential was last modified. The
+// modification time is looked up on every collection, so that a credential that is rotated
+// on disk is reported as fresh again. Credentials without a modification time, such as those
+// read from file descriptors, or that are gone are skipped.
+func (m *providerMetrics) observeStaleness(o metric.Observer, scheme string) {
+	m.mu.Lock()
+	paths := make(map[string]string, len(m.paths))
+	names := make([]string, 0, len(m.paths))
+	for name, path := range m.paths {
+		paths[name] = path
+		names = append(names, name)
+	}
+	m.mu.Unlock()
+	sort.Strings(names)
+	for _, name := range names {
+		modTime, err := m.modTime(paths[name])
+		if err != nil {
+			continue
+		}
+		o.ObserveFloat64(m.staleness, time.Since(modTime).Seconds(),
+			metric.WithAttributes(attribute.String("scheme", scheme), attribute.String("credential.name", name)))
+	}
+}
+
+// shutdown stops reporting the staleness of the retrieved credentials.
+func (m *providerMetrics) shutdown() error {
+	if m.registration == nil {
+		return nil
+	}
+	return m.registration.Unregister()
+}
+
+// record records the retrieval described by event.
+func (m *providerMetrics) record(ctx context.Context, scheme string, event RetrieveEvent) {
+	schemeAttr := attribute.String("scheme", scheme)
+	outcome := "success"
+	if event.Err != nil {
+		outcome = "failure"
+		m.failures.Add(ctx, 1, metric.WithAttributes(schemeAttr, attribute.String("reason", failureReason(event.Err))))
+	} else {
+		m.size.Record(ctx, int64(event.Bytes), metric.WithAttributes(schemeAttr))
+	}
+	outcomeAttrs := metric.WithAttributes(schemeAttr, attribute.String("outcome", outcome))
+	m.retrievals.Add(ctx, 1, outcomeAttrs)
+	m.duration.Record(ctx, event.Duration.Seconds(), outcomeAttrs)
+}
+
+// failureReason classifies err for the reason attribute of the failures metric.
+func failureReason(err error) string {
+	switch {
+	case isMissing(err):
+		return "not_found"
+	case errors.Is(err, fs.ErrPermission):
+		return "permission_denied"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// fakeMeter records the measurements of its instruments by instrument name and attributes.
+type fakeMeter struct {
+	noop.Meter
+	mu           sync.Mutex
+	measurements map[string][]float64
+	callbacks    []metric.Callback
+}
+
+// collect runs the registered callbacks, recording what they observe.
+func (m *fakeMeter) collect() {
+	for _, cb := range m.callbacks {
+		_ = cb(context.Background(), fakeObserver{meter: m})
+	}
+}
+
+func (m *fakeMeter) record(name string, attrs attribute.Set, val float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := name + "{" + attrs.Encoded(attribute.DefaultEncoder()) + "}"
+	m.measurements[key] = append(m.measurements[key], val)
+}
+
+func (m *fakeMeter) Int64Counter(name string, _ ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return fakeInt64Counter{meter: m, name: name}, nil
+}
+
+func (m *fakeMeter) Float64Histogram(name string, _ ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return fakeFloat64Histogram{meter: m, name: name}, nil
+}
+
+func (m *fakeMeter) Int64Histogram(name string, _ ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
+	return fakeInt64Histogram{meter: m, name: name}, nil
+}
+
+func (m *fakeMeter) Float64Gauge(name string, _ ...metric.Float64GaugeOption) (metric.Float64Gauge, error) {
+	return fakeFloat64Gauge{meter: m, name: name}, nil
+}
+
+func (m *fakeMeter) Float64ObservableGauge(name string, _ ...metric.Float64ObservableGaugeOption) (metric.Float64ObservableGauge, error) {
+	return fakeFloat64ObservableGauge{name: name}, nil
+}
+
+func (m *fakeMeter) RegisterCallback(cb metric.Callback, _ ...metric.Observable) (metric.Registration, error) {
+	m.callbacks = append(m.callbacks, cb)
+	return fakeRegistration{meter: m}, nil
+}
+
+type fakeRegistration struct {
+	noop.Registration
+	meter *fakeMeter
+}
+
+func (r fakeRegistration) Unregister() error {
+	r.meter.callbacks = nil
+	return nil
+}
+
+type fakeFloat64ObservableGauge struct {
+	noop.Float64ObservableGauge
+	name string
+}
+
+type fakeObserver struct {
+	noop.Observer
+	meter *fakeMeter
+}
+
+func (o fakeObserver) ObserveFloat64(obsrv metric.Float64Observable, val float64, opts ...metric.ObserveOption) {
+	o.meter.record(obsrv.(fakeFloat64ObservableGauge).name, metric.NewObserveConfig(opts).Attributes(), val)
+}
+
+type fakeInt64Counter struct {
+	noop.Int64Counter
+	meter *fakeMeter
+	name  string
+}
+
+func (c fakeInt64Counter) Add(_ context.Context, incr int64, opts ...metric.AddOption) {
+	c.meter.record(c.name, metric.NewAddConfig(opts).Attributes(), float64(incr))
+}
+
+type fakeFloat64Histogram struct {
+	noop.Float64Histogram
+	meter *fakeMeter
+	name  string
+}
+
+func (h fakeFloat64Histogram) Record(_ context.Context, val float64, opts ...metric.RecordOption) {
+	h.meter.record(h.name, metric.NewRecordConfig(opts).Attributes(), val)
+}
+
+type fakeInt64Histogram struct {
+	noop.Int64Histogram
+	meter *fakeMeter
+	name  string
+}
+
+func (h fakeInt64Histogram) Record(_ context.Context, val int64, opts ...metric.RecordOption) {
+	h.meter.record(h.name, metric.NewRecordConfig(opts).Attributes(), float64(val))
+}
+
+type fakeFloat64Gauge struct {
+	noop.Float64Gauge
+	meter *fakeMeter
+	name  string
+}
+
+func (g fakeFloat64Gauge) Record(_ context.Context, val float64, opts ...metric.RecordOption) {
+	g.meter.record(g.name, metric.NewRecordConfig(opts).Attributes(), val)
+}
+
+type fakeMeterProvider struct {
+	noop.MeterProvider
+	meter *fakeMeter
+}
+
+func (p fakeMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter {
+	return p.meter
+}
+
+func TestMetrics(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue+"\n"), 0600))
+	meter := &fakeMeter{measurements: map[string][]float64{}}
+
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithMeterProvider(fakeMeterProvider{meter: meter})).Create(confmaptest.NewNopProviderSettings())
+	for _, name := range []string{"api_token", "api_token", "missing", "../name"} {
+		_, _ = prov.Retrieve(context.Background(), credSchemePrefix+name, nil)
+	}
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	m := meter.measurements
+	assert.Equal(t, []float64{1, 1}, m["systemdcredential.retrievals{outcome=success,scheme=systemdcredential}"])
+	assert.Equal(t, []float64{1, 1}, m["systemdcredential.retrievals{outcome=failure,scheme=systemdcredential}"])
+	assert.Equal(t, []float64{1}, m["systemdcredential.retrieval.failures{reason=not_found,scheme=systemdcredential}"])
+	assert.Equal(t, []float64{1}, m["systemdcredential.retrieval.failures{reason=other,scheme=systemdcredential}"])
+	assert.Equal(t, []float64{float64(len(testCredValue)), float64(len(testCredValue))}, m["systemdcredential.credential.size{scheme=systemdcredential}"])
+	assert.Len(t, m["systemdcredential.retrieval.duration{outcome=success,scheme=systemdcredential}"], 2)
+	assert.Len(t, m["systemdcredential.retrieval.duration{outcome=failure,scheme=systemdcredential}"], 2)
+}
+
+func TestStalenessMetric(t *testing.T) {
+	credDir := t.TempDir()
+	credPath := filepath.Join(credDir, "api_token")
+	require.NoError(t, os.WriteFile(credPath, []byte(testCredValue), 0600))
+	modTime := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(credPath, modTime, modTime))
+	meter := &fakeMeter{measurements: map[string][]float64{}}
+	const key = "systemdcredential.credential.staleness{credential.name=api_token,scheme=systemdcredential}"
+
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithMeterProvider(fakeMeterProvider{meter: meter})).Create(confmaptest.NewNopProviderSettings())
+	meter.collect()
+	assert.Empty(t, meter.measurements, "credentials are only reported once retrieved")
+
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	_, _ = prov.Retrieve(context.Background(), credSchemePrefix+"missing", nil)
+	meter.collect()
+	require.Len(t, meter.measurements[key], 1)
+	assert.InDelta(t, 2*time.Hour.Seconds(), meter.measurements[key][0], time.Minute.Seconds())
+
+	// Rotating the credential makes it fresh again, without retrieving it again.
+	require.NoError(t, os.WriteFile(credPath, []byte("rotated"), 0600))
+	meter.collect()
+	require.Len(t, meter.measurements[key], 2)
+	assert.Less(t, meter.measurements[key][1], time.Minute.Seconds())
+	for name := range meter.measurements {
+		assert.NotContains(t, name, "missing")
+		assert.NotContains(t, name, testCredValue)
+	}
+
+	assert.NoError(t, prov.Shutdown(context.Background()))
+	assert.Empty(t, meter.callbacks)
+}
+
+func TestFailureReason(t *testing.T) {
+	assert.Equal(t, "not_found", failureReason(os.ErrNotExist))
+	assert.Equal(t, "not_found", failureReason(ErrNoCredentialsDirectory))
+	assert.Equal(t, "permission_denied", failureReason(os.ErrPermission))
+	assert.Equal(t, "other", failureReason(assert.AnError))
+}
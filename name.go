@@ -0,0 +1,43 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"strings"
+
+	"bou.ke/systemdcredentialprovider/creds"
+)
+
+// NameCase controls how names written in the configuration are transformed into the names of
+// the credentials that are read, see WithNameCase.
+type NameCase string
+
+const (
+	// NameCaseNone reads the credential with the name as written.
+	NameCaseNone NameCase = "none"
+	// NameCaseLower lowercases the name, e.g. `API_TOKEN` reads `api_token`.
+	NameCaseLower NameCase = "lower"
+	// NameCaseLowerKebab lowercases the name and replaces underscores with dashes, turning
+	// environment variable style names into systemd style ones, e.g. `API_TOKEN` reads `api-token`.
+	NameCaseLowerKebab NameCase = "lower-kebab"
+)
+
+var nameCaseFuncs = map[NameCase]func(string) string{
+	NameCaseNone: func(name string) string {
+		return name
+	},
+	NameCaseLower: strings.ToLower,
+	NameCaseLowerKebab: func(name string) string {
+		return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+	},
+}
+
+// credentialNameRules describes the rules enforced by validCredentialName.
+const credentialNameRules = `must be 1 to 255 printable ASCII characters other than '/' and ':', and must not be "." or ".."`
+
+// validCredentialName reports whether name is a valid credential name according to
+// systemd's own rules, see creds.ValidName.
+func validCredentialName(name string) bool {
+	return creds.ValidName(name)
+}
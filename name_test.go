@@ -0,0 +1,89 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestValidCredentialName(t *testing.T) {
+	valid := []string{
+		"api_token",
+		"myapp.api.token",
+		"1password",
+		"default%config",
+		"with space",
+		"...",
+		strings.Repeat("a", 255),
+	}
+	for _, name := range valid {
+		assert.True(t, validCredentialName(name), "expected %q to be valid", name)
+	}
+
+	invalid := []string{
+		"",
+		".",
+		"..",
+		"../etc/passwd",
+		"dir/cred",
+		"my:cred",
+		"tab\tcred",
+		"newline\n",
+		"café",
+		strings.Repeat("a", 256),
+	}
+	for _, name := range invalid {
+		assert.False(t, validCredentialName(name), "expected %q to be invalid", name)
+	}
+}
+
+func TestNameCase(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api-token"), []byte(testCredValue), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "db_password"), []byte("lower"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "otel.exporter.token"), []byte("aliased"), 0600))
+
+	for _, tt := range []struct {
+		nameCase NameCase
+		uri      string
+		want     string
+	}{
+		{nameCase: NameCaseLowerKebab, uri: "API_TOKEN", want: testCredValue},
+		{nameCase: NameCaseLowerKebab, uri: "api-token", want: testCredValue},
+		{nameCase: NameCaseLower, uri: "DB_PASSWORD", want: "lower"},
+		{nameCase: NameCaseNone, uri: "db_password", want: "lower"},
+		// Aliases are not transformed.
+		{nameCase: NameCaseLowerKebab, uri: "EXPORTER_TOKEN", want: "aliased"},
+	} {
+		t.Run(string(tt.nameCase)+"/"+tt.uri, func(t *testing.T) {
+			prov := NewFactory(WithNameCase(tt.nameCase), WithAliases(map[string]string{"EXPORTER_TOKEN": "otel.exporter.token"})).
+				Create(confmaptest.NewNopProviderSettings())
+			ret, err := prov.Retrieve(context.Background(), credSchemePrefix+tt.uri, nil)
+			require.NoError(t, err)
+			str, err := ret.AsString()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, str)
+			assert.NoError(t, prov.Shutdown(context.Background()))
+		})
+	}
+
+	prov := NewFactory().Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"DB_PASSWORD", nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	prov = NewFactory(WithNameCase("upper")).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"db_password", nil)
+	assert.EqualError(t, err, `unsupported name case "upper"`)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
@@ -0,0 +1,53 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	utf8BOM    = []byte{0xef, 0xbb, 0xbf}
+	utf16LEBOM = []byte{0xff, 0xfe}
+	utf16BEBOM = []byte{0xfe, 0xff}
+)
+
+// normalize strips a leading byte order mark, transcodes UTF-16 content to UTF-8
+// and replaces CRLF line endings with LF.
+func normalize(val []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(val, utf8BOM):
+		val = val[len(utf8BOM):]
+	case bytes.HasPrefix(val, utf16LEBOM):
+		var err error
+		if val, err = decodeUTF16(val[len(utf16LEBOM):], binary.LittleEndian); err != nil {
+			return nil, err
+		}
+	case bytes.HasPrefix(val, utf16BEBOM):
+		var err error
+		if val, err = decodeUTF16(val[len(utf16BEBOM):], binary.BigEndian); err != nil {
+			return nil, err
+		}
+	}
+	return bytes.ReplaceAll(val, []byte("\r\n"), []byte("\n")), nil
+}
+
+func decodeUTF16(val []byte, order binary.ByteOrder) ([]byte, error) {
+	if len(val)%2 != 0 {
+		return nil, errors.New("UTF-16 content has an odd number of bytes")
+	}
+	units := make([]uint16, len(val)/2)
+	for i := range units {
+		units[i] = order.Uint16(val[2*i:])
+	}
+	out := make([]byte, 0, len(units))
+	for _, r := range utf16.Decode(units) {
+		out = utf8.AppendRune(out, r)
+	}
+	return out, nil
+}
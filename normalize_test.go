@@ -0,0 +1,38 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected string
+	}{
+		{name: "plain", input: []byte("token\n"), expected: "token\n"},
+		{name: "crlf", input: []byte("line1\r\nline2\r\n"), expected: "line1\nline2\n"},
+		{name: "utf-8 bom", input: []byte("\xef\xbb\xbftoken\r\n"), expected: "token\n"},
+		{name: "utf-16le", input: []byte{0xff, 0xfe, 't', 0, 0xe9, 0, '\r', 0, '\n', 0}, expected: "té\n"},
+		{name: "utf-16be", input: []byte{0xfe, 0xff, 0, 't', 0, 0xe9}, expected: "té"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := normalize(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(out))
+		})
+	}
+}
+
+func TestNormalizeOddUTF16(t *testing.T) {
+	_, err := normalize([]byte{0xff, 0xfe, 't'})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "odd number of bytes")
+}
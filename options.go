@@ -0,0 +1,659 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"crypto/ed25519"
+	"io/fs"
+	"maps"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"bou.ke/systemdcredentialprovider/internal/journal"
+)
+
+// defaultMaxSize is the default maximum size of a credential, see WithMaxSize.
+const defaultMaxSize = 1 << 20
+
+// config holds the factory-wide settings of the provider.
+type config struct {
+	// scheme is the URI scheme of the provider.
+	scheme string
+
+	credentialsDirectory    string
+	credentialsDirectoryEnv []string
+	discoverUnitDirectory   bool
+	procSelfCgroup          string
+	runCredentialsDirectory string
+	serviceManager          ServiceManager
+	detectContainer         bool
+	containerRoot           string
+	devMode                 bool
+	devDirectory            string
+	machineIDPath           string
+	bootIDPath              string
+	fsys                    fs.FS
+	projectedVolume         bool
+	fileRoots               []string
+	envFilePaths            map[string]string
+	envFile                 bool
+	listenFDs               bool
+	listenFDsStart          int
+	validator               *Validator
+	redactor                *Redactor
+	accessLog               *AccessLog
+	statusNotification      bool
+	auditLog                bool
+	retrieveHook            func(RetrieveEvent)
+	sourceMetadata          bool
+	escapeDollarSigns       bool
+	meterProvider           metric.MeterProvider
+	tracerProvider          trace.TracerProvider
+	journalSocket           string
+	logger                  *zap.Logger
+	certExpiryWindow        time.Duration
+
+	retryAttempts int
+	retryBackoff  time.Duration
+	cacheTTL      time.Duration
+	preload       bool
+	watchInterval time.Duration
+
+	trimMode        TrimMode
+	permissionCheck PermissionCheck
+	checksumPolicy  ChecksumPolicy
+	symlinkPolicy   SymlinkPolicy
+	normalize       bool
+	strict          bool
+	maxSize         int64
+
+	systemFallback             bool
+	systemCredentialsDirectory string
+	caseInsensitiveFallback    bool
+	aliases                    map[string]string
+	nameCase                   NameCase
+	allowedCredentials         []string
+	deniedCredentials          []string
+	signatureKeys              []ed25519.PublicKey
+	signatureKeyCredential     string
+	envFallback                bool
+	envFallbackPrefix          string
+	fileFallback               bool
+	systemdCredsCommand        string
+	ageCommand                 string
+	sopsCommand                string
+	credentialSecretPath       string
+	varlinkSocket              string
+	firmwareFallback           bool
+	smbiosEntriesDirectory     string
+	fwCfgCredentialsDirectory  string
+	unitValidation             bool
+	validationUnit             string
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{
+		scheme:                     schemeName,
+		credentialsDirectoryEnv:    []string{credentialsDirectoryEnv},
+		procSelfCgroup:             procSelfCgroup,
+		runCredentialsDirectory:    runCredentialsDirectory,
+		serviceManager:             ServiceManagerAuto,
+		containerRoot:              "/",
+		devMode:                    devModeFromEnv(),
+		devDirectory:               ".",
+		machineIDPath:              machineIDPath,
+		bootIDPath:                 bootIDPath,
+		systemCredentialsDirectory: systemCredentialsDirectory,
+		listenFDsStart:             listenFDsStart,
+		journalSocket:              journal.DefaultSocket,
+		smbiosEntriesDirectory:     smbiosEntriesDirectory,
+		fwCfgCredentialsDirectory:  fwCfgCredentialsDirectory,
+		trimMode:                   TrimTrailingNewline,
+		nameCase:                   NameCaseNone,
+		permissionCheck:            PermissionCheckOff,
+		checksumPolicy:             ChecksumOff,
+		symlinkPolicy:              SymlinkFollowWithinDirectory,
+		maxSize:                    defaultMaxSize,
+		systemdCredsCommand:        defaultSystemdCredsCommand,
+		ageCommand:                 defaultAgeCommand,
+		sopsCommand:                defaultSopsCommand,
+		watchInterval:              defaultWatchInterval,
+	}
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+	return cfg
+}
+
+// Option configures the provider created by NewFactory.
+type Option interface {
+	apply(*config)
+}
+
+type optionFunc func(*config)
+
+func (of optionFunc) apply(cfg *config) {
+	of(cfg)
+}
+
+// WithScheme registers the provider under scheme instead of its default scheme, such as
+// "systemdcredential" for NewFactory. Creating a second factory with it makes the same
+// credentials available under an alias, e.g. for configurations written for another
+// distribution:
+//
+//	NewFactory(), NewFactory(WithScheme("cred"))
+//
+// Fallback chains and expansion use the scheme of the provider. CredentialReferences and
+// CredentialReferenceURIs only find references using the default "systemdcredential" scheme.
+func WithScheme(scheme string) Option {
+	return schemeOption(scheme)
+}
+
+// schemeOption is the Option returned by WithScheme, a type of its own so that NewFactories can
+// leave it out.
+type schemeOption string
+
+func (o schemeOption) apply(cfg *config) {
+	cfg.scheme = string(o)
+}
+
+// WithCredentialsDirectory sets the directory credentials are read from, instead of $CREDENTIALS_DIRECTORY.
+func WithCredentialsDirectory(dir string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.credentialsDirectory = dir
+	})
+}
+
+// WithCredentialsDirectoryEnv sets the environment variables the credentials directory is read
+// from when WithCredentialsDirectory is not used. The first variable that is set wins. This allows
+// supervisors other than systemd to pass a compatible directory under a different variable.
+// The default is $CREDENTIALS_DIRECTORY.
+func WithCredentialsDirectoryEnv(names ...string) Option {
+	names = slices.Clone(names)
+	return optionFunc(func(cfg *config) {
+		cfg.credentialsDirectoryEnv = names
+	})
+}
+
+// WithUnitDirectoryDiscovery controls whether, when no credentials directory is configured
+// but $INVOCATION_ID shows that the process runs under systemd, the provider looks up the
+// service owning the process in /proc/self/cgroup and uses /run/credentials/<unit>.service.
+// This is disabled by default, so that where credentials are read from doesn't depend on the host.
+func WithUnitDirectoryDiscovery(enabled bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.discoverUnitDirectory = enabled
+	})
+}
+
+// WithContainerDetection controls whether, when no credentials directory is configured or
+// discovered from the unit, the provider detects that it runs in a container and uses the
+// directory the container manager places credentials in: /run/host/credentials for
+// systemd-nspawn and /run/secrets for Podman, including quadlets, and Docker. The container
+// manager is detected from $container, /run/host/container-manager, /run/.containerenv and
+// /.dockerenv. This is disabled by default, so that a missing $CREDENTIALS_DIRECTORY fails
+// rather than silently reading container secrets; the containersecret scheme reads those
+// explicitly, see NewContainerSecretFactory.
+func WithContainerDetection(enabled bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.detectContainer = enabled
+	})
+}
+
+// WithDevMode controls the insecure development shim, for running configurations that reference
+// credentials on machines without systemd, such as macOS and Windows. When no credentials
+// directory is configured, discovered or detected, credentials are read from the .credentials
+// directory in the working directory or, when it doesn't exist, from the .credentials.yaml file
+// there, holding a mapping of credential names to values. A warning is logged once the shim is
+// used. It is disabled by default, and enabled when $SYSTEMDCREDENTIAL_DEV is set to a true
+// value, such as 1, unless disabled with this option.
+func WithDevMode(enabled bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.devMode = enabled
+	})
+}
+
+// WithServiceManager sets whether the collector runs as a system service or as a user service,
+// under `systemd --user`. This selects where the credentials directory is discovered, see
+// WithUnitDirectoryDiscovery, the journal field naming the unit in audit entries, the bus
+// WithUnitValidation queries, and the scope of credentials decrypted with `encrypted=true`.
+// The default is ServiceManagerAuto, which detects user services from their control group.
+func WithServiceManager(manager ServiceManager) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.serviceManager = manager
+	})
+}
+
+// WithFS sets the file system credentials are read from, taking precedence over
+// WithCredentialsDirectory and $CREDENTIALS_DIRECTORY. Credentials are looked up
+// by name at the root of fsys.
+func WithFS(fsys fs.FS) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.fsys = fsys
+	})
+}
+
+// WithLogger sets the logger used to report directory resolution, rejected names and fallback
+// decisions, instead of the logger of the confmap.ProviderSettings. Credential values are never logged.
+func WithLogger(logger *zap.Logger) Option {
+	return optionFunc(func(cfg *config) {
+		if logger != nil {
+			cfg.logger = logger
+		}
+	})
+}
+
+// WithTrimMode sets the trim mode applied to credentials whose URI doesn't set the `trim` query parameter.
+// The default is TrimTrailingNewline.
+func WithTrimMode(mode TrimMode) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.trimMode = mode
+	})
+}
+
+// WithReadRetry makes the provider retry reading a credential up to attempts times when it
+// doesn't exist or is truncated while being read, as happens when it is replaced during a
+// rotation. The first retry happens after backoff, which doubles for every further retry.
+// Retries stop when the context passed to Retrieve is done. By default reads aren't retried.
+func WithReadRetry(attempts int, backoff time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.retryAttempts = attempts
+		cfg.retryBackoff = backoff
+	})
+}
+
+// WithCache makes the provider cache the contents of credentials for ttl, so that a credential
+// referenced many times is only read once, and concurrent retrievals of the same credential share
+// a single read. Cached credentials are removed, and their contents wiped, once the values read
+// from them are closed, which the resolver does when the configuration is reloaded, as well as on
+// Shutdown. Failed reads aren't cached. By default credentials aren't cached.
+func WithCache(ttl time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.cacheTTL = ttl
+	})
+}
+
+// WithPreload makes the provider read all credentials in the credentials directory into memory
+// when it is created, and serve every retrieval from that snapshot. Configuration resolution then
+// doesn't depend on the directory anymore, e.g. when it is unmounted or the sandbox is tightened
+// after startup. Credentials that fail to be preloaded keep failing with the same error. The
+// snapshot is wiped on Shutdown. Preloading doesn't apply to NewFDFactory and NewFileCredentialFactory.
+func WithPreload(enabled bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.preload = enabled
+	})
+}
+
+// WithWatchInterval sets how often the credentials read by Retrieve are checked for changes when
+// the resolver watches them, e.g. for a collector that reloads its configuration when credentials
+// are rotated. The resolver is notified once any of them changes, is created or is removed,
+// including the snapshot a projected volume publishes. The default is 5 seconds; an interval
+// <= 0 disables watching. Credentials read from file descriptors or preloaded aren't watched.
+func WithWatchInterval(interval time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.watchInterval = interval
+	})
+}
+
+// WithPermissionCheck sets what happens when a credential file read from a directory is owned by a
+// user other than root or the user the process runs as, or can be read or written by group members
+// or other users, e.g. a 0644 file passed with SetCredential=. The default is PermissionCheckOff;
+// PermissionCheckWarn logs a warning and PermissionCheckEnforce fails with ErrInsecurePermissions.
+func WithPermissionCheck(check PermissionCheck) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.permissionCheck = check
+	})
+}
+
+// WithChecksumVerification sets how credentials are verified against the credential called
+// NAME.sha256 next to them, holding the SHA-256 checksum of the credential called NAME on its own
+// or in the output format of `sha256sum`, so that corrupted or partially written credentials
+// fail to resolve. The default is ChecksumOff; ChecksumIfPresent verifies the credentials that
+// have a checksum and ChecksumRequired fails for those that don't. Failures are ErrChecksumMismatch.
+// The checksum covers the credential as stored, before it is decrypted or decoded.
+func WithChecksumVerification(policy ChecksumPolicy) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.checksumPolicy = policy
+	})
+}
+
+// WithSignatureVerification makes the provider refuse credentials that aren't signed by one of
+// keys, so that a compromised provisioning step can't inject values such as rogue endpoints. The
+// signature of the credential called NAME is read from the credential called NAME.sig, holding
+// an ed25519 signature of the credential as stored, raw or base64-encoded, or a minisign signature
+// created with `minisign -S -l`. Failures are ErrInvalidSignature. Can be combined with
+// WithSignatureKeyCredential.
+func WithSignatureVerification(keys ...ed25519.PublicKey) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.signatureKeys = append(cfg.signatureKeys, keys...)
+	})
+}
+
+// WithSignatureKeyCredential is like WithSignatureVerification, with the key read from the
+// credential called name, holding a base64-encoded ed25519 public key or a minisign public key.
+func WithSignatureKeyCredential(name string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.signatureKeyCredential = name
+	})
+}
+
+// WithSymlinkPolicy sets how credentials that are symlinks, such as the ones systemd creates for
+// some renamed credentials, are read from the credentials directory. The default is
+// SymlinkFollowWithinDirectory, which refuses symlinks that resolve outside of the directory.
+// SymlinkFollow follows symlinks wherever they point, except in the roots of
+// NewFileCredentialFactory, SymlinkReject refuses all symlinks. The policy doesn't apply to
+// file systems set with WithFS.
+func WithSymlinkPolicy(policy SymlinkPolicy) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.symlinkPolicy = policy
+	})
+}
+
+// WithNormalization enables content normalization for credentials whose URI doesn't set the
+// `normalize` query parameter: a leading byte order mark is stripped, UTF-16 content is
+// transcoded to UTF-8 and CRLF line endings are replaced with LF.
+func WithNormalization(enabled bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.normalize = enabled
+	})
+}
+
+// WithStrictContent rejects credentials whose URI doesn't set the `strict` query parameter when
+// they contain control characters other than tabs and newlines, NUL bytes or invalid UTF-8, as
+// corrupted or binary files do. Failures are ErrInvalidContent. Raw credentials are not checked.
+func WithStrictContent(enabled bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.strict = enabled
+	})
+}
+
+// WithMaxSize sets the maximum size in bytes of a credential. Larger credentials fail to
+// resolve without being read into memory. The default is 1 MiB; a size <= 0 disables the limit.
+func WithMaxSize(size int64) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.maxSize = size
+	})
+}
+
+// WithSystemCredentialsFallback makes the provider fall back to the system-wide credentials in
+// /run/credentials/@system (imported from SMBIOS, the kernel command line or the initrd) when a
+// credential doesn't exist in the service's own credentials directory.
+func WithSystemCredentialsFallback(enabled bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.systemFallback = enabled
+	})
+}
+
+// WithFirmwareCredentialsFallback makes the provider fall back to credentials passed to a virtual
+// machine through qemu fw_cfg (opt/io.systemd.credentials/NAME) or SMBIOS Type 11 OEM strings
+// (io.systemd.credential:NAME=VALUE and io.systemd.credential.binary:NAME=BASE64), read directly
+// from /sys/firmware. This allows using them before systemd has imported them into
+// /run/credentials/@system, e.g. while bootstrapping a cloud image. Reading SMBIOS requires root.
+func WithFirmwareCredentialsFallback(enabled bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.firmwareFallback = enabled
+	})
+}
+
+// WithCaseInsensitiveFallback makes the provider fall back to a case-insensitive match when
+// no credential has exactly the requested name, e.g. `api_token` for `API_TOKEN`.
+// Resolution fails if more than one credential matches.
+func WithCaseInsensitiveFallback(enabled bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.caseInsensitiveFallback = enabled
+	})
+}
+
+// WithAliases maps names used in the configuration to the names of the credentials that are
+// actually read, so that one configuration can be shared between units whose `LoadCredential=`
+// names differ. Names without an alias are read as is.
+func WithAliases(aliases map[string]string) Option {
+	aliases = maps.Clone(aliases)
+	return optionFunc(func(cfg *config) {
+		cfg.aliases = aliases
+	})
+}
+
+// WithNameCase transforms the names written in the configuration before the credentials are
+// read, e.g. NameCaseLowerKebab reads `api-token` for `${systemdcredential:API_TOKEN}`, so that
+// configurations written with environment variable style names don't need an alias for each
+// credential. Names with an alias, see WithAliases, are not transformed. The default is NameCaseNone.
+func WithNameCase(nameCase NameCase) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.nameCase = nameCase
+	})
+}
+
+// WithAllowedCredentials restricts the credentials that can be read to the ones whose name
+// matches one of patterns, using the syntax of path.Match, e.g. `tenant-a.*`. The patterns
+// apply to the names of the credentials that are actually read, after resolving aliases.
+// Calling it more than once adds to the allowed patterns. An invalid pattern fails the retrieval.
+// While allowed or denied patterns are set, the `env:` and `file:` sources of fallback chains and
+// `${env:...}` references in expanded credentials fail with ErrNotAllowed, as the patterns can't
+// apply to them.
+func WithAllowedCredentials(patterns ...string) Option {
+	patterns = slices.Clone(patterns)
+	return optionFunc(func(cfg *config) {
+		cfg.allowedCredentials = append(slices.Clip(cfg.allowedCredentials), patterns...)
+	})
+}
+
+// WithDeniedCredentials prevents the credentials whose name matches one of patterns from
+// being read, like WithAllowedCredentials. Denied patterns take precedence over allowed ones,
+// and denied credentials are never suggested as alternatives to missing ones.
+func WithDeniedCredentials(patterns ...string) Option {
+	patterns = slices.Clone(patterns)
+	return optionFunc(func(cfg *config) {
+		cfg.deniedCredentials = append(slices.Clip(cfg.deniedCredentials), patterns...)
+	})
+}
+
+// WithEnvFallback makes the provider fall back to the environment variable named prefix
+// followed by the credential name, e.g. `OTEL_CRED_api_token` for the prefix `OTEL_CRED_`,
+// when a credential doesn't exist or $CREDENTIALS_DIRECTORY is not set.
+// The prefix may be empty to use the credential name as is.
+func WithEnvFallback(prefix string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.envFallback = true
+		cfg.envFallbackPrefix = prefix
+	})
+}
+
+// WithFileFallback allows `file:` sources in fallback chains, e.g.
+// `systemdcredential:TOKEN|file:/etc/otel/token`, which read any file the configuration names.
+// The file is read with the symlink policy, permission check, size limit and checksum and signature
+// verification of credentials, relative to its directory, with the sidecars next to it. File sources
+// are disabled by default.
+func WithFileFallback(enabled bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.fileFallback = enabled
+	})
+}
+
+// WithAgeCommand sets the name or path of the age binary used to decrypt credentials referenced
+// with `age_identity=NAME`. The default is "age", looked up in $PATH; rage works as well.
+func WithAgeCommand(command string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ageCommand = command
+	})
+}
+
+// WithSopsCommand sets the name or path of the sops binary used to decrypt credentials referenced
+// with `sops=true`. The default is "sops", looked up in $PATH.
+func WithSopsCommand(command string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sopsCommand = command
+	})
+}
+
+// WithSystemdCredsCommand sets the name or path of the systemd-creds binary used to decrypt
+// credentials referenced with `encrypted=true`. The default is "systemd-creds", looked up in $PATH.
+func WithSystemdCredsCommand(command string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.systemdCredsCommand = command
+	})
+}
+
+// WithCredentialSecretPath sets the path of the host key used to natively decrypt credentials
+// referenced with `encrypted=true` that were encrypted with `systemd-creds encrypt --with-key=host`.
+// The default is $SYSTEMD_CREDENTIAL_SECRET if set, and /var/lib/systemd/credential.secret otherwise.
+func WithCredentialSecretPath(path string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.credentialSecretPath = path
+	})
+}
+
+// WithVarlinkSocket makes the provider decrypt credentials referenced with `encrypted=true` that
+// are not encrypted with the host key, such as TPM2-bound ones, by calling the io.systemd.Credentials
+// varlink service listening on path (usually /run/systemd/io.systemd.Credentials) instead of running
+// systemd-creds. This works in sandboxes where the systemd-creds binary or the TPM isn't available.
+func WithVarlinkSocket(path string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.varlinkSocket = path
+	})
+}
+
+// WithUnitValidation makes errors for missing credentials explain when the systemd service unit
+// doesn't pass the credential at all, by querying its LoadCredential=, SetCredential= and
+// ImportCredential= settings from the systemd manager over D-Bus. If unit is empty, the service
+// owning the current process is used. Use LookupUnitCredentials and CredentialReferences to
+// validate a whole configuration up front.
+func WithUnitValidation(unit string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.unitValidation = true
+		cfg.validationUnit = unit
+	})
+}
+
+// WithEnvFilePath makes the provider returned by NewEnvFileFactory read the env file called name
+// from the fixed path, e.g. /etc/default/otelcol, instead of from the credentials directory.
+func WithEnvFilePath(name, path string) Option {
+	return optionFunc(func(cfg *config) {
+		if cfg.envFilePaths == nil {
+			cfg.envFilePaths = map[string]string{}
+		}
+		cfg.envFilePaths[name] = path
+	})
+}
+
+// WithValidator makes the provider record references that fail to resolve in v instead of
+// failing, so that v's converter can report all of them together, see Validator.
+func WithValidator(v *Validator) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.validator = v
+	})
+}
+
+// WithRedactor makes the provider tag the values it resolves in r, so that r can redact them
+// from serialized configurations, see Redactor.
+func WithRedactor(r *Redactor) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.redactor = r
+	})
+}
+
+// WithAccessLog makes the provider record every credential it reads in a, without the values.
+func WithAccessLog(a *AccessLog) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.accessLog = a
+	})
+}
+
+// WithStatusNotification makes the provider report credentials that fail to resolve to systemd
+// with sd_notify STATUS=, e.g. "credential api_token missing", so that `systemctl status` shows
+// why the service failed. It has no effect unless the service manager set $NOTIFY_SOCKET.
+func WithStatusNotification(enabled bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.statusNotification = enabled
+	})
+}
+
+// WithAuditLog makes the provider write a structured journal entry for every credential it
+// reads, with the credential name, the URI, the outcome and the process ID, but never the value.
+// Entries have MESSAGE_ID=0b3f6f1e2c8a4f5d9e7a1c2b3d4e5f60 and CREDENTIAL_* fields.
+func WithAuditLog(enabled bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.auditLog = enabled
+	})
+}
+
+// WithDollarSignEscaping controls whether the provider doubles every '$' in the values it
+// returns, which is disabled by default. The resolver expands the values returned by providers
+// again and replaces `$$` with `$`, so without escaping, credentials containing `${...}` are
+// expanded and `$$` in credentials turns into `$`. Enabling it makes credentials resolve
+// byte-for-byte, but also stops references in whole configurations retrieved through the
+// provider from being expanded.
+func WithDollarSignEscaping(enabled bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.escapeDollarSigns = enabled
+	})
+}
+
+// WithRetrieveHook makes the provider call hook after every call to Retrieve, e.g. for custom
+// auditing or metrics. The event never holds the credential value. hook is called synchronously
+// and must not block. As the resolver may retrieve several URIs concurrently, hook must be safe
+// for concurrent use.
+func WithRetrieveHook(hook func(event RetrieveEvent)) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.retrieveHook = hook
+	})
+}
+
+// WithSourceMetadata makes the provider describe where each credential was read from in the
+// Sources of the events passed to the hook set with WithRetrieveHook: the path of the credential
+// and when it was last modified, e.g. to tell how stale a token is. Describing the sources costs
+// an extra stat of every credential.
+func WithSourceMetadata(enabled bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sourceMetadata = enabled
+	})
+}
+
+// WithMeterProvider makes the provider report metrics about its retrievals through mp, usually
+// the MeterProvider of the collector's own telemetry:
+//   - systemdcredential.retrievals: retrievals by scheme and outcome.
+//   - systemdcredential.retrieval.failures: failed retrievals by scheme and reason (not_found,
+//     permission_denied or other).
+//   - systemdcredential.retrieval.duration: retrieval latency in seconds.
+//   - systemdcredential.credential.size: size of the resolved values in bytes.
+//   - systemdcredential.certificate.time_to_expiry: seconds until the earliest certificate in a
+//     credential expires, by credential, see WithCertificateExpiryWarning.
+//   - systemdcredential.credential.staleness: seconds since the file every retrieved credential
+//     was read from was last modified, by credential, to alert on credentials that aren't rotated.
+//     Credentials read from file descriptors or environment variables aren't reported.
+//
+// Metrics only carry credential names, never values.
+//
+// confmap.ProviderSettings doesn't carry telemetry settings, so it has to be passed explicitly.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.meterProvider = mp
+	})
+}
+
+// WithCertificateExpiryWarning makes the provider inspect the PEM-encoded certificates in the
+// credentials it retrieves, logging a warning for every certificate that expires within window
+// or has already expired, so that it is noticed when the configuration is resolved rather than
+// when TLS handshakes start failing. With WithMeterProvider, the time left until the earliest
+// certificate of every credential expires is reported as systemdcredential.certificate.time_to_expiry.
+// Zero, the default, disables the inspection.
+func WithCertificateExpiryWarning(window time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.certExpiryWindow = window
+	})
+}
+
+// WithTracerProvider makes the provider create a span for every Retrieve call through tp. The span
+// records the names of the referenced credentials, an event for every path a credential was read
+// from, and the error the retrieval failed with. Credential values are never recorded.
+//
+// confmap.ProviderSettings doesn't carry telemetry settings, so it has to be passed explicitly.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.tracerProvider = tp
+	})
+}
@@ -0,0 +1,85 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// pemSelector selects a single PEM block from a credential holding several of them,
+// e.g. a certificate, its private key and the chain, see parsePEMSelector.
+type pemSelector struct {
+	// blockType is the type of the block to select, e.g. "CERTIFICATE"; any type when empty.
+	blockType string
+	// index is the index of the block among the blocks of blockType.
+	index int
+}
+
+// parsePEMSelector parses the fragment of a `systemdcredential:NAME#pem=TYPE&index=N` URI.
+// Both parameters are optional, but at least one has to be given.
+func parsePEMSelector(fragment string) (*pemSelector, error) {
+	params, err := url.ParseQuery(fragment)
+	if err != nil {
+		return nil, err
+	}
+	sel := &pemSelector{}
+	for key, values := range params {
+		if len(values) > 1 {
+			return nil, fmt.Errorf("parameter %q is specified more than once", key)
+		}
+		switch key {
+		case "pem":
+			if values[0] == "" {
+				return nil, errors.New("parameter \"pem\" must not be empty")
+			}
+			sel.blockType = values[0]
+		case "index":
+			if sel.index, err = strconv.Atoi(values[0]); err != nil || sel.index < 0 {
+				return nil, fmt.Errorf("parameter \"index\" must be a non-negative integer, got %q", values[0])
+			}
+		default:
+			return nil, fmt.Errorf("unsupported parameter %q", key)
+		}
+	}
+	if len(params) == 0 {
+		return nil, errors.New(`must select a PEM block with "pem" or "index"`)
+	}
+	return sel, nil
+}
+
+// String describes the selected block for error messages.
+func (s *pemSelector) String() string {
+	if s.blockType == "" {
+		return fmt.Sprintf("PEM block %d", s.index)
+	}
+	return fmt.Sprintf("%s PEM block %d", s.blockType, s.index)
+}
+
+// selectPEMBlock returns the block of content selected by s, PEM-encoded. Text outside of
+// the blocks, such as the comments written by `openssl x509 -text`, is ignored.
+func selectPEMBlock(content []byte, s *pemSelector) ([]byte, error) {
+	n := 0
+	for rest := content; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, fmt.Errorf("credential has no %s, it has %d matching blocks", s, n)
+		}
+		if s.blockType != "" && block.Type != s.blockType {
+			clear(block.Bytes)
+			continue
+		}
+		if n == s.index {
+			encoded := pem.EncodeToMemory(block)
+			clear(block.Bytes)
+			return encoded, nil
+		}
+		clear(block.Bytes)
+		n++
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+var (
+	testCertificate = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("leaf")})
+	testPrivateKey  = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")})
+	testChain       = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("intermediate")})
+)
+
+func TestParsePEMSelector(t *testing.T) {
+	sel, err := parsePEMSelector("pem=PRIVATE%20KEY")
+	require.NoError(t, err)
+	assert.Equal(t, &pemSelector{blockType: "PRIVATE KEY"}, sel)
+
+	sel, err = parsePEMSelector("pem=CERTIFICATE&index=1")
+	require.NoError(t, err)
+	assert.Equal(t, &pemSelector{blockType: "CERTIFICATE", index: 1}, sel)
+
+	for _, fragment := range []string{"", "pem=", "index=-1", "index=first", "pem=A&pem=B", "type=CERTIFICATE"} {
+		_, err = parsePEMSelector(fragment)
+		assert.Error(t, err, fragment)
+	}
+}
+
+func TestSelectPEMBlock(t *testing.T) {
+	bundle := append(append(append([]byte("subject=CN=leaf\n"), testCertificate...), testPrivateKey...), testChain...)
+
+	tests := []struct {
+		sel     pemSelector
+		want    []byte
+		wantErr string
+	}{
+		{sel: pemSelector{blockType: "CERTIFICATE"}, want: testCertificate},
+		{sel: pemSelector{blockType: "CERTIFICATE", index: 1}, want: testChain},
+		{sel: pemSelector{blockType: "PRIVATE KEY"}, want: testPrivateKey},
+		{sel: pemSelector{index: 1}, want: testPrivateKey},
+		{sel: pemSelector{blockType: "CERTIFICATE", index: 2}, wantErr: "credential has no CERTIFICATE PEM block 2, it has 2 matching blocks"},
+		{sel: pemSelector{blockType: "EC PRIVATE KEY"}, wantErr: "credential has no EC PRIVATE KEY PEM block 0, it has 0 matching blocks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sel.String(), func(t *testing.T) {
+			block, err := selectPEMBlock(bundle, &tt.sel)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, block)
+		})
+	}
+}
+
+func TestPEMBlockSelection(t *testing.T) {
+	credDir := t.TempDir()
+	bundle := append(append(append([]byte{}, testCertificate...), testPrivateKey...), testChain...)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "tls_bundle"), bundle, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "token"), []byte(testCredValue), 0600))
+
+	prov := NewFactory(WithCredentialsDirectory(credDir)).Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"tls_bundle#pem=PRIVATE KEY", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, string(testPrivateKey[:len(testPrivateKey)-1]), str)
+
+	ret, err = prov.Retrieve(context.Background(), credSchemePrefix+"tls_bundle?trim=none#pem=CERTIFICATE&index=1", nil)
+	require.NoError(t, err)
+	str, err = ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, string(testChain), str)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"token#pem=CERTIFICATE", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `failed to select PEM block from credential "token"`)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
@@ -0,0 +1,74 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// PermissionCheck controls what happens when a credential file is accessible to other users.
+type PermissionCheck string
+
+const (
+	// PermissionCheckOff reads credentials regardless of their ownership and mode.
+	PermissionCheckOff PermissionCheck = "off"
+	// PermissionCheckWarn logs a warning for insecure credential files, but reads them.
+	PermissionCheckWarn PermissionCheck = "warn"
+	// PermissionCheckEnforce fails to read insecure credential files.
+	PermissionCheckEnforce PermissionCheck = "enforce"
+)
+
+// insecureModeBits are the permission bits that give group members or other users access to a credential.
+const insecureModeBits fs.FileMode = 0066
+
+// readCredentialFile reads the credential file called name from fsys, see readAll. The ownership
+// and mode of the opened file are checked according to the configured PermissionCheck.
+func (p *provider) readCredentialFile(fsys fs.FS, name, credPath string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if p.cfg.permissionCheck != PermissionCheckOff {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.checkPermissions(name, credPath, info); err != nil {
+			return nil, err
+		}
+	}
+	return readAll(f, p.cfg.maxSize)
+}
+
+// checkPermissions checks that the credential called name, read from credPath, is owned by root
+// or the user the process runs as, and that neither group members nor other users can read or write it.
+func (p *provider) checkPermissions(name, credPath string, info fs.FileInfo) error {
+	err := insecurePermissions(info)
+	if err == nil {
+		return nil
+	}
+	if p.cfg.permissionCheck == PermissionCheckEnforce {
+		return withKind(ErrInsecurePermissions, err)
+	}
+	p.cfg.logger.Warn("Credential file has insecure permissions",
+		zap.String("credential", name), zap.String("path", credPath), zap.Error(err))
+	return nil
+}
+
+// insecurePermissions returns an error describing why a credential file with info is insecure,
+// or nil if it isn't. The owner is only checked when the platform reports it.
+func insecurePermissions(info fs.FileInfo) error {
+	if perm := info.Mode().Perm(); perm&insecureModeBits != 0 {
+		return fmt.Errorf("mode %#o allows group members or other users to access it", perm)
+	}
+	if uid, ok := fileOwner(info); ok && uid != 0 && uid != os.Geteuid() {
+		return fmt.Errorf("owned by uid %d instead of root or uid %d", uid, os.Geteuid())
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestPermissionCheck(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "private"), []byte(testCredValue), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "public"), []byte(testCredValue), 0600))
+	// Set the mode explicitly, os.WriteFile is subject to the umask.
+	require.NoError(t, os.Chmod(filepath.Join(credDir, "public"), 0644))
+
+	for _, check := range []PermissionCheck{PermissionCheckOff, PermissionCheckWarn, PermissionCheckEnforce} {
+		t.Run(string(check), func(t *testing.T) {
+			core, logs := observer.New(zap.WarnLevel)
+			prov := NewFactory(
+				WithCredentialsDirectory(credDir),
+				WithPermissionCheck(check),
+				WithLogger(zap.New(core)),
+			).Create(confmaptest.NewNopProviderSettings())
+
+			_, err := prov.Retrieve(context.Background(), credSchemePrefix+"private", nil)
+			require.NoError(t, err)
+
+			ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"public", nil)
+			if check == PermissionCheckEnforce {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, ErrInsecurePermissions)
+				assert.ErrorContains(t, err, "mode 0644")
+			} else {
+				require.NoError(t, err)
+				str, err := ret.AsString()
+				require.NoError(t, err)
+				assert.Equal(t, testCredValue, str)
+			}
+			if check == PermissionCheckWarn {
+				require.Equal(t, 1, logs.Len())
+				assert.Equal(t, "Credential file has insecure permissions", logs.All()[0].Message)
+			} else {
+				assert.Zero(t, logs.Len())
+			}
+			assert.NoError(t, prov.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestInsecurePermissions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"owner_only":     {Mode: 0400},
+		"group_readable": {Mode: 0440},
+		"world_writable": {Mode: 0602},
+	}
+	tests := []struct {
+		name    string
+		wantErr string
+	}{
+		{name: "owner_only"},
+		{name: "group_readable", wantErr: "mode 0440 allows group members or other users to access it"},
+		{name: "world_writable", wantErr: "mode 0602 allows group members or other users to access it"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := fsys.Stat(tt.name)
+			require.NoError(t, err)
+			err = insecurePermissions(info)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"slices"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// snapshotFS is an in-memory copy of a credentials directory, see WithPreload. Its entries
+// don't change after it is taken, but their contents are wiped on Shutdown, which may race
+// with retrievals that are still running.
+type snapshotFS struct {
+	// dir is the directory the snapshot was taken of, empty for file systems set with WithFS.
+	dir     string
+	info    fs.FileInfo
+	entries map[string]snapshotEntry
+
+	// mu guards the contents of the entries against being wiped while they are read.
+	mu    sync.RWMutex
+	wiped bool
+}
+
+// snapshotEntry is a credential in a snapshotFS. Credentials that failed to be read keep the
+// error, so that retrieving them keeps failing the same way.
+type snapshotEntry struct {
+	val  []byte
+	info fs.FileInfo
+	err  error
+}
+
+// preload replaces the credentials directory with a snapshot of it. When taking the snapshot
+// fails, credentials are read from the directory on demand.
+func (p *provider) preload() {
+	if p.cfg.listenFDs || p.cfg.fileRoots != nil {
+		return
+	}
+	fsys, dir, exists := p.credentialsFS()
+	if !exists {
+		return
+	}
+	snapshot, err := p.takeSnapshot(fsys, dir)
+	if err != nil {
+		p.cfg.logger.Warn("Failed to preload credentials, reading them on demand", zap.String("directory", dir), zap.Error(err))
+		return
+	}
+	p.cfg.logger.Debug("Preloaded credentials", zap.String("directory", dir), zap.Int("credentials", len(snapshot.entries)))
+	p.snapshot = snapshot
+}
+
+// takeSnapshot reads all credentials at the root of fsys, rooted at dir, into memory.
+func (p *provider) takeSnapshot(fsys fs.FS, dir string) (*snapshotFS, error) {
+	info, err := fs.Stat(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	dirEntries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &snapshotFS{dir: dir, info: info, entries: map[string]snapshotEntry{}}
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || !validCredentialName(name) {
+			continue
+		}
+		val, info, err := p.preloadCredential(fsys, name)
+		if err != nil {
+			p.cfg.logger.Warn("Failed to preload credential", zap.String("credential", name), zap.Error(err))
+			snapshot.entries[name] = snapshotEntry{err: err}
+			continue
+		}
+		if info.IsDir() {
+			// A symlink to a directory.
+			continue
+		}
+		snapshot.entries[name] = snapshotEntry{val: val, info: info}
+	}
+	return snapshot, nil
+}
+
+// preloadCredential reads the credential called name from fsys, unless it is a directory.
+func (p *provider) preloadCredential(fsys fs.FS, name string) ([]byte, fs.FileInfo, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return nil, info, err
+	}
+	val, err := readAll(f, p.cfg.maxSize)
+	return val, info, err
+}
+
+// wipe overwrites the contents of all preloaded credentials with zeroes. Reading a credential
+// from the snapshot fails afterwards.
+func (s *snapshotFS) wipe() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entry := range s.entries {
+		clear(entry.val)
+	}
+	s.wiped = true
+}
+
+func (s *snapshotFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		names := slices.Sorted(func(yield func(string) bool) {
+			for name, entry := range s.entries {
+				if entry.err == nil && !yield(name) {
+					return
+				}
+			}
+		})
+		dirEntries := make([]fs.DirEntry, len(names))
+		for i, name := range names {
+			dirEntries[i] = fs.FileInfoToDirEntry(snapshotInfo{FileInfo: s.entries[name].info, name: name})
+		}
+		return &snapshotDir{info: s.info, entries: dirEntries}, nil
+	}
+	entry, ok := s.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	return &snapshotFile{snapshot: s, val: entry.val, info: entry.info}, nil
+}
+
+// snapshotInfo reports a preloaded credential under the name it was listed with, which may be
+// a symlink to a file with another name.
+type snapshotInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (i snapshotInfo) Name() string { return i.name }
+
+// snapshotFile is an opened credential of a snapshotFS.
+type snapshotFile struct {
+	snapshot *snapshotFS
+	val      []byte
+	offset   int
+	info     fs.FileInfo
+}
+
+func (f *snapshotFile) Read(b []byte) (int, error) {
+	f.snapshot.mu.RLock()
+	defer f.snapshot.mu.RUnlock()
+	if f.snapshot.wiped {
+		return 0, &fs.PathError{Op: "read", Path: f.info.Name(), Err: fs.ErrClosed}
+	}
+	if f.offset >= len(f.val) {
+		return 0, io.EOF
+	}
+	n := copy(b, f.val[f.offset:])
+	f.offset += n
+	return n, nil
+}
+
+func (f *snapshotFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (*snapshotFile) Close() error { return nil }
+
+// snapshotDir is the opened root directory of a snapshotFS.
+type snapshotDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+}
+
+func (d *snapshotDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *snapshotDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".", Err: errors.New("is a directory")}
+}
+
+func (*snapshotDir) Close() error { return nil }
+
+func (d *snapshotDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
@@ -0,0 +1,87 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestPreload(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue+"\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "large"), make([]byte, 64), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(credDir, "subdir"), 0700))
+	require.NoError(t, os.Symlink("api_token", filepath.Join(credDir, "alias")))
+
+	prov := NewFactory(
+		WithCredentialsDirectory(credDir),
+		WithPreload(true),
+		WithMaxSize(32),
+		WithCaseInsensitiveFallback(true),
+	).Create(confmaptest.NewNopProviderSettings()).(*provider)
+	require.NotNil(t, prov.snapshot)
+
+	// Retrievals are served from the snapshot, even after the directory is gone.
+	require.NoError(t, os.RemoveAll(credDir))
+	for _, name := range []string{"api_token", "alias", "API_TOKEN"} {
+		ret, err := prov.Retrieve(context.Background(), credSchemePrefix+name, nil)
+		require.NoError(t, err, name)
+		str, err := ret.AsString()
+		require.NoError(t, err)
+		assert.Equal(t, testCredValue, str)
+	}
+
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"large", nil)
+	assert.ErrorIs(t, err, ErrTooLarge)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"subdir", nil)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api-token", nil)
+	assert.ErrorContains(t, err, `did you mean "api_token"?`)
+
+	val := prov.snapshot.entries["api_token"].val
+	assert.NoError(t, prov.Shutdown(context.Background()))
+	assert.Equal(t, make([]byte, len(val)), val)
+}
+
+func TestPreloadWithoutDirectory(t *testing.T) {
+	t.Setenv(credentialsDirectoryEnv, filepath.Join(t.TempDir(), "missing"))
+	prov := NewFactory(WithPreload(true)).Create(confmaptest.NewNopProviderSettings()).(*provider)
+	assert.Nil(t, prov.snapshot)
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestSnapshotFSReadDir(t *testing.T) {
+	credDir := t.TempDir()
+	for _, name := range []string{"c", "a", "b"} {
+		require.NoError(t, os.WriteFile(filepath.Join(credDir, name), []byte(name), 0600))
+	}
+	prov := NewFactory(WithCredentialsDirectory(credDir)).Create(confmaptest.NewNopProviderSettings()).(*provider)
+	snapshot, err := prov.takeSnapshot(os.DirFS(credDir), credDir)
+	require.NoError(t, err)
+
+	entries, err := fs.ReadDir(snapshot, ".")
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+
+	val, err := fs.ReadFile(snapshot, "b")
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(val))
+	_, err = snapshot.Open("../a")
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
@@ -4,26 +4,52 @@
 package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
 )
 
 const (
 	schemeName = "systemdcredential"
-)
 
-var (
-	// credNameValidation matches valid credential names (alphanumeric, underscore, dash)
-	credNameValidation = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+	// credentialsDirectoryEnv is the environment variable systemd sets to the credentials directory.
+	credentialsDirectoryEnv = "CREDENTIALS_DIRECTORY"
+	// systemCredentialsDirectory is where systemd exposes system-wide credentials.
+	systemCredentialsDirectory = "/run/credentials/@system"
 )
 
 type provider struct {
+	cfg     config
+	metrics *providerMetrics
+	tracer  trace.Tracer
+	// buffers holds the credential contents of the values that haven't been closed yet.
+	buffers bufferRegistry
+	// cache is nil unless enabled with WithCache.
+	cache *credentialCache
+	// snapshot is nil unless enabled with WithPreload.
+	snapshot *snapshotFS
+	// now returns the current time, overridden in tests.
+	now func() time.Time
+	// devModeWarning makes sure the development shim is only warned about once, see warnDevMode.
+	devModeWarning sync.Once
+	// watchCtx is canceled on Shutdown to stop the watchers started by Retrieve, see watch.
+	watchCtx     context.Context
+	stopWatching context.CancelFunc
+	watchers     sync.WaitGroup
 }
 
 // NewFactory returns a factory for a confmap.Provider that reads the configuration from systemd credentials.
@@ -31,45 +57,710 @@ type provider struct {
 // This Provider supports "systemdcredential" scheme, and can be called with a selector:
 // `systemdcredential:CREDENTIAL_NAME`
 //
-// The credential is read from $CREDENTIALS_DIRECTORY/CREDENTIAL_NAME
+// The credential name may be percent-encoded, e.g. `systemdcredential:my%2Eapp%2Etoken`.
+//
+// Multiple credentials can be joined into a single value, separated by newlines, e.g.
+// `systemdcredential:cert+intermediate+root`. The options below apply to each of them;
+// a literal '+' in a credential name has to be percent-encoded as `%2B`.
+//
+// Options can be attached to a single reference as URI query parameters:
+// `systemdcredential:CREDENTIAL_NAME?key=value`
+//
+// Supported options:
+//   - `default=VALUE`: returned when the credential or the credentials directory is missing.
+//     `systemdcredential:CREDENTIAL_NAME:-VALUE` is a shorthand for this option, in which
+//     VALUE cannot contain '?' or '#'.
+//   - `optional=true`: a missing credential resolves to null (or an empty string when used
+//     inline) instead of failing.
+//   - `require=nonempty`: fail when the credential is empty or only contains whitespace.
+//   - `decode=base64` or `decode=hex`: decode the credential, ignoring whitespace, and return the
+//     decoded value as is.
+//   - `raw=true`: return the untouched (or decoded) bytes, base64-encoded, so that binary
+//     credentials survive resolution.
+//   - `trim=none|trailing-newline|all-whitespace`: override the trim mode set with WithTrimMode.
+//     Decoded and raw credentials are never trimmed.
+//   - `normalize=true|false`: override the normalization setting set with WithNormalization.
+//     Raw credentials are never normalized.
+//   - `strict=true|false`: override the strict content setting set with WithStrictContent, which
+//     rejects credentials containing control characters, NUL bytes or invalid UTF-8.
+//   - `encrypted=true`: decrypt the credential before using it, for credentials encrypted with
+//     `systemd-creds encrypt` that are read outside of their unit. Credentials encrypted with the
+//     host key are decrypted natively, others through io.systemd.Credentials (see WithVarlinkSocket)
+//     or with `systemd-creds decrypt`.
+//   - `age_identity=NAME`: decrypt the credential, age-encrypted in binary or armored form, with
+//     the identities in the credential called NAME, using the age binary, see WithAgeCommand.
+//   - `sops=true`: decrypt the credential, a SOPS-encrypted YAML or JSON document, using the sops
+//     binary (see WithSopsCommand) and the keys it finds on its own, or the age identities in the
+//     credential named by `age_identity`. `sops_key=PATH` selects a single value, with map keys and
+//     list indices separated by '.', e.g. `systemdcredential:secrets?sops=true&sops_key=db.password`.
+//     Combine with `format=map` to resolve a whole document.
+//   - `expand=true`: resolve `${env:NAME}` and `${systemdcredential:...}` references in the
+//     credential, up to 8 levels deep. `$$` is an escaped `$`.
+//   - `parse=yaml`: parse the credential, or the default value, as a YAML scalar so that numbers,
+//     booleans and durations keep their type, e.g. `systemdcredential:port?parse=yaml`.
+//     `parse=string`, the default, returns the credential as a string.
+//   - `format=map`: parse the credential, or the default value, as a JSON or YAML mapping that is
+//     used as the value of the surrounding configuration node, e.g. `otlp: ${systemdcredential:otlp?format=map}`.
+//   - `dropins=true`: also read the drop-ins of the credential, the credentials named
+//     `CREDENTIAL_NAME.d-SUFFIX`, e.g. `otlp.d-00-base` and `otlp.d-10-override`, in lexical order
+//     after the credential itself, which may then be missing. The contents are joined with
+//     newlines, or deep-merged with `format=map`. Drop-ins are only found in credentials directories.
+//   - `BUNDLE/MEMBER`: read MEMBER from the bundle credential BUNDLE, a tar archive or a JSON or
+//     YAML mapping, so that services that can only be given a single credential can still
+//     reference its parts, e.g. `systemdcredential:bundle/api_token`. Members of mappings are
+//     addressed by key, with '/' selecting keys of nested mappings. The options apply to the member,
+//     after the bundle is decrypted.
+//   - `exists=true`: resolve to true or false depending on whether the credential exists, without
+//     reading it, e.g. to enable an optional component: `enabled: ${systemdcredential:api_token?exists=true}`.
+//     It can't be combined with other options.
+//
+// The credential is read from $CREDENTIALS_DIRECTORY/CREDENTIAL_NAME, or from the directory
+// set with WithCredentialsDirectory or WithCredentialsDirectoryEnv, or from the file system set with WithFS.
+// When none of these are set, WithUnitDirectoryDiscovery uses the credentials directory of the
+// systemd service the process runs as part of, and WithContainerDetection the directory the
+// container manager places credentials in, when it runs in a container.
+// For development on machines without systemd, WithDevMode reads credentials from the working directory.
+// The behavior of the provider can be tuned with Option values.
+// On Linux, credentials in a directory are opened relative to it with openat2 and RESOLVE_BENEATH,
+// so that neither the name nor a symlink can make them resolve outside of the directory, see
+// WithSymlinkPolicy.
+//
+// A single PEM block can be selected from a credential holding several, e.g. a certificate
+// bundled with its private key and chain, with a fragment: `systemdcredential:tls_bundle#pem=CERTIFICATE`
+// selects the first certificate, `#pem=CERTIFICATE&index=1` the second one and `#index=2` the
+// third block of any type. The selected block is returned PEM-encoded. Options go before the '#'.
+//
+// The resolver expands `${...}` in the values returned by providers and turns `$$` into `$`.
+// WithDollarSignEscaping makes credentials resolve byte-for-byte instead. Use `expand=true` to
+// resolve references in credentials.
+//
+// When the resolver watches the configuration, the credentials a URI was resolved from are checked
+// for changes, and the resolver is notified once they change, see WithWatchInterval.
+//
+// A fallback chain of sources separated by '|' resolves to the first source that exists, e.g.
+// `systemdcredential:TOKEN|env:TOKEN|file:/etc/otel/token`. Besides `systemdcredential:`,
+// the `env:` scheme is supported in a chain, and the `file:` scheme when enabled with
+// WithFileFallback. A literal '|' in a credential name has to be percent-encoded as `%7C`.
 //
 // See also: https://systemd.io/CREDENTIALS/
-func NewFactory() confmap.ProviderFactory {
-	return confmap.NewProviderFactory(newProvider)
+func NewFactory(opts ...Option) confmap.ProviderFactory {
+	cfg := newConfig(opts)
+	return confmap.NewProviderFactory(func(ps confmap.ProviderSettings) confmap.Provider {
+		return newProvider(ps, cfg)
+	})
+}
+
+func newProvider(ps confmap.ProviderSettings, cfg config) confmap.Provider {
+	if cfg.logger == nil {
+		cfg.logger = ps.Logger
+	}
+	if cfg.logger == nil {
+		cfg.logger = zap.NewNop()
+	}
+	p := &provider{cfg: cfg, tracer: noop.NewTracerProvider().Tracer(tracerName), now: time.Now}
+	p.watchCtx, p.stopWatching = context.WithCancel(context.Background())
+	if cfg.tracerProvider != nil {
+		p.tracer = cfg.tracerProvider.Tracer(tracerName)
+	}
+	if cfg.cacheTTL > 0 {
+		p.cache = newCredentialCache(cfg.cacheTTL)
+	}
+	if cfg.preload {
+		p.preload()
+	}
+	if cfg.meterProvider != nil {
+		var err error
+		if p.metrics, err = newProviderMetrics(cfg.meterProvider, cfg.scheme, p.modTime); err != nil {
+			cfg.logger.Warn("Failed to create metrics, metrics are disabled", zap.Error(err))
+			p.metrics = nil
+		}
+	}
+	return p
+}
+
+func (p *provider) Retrieve(ctx context.Context, uri string, watcher confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	start := time.Now()
+	ctx, span := p.startSpan(ctx)
+	var names []string
+	if span.IsRecording() || p.cfg.retrieveHook != nil {
+		// The names are only parsed once, and only when something reports them.
+		names = p.uriNames(uri)
+		setSpanNames(span, names)
+	}
+	var sources *sourceRecorder
+	if p.cfg.retrieveHook != nil && p.cfg.sourceMetadata {
+		sources = &sourceRecorder{}
+		ctx = context.WithValue(ctx, sourcesKey{}, sources)
+	}
+	var watched *watchRecorder
+	if watcher != nil && p.cfg.watchInterval > 0 && !p.cfg.listenFDs && p.snapshot == nil {
+		// File descriptors and preloaded credentials can't change.
+		watched = &watchRecorder{credentials: map[string][sha256.Size]byte{}, files: map[string][sha256.Size]byte{}}
+		ctx = context.WithValue(ctx, watchKey{}, watched)
+	}
+	ret, err := p.retrieve(ctx, uri, nil)
+	if err == nil && watched != nil {
+		p.watch(watched, watcher)
+	}
+	endSpan(span, err)
+	if p.cfg.retrieveHook != nil || p.metrics != nil {
+		event := RetrieveEvent{URI: uri, Names: names, Duration: time.Since(start), Err: err}
+		if sources != nil {
+			event.Sources = sources.sources
+		}
+		if err == nil {
+			if val, strErr := ret.AsString(); strErr == nil {
+				event.Bytes = len(val)
+			}
+		}
+		if p.metrics != nil {
+			p.metrics.record(ctx, p.cfg.scheme, event)
+		}
+		if p.cfg.retrieveHook != nil {
+			p.cfg.retrieveHook(event)
+		}
+	}
+	if err != nil && p.cfg.statusNotification {
+		p.notifyStatus(uri, err)
+	}
+	if err != nil && p.cfg.validator != nil {
+		p.cfg.validator.record(uri, err)
+		return confmap.NewRetrievedFromYAML(nil)
+	}
+	if err == nil && p.cfg.redactor != nil {
+		if ret, err = p.cfg.redactor.track(uri, ret); err != nil {
+			return nil, err
+		}
+	}
+	if err == nil && p.cfg.escapeDollarSigns {
+		return escapeDollarSigns(ret)
+	}
+	return ret, err
+}
+
+// retrieve retrieves uri, which may be a fallback chain.
+// stack holds the URIs whose contents are being expanded, see expand.
+func (p *provider) retrieve(ctx context.Context, uri string, stack []string) (*confmap.Retrieved, error) {
+	if strings.Contains(uri, fallbackSeparator) {
+		return p.retrieveFallback(ctx, uri, stack)
+	}
+	return p.retrieveCredential(ctx, uri, stack)
+}
+
+// retrieveCredential retrieves a single `systemdcredential:` (or `containersecret:`) uri.
+func (p *provider) retrieveCredential(ctx context.Context, uri string, stack []string) (*confmap.Retrieved, error) {
+	ref, err := parseURI(uri, p.cfg.scheme, p.cfg.envFile)
+	if err != nil {
+		return nil, err
+	}
+	if ref.opts.exists {
+		return p.retrieveExists(uri, ref)
+	}
+	if ref.opts.expand && ref.opts.raw {
+		return nil, fmt.Errorf("uri %q must not combine expand and raw", uri)
+	}
+	if ref.opts.sopsKey != "" && !ref.opts.sops {
+		return nil, fmt.Errorf("uri %q must not set sops_key without sops=true", uri)
+	}
+	if ref.opts.parseYAML && ref.opts.raw {
+		return nil, fmt.Errorf("uri %q must not combine parse=yaml and raw", uri)
+	}
+	if ref.opts.formatMap && (ref.opts.raw || ref.opts.parseYAML) {
+		return nil, fmt.Errorf("uri %q must not combine format=map with raw or parse=yaml", uri)
+	}
+	credNames := make([]string, len(ref.names))
+	// members holds the bundle members read from the credentials, nil unless there are any.
+	var members []string
+	for i, name := range ref.names {
+		bundle, member, err := splitBundleMember(name)
+		if err != nil {
+			return nil, err
+		}
+		if member != "" {
+			if members == nil {
+				members = make([]string, len(ref.names))
+			}
+			members[i] = member
+		}
+		if credNames[i], err = p.credentialName(bundle); err != nil {
+			return nil, err
+		}
+	}
+	if members != nil && ref.opts.dropIns {
+		return nil, fmt.Errorf("uri %q must not combine dropins with bundle members", uri)
+	}
+	// overridden holds the credentials that may be missing because drop-ins exist for them.
+	var overridden map[string]bool
+	if ref.opts.dropIns {
+		if credNames, overridden, err = p.withDropIns(credNames); err != nil {
+			return nil, err
+		}
+	}
+
+	// The buffers holding credential contents are wiped when the retrieved value is closed,
+	// or right away when the retrieval fails.
+	bufs := &credentialBuffers{}
+	tracked := false
+	defer func() {
+		if !tracked {
+			bufs.wipe()
+		}
+	}()
+	vals := make([][]byte, 0, len(credNames))
+	for i, credName := range credNames {
+		val, credPath, err := p.readCredentialObserved(ctx, uri, credName)
+		if err != nil {
+			if isMissing(err) && overridden[credName] {
+				continue
+			}
+			if isMissing(err) {
+				return missingCredential(ref, withKind(ErrNotFound, p.explainMissing(ctx, credName, err)))
+			}
+			return nil, err
+		}
+		bufs.add(val)
+		p.recordSource(ctx, credName, credPath)
+		if p.metrics != nil {
+			p.metrics.trackStaleness(credName, credPath)
+		}
+		readSidecar := func(ctx context.Context, name string) ([]byte, string, error) {
+			return p.readCredentialObserved(ctx, uri, name)
+		}
+		if err := p.verifySidecars(ctx, uri, credName, val, readSidecar); err != nil {
+			return nil, err
+		}
+		if ref.opts.encrypted {
+			if val, err = p.decrypt(ctx, credName, val); err != nil {
+				return nil, fmt.Errorf("failed to decrypt credential %q read from %q: %w", credName, credPath, err)
+			}
+			bufs.add(val)
+		}
+		switch {
+		case ref.opts.sops:
+			if val, err = p.decryptSops(ctx, uri, credName, &ref.opts, val, bufs); err != nil {
+				return nil, err
+			}
+		case ref.opts.ageIdentity != "":
+			if val, err = p.decryptAge(ctx, uri, credName, ref.opts.ageIdentity, val, bufs); err != nil {
+				return nil, err
+			}
+		}
+		if members != nil && members[i] != "" {
+			if val, err = bundleMember(val, members[i]); err != nil {
+				err = fmt.Errorf("failed to read member of bundle credential %q read from %q: %w", credName, credPath, err)
+				if isMissing(err) {
+					return missingCredential(ref, withKind(ErrNotFound, err))
+				}
+				return nil, err
+			}
+			bufs.add(val)
+		}
+		if ref.pem != nil {
+			if val, err = selectPEMBlock(val, ref.pem); err != nil {
+				return nil, fmt.Errorf("failed to select PEM block from credential %q read from %q: %w", credName, credPath, err)
+			}
+			bufs.add(val)
+		}
+		if ref.key != "" {
+			if val, err = envFileValue(val, ref.key); err != nil {
+				return missingCredential(ref, withKind(ErrNotFound, fmt.Errorf("failed to read env file %q from %q: %w", credName, credPath, err)))
+			}
+			bufs.add(val)
+		}
+		if val, err = p.transform(ref, credName, credPath, val, bufs); err != nil {
+			return nil, err
+		}
+		if p.cfg.certExpiryWindow > 0 {
+			p.checkCertificateExpiry(ctx, credName, val)
+		}
+		vals = append(vals, val)
+	}
+	// content returns the string the credential value val resolves to.
+	content := func(val []byte) (string, error) {
+		strict := p.cfg.strict
+		if ref.opts.strict != nil {
+			strict = *ref.opts.strict
+		}
+		if strict && !ref.opts.raw {
+			if err := checkContent(val); err != nil {
+				return "", withKind(ErrInvalidContent, fmt.Errorf("credential of uri %q %w", uri, err))
+			}
+		}
+		switch {
+		case ref.opts.raw:
+			// The retrieved value can only hold strings, so binary content is returned base64-encoded
+			return base64.StdEncoding.EncodeToString(val), nil
+		case ref.opts.expand:
+			str, err := p.expand(ctx, string(val), append(stack, uri))
+			if err != nil {
+				return "", fmt.Errorf("failed to expand references in %q: %w", uri, err)
+			}
+			return str, nil
+		}
+		return string(val), nil
+	}
+	if ref.opts.dropIns && ref.opts.formatMap && len(vals) > 1 {
+		ret, err := mergeDropIns(vals, content, p.retrievedClose(bufs, credNames))
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge drop-ins of uri %q: %w", uri, err)
+		}
+		tracked = true
+		return ret, nil
+	}
+
+	// Only join when needed, every copy leaves the credential behind on the heap.
+	val := vals[0]
+	if len(vals) > 1 {
+		val = bufs.add(bytes.Join(vals, []byte("\n")))
+	}
+	str, err := content(val)
+	if err != nil {
+		return nil, err
+	}
+	if ref.opts.parseYAML || ref.opts.formatMap {
+		parse := retrievedScalar
+		if ref.opts.formatMap {
+			parse = retrievedMap
+		}
+		ret, err := parse(str, p.retrievedClose(bufs, credNames))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse credential of uri %q as YAML: %w", uri, err)
+		}
+		tracked = true
+		return ret, nil
+	}
+	tracked = true
+	return confmap.NewRetrieved(str, p.retrievedClose(bufs, credNames))
+}
+
+// readCredentialObserved reads the credential called name, for uri, through the cache. Like every
+// credential read, including the ones of sidecars and keys, it is watched for changes when the
+// Retrieve call watches, and recorded in the access and audit logs and the trace.
+func (p *provider) readCredentialObserved(ctx context.Context, uri, name string) ([]byte, string, error) {
+	val, credPath, err := p.readCredentialCached(ctx, name)
+	recordWatched(ctx, name, credPath, val, err)
+	if p.cfg.accessLog != nil {
+		p.cfg.accessLog.record(name, credPath, err)
+	}
+	if p.cfg.auditLog {
+		p.audit(name, uri, credPath, err)
+	}
+	traceRead(ctx, name, credPath, err)
+	p.cfg.logger.Debug("Read credential",
+		zap.String("credential", name), zap.String("path", credPath), zap.Bool("found", err == nil))
+	return val, credPath, err
 }
 
-func newProvider(ps confmap.ProviderSettings) confmap.Provider {
-	return &provider{}
+// sidecarReader reads the sidecar called name of a credential, such as its checksum or signature,
+// returning its content and where it was read from.
+type sidecarReader func(ctx context.Context, name string) ([]byte, string, error)
+
+// verifySidecars verifies val, the content of the credential called name retrieved for uri, against
+// the checksum and signature sidecars read with read, as far as WithChecksumVerification and
+// WithSignatureVerification require.
+func (p *provider) verifySidecars(ctx context.Context, uri, name string, val []byte, read sidecarReader) error {
+	if p.cfg.checksumPolicy != ChecksumOff {
+		if err := p.verifyChecksum(ctx, name, val, read); err != nil {
+			return err
+		}
+	}
+	if len(p.cfg.signatureKeys) > 0 || p.cfg.signatureKeyCredential != "" {
+		if err := p.verifySignature(ctx, uri, name, val, read); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// credentialName returns the name of the credential to read for name as written in the
+// configuration, resolving aliases and checking that it may be read.
+func (p *provider) credentialName(name string) (string, error) {
+	if alias, ok := p.cfg.aliases[name]; ok {
+		name = alias
+	} else {
+		transform, ok := nameCaseFuncs[p.cfg.nameCase]
+		if !ok {
+			return "", fmt.Errorf("unsupported name case %q", p.cfg.nameCase)
+		}
+		name = transform(name)
+	}
+	if !validCredentialName(name) {
+		p.cfg.logger.Debug("Rejected invalid credential name", zap.String("credential", name))
+		return "", withKind(ErrInvalidName, fmt.Errorf("credential name %q has invalid name: %s", name, credentialNameRules))
+	}
+	if p.cfg.projectedVolume && reservedProjectedName(name) {
+		p.cfg.logger.Debug("Rejected reserved credential name", zap.String("credential", name))
+		return "", withKind(ErrInvalidName, fmt.Errorf("credential name %q is reserved: names starting with %q are used internally by the kubelet", name, projectedReservedPrefix))
+	}
+	if err := p.checkAllowed(name); err != nil {
+		p.cfg.logger.Debug("Rejected credential that is not allowed", zap.String("credential", name))
+		return "", err
+	}
+	return name, nil
+}
+
+// transform applies the content options of ref to the credential read from credPath,
+// adding the buffers it allocates to bufs.
+func (p *provider) transform(ref *reference, credName, credPath string, val []byte, bufs *credentialBuffers) ([]byte, error) {
+	var err error
+	normalizeContent := p.cfg.normalize
+	if ref.opts.normalize != nil {
+		normalizeContent = *ref.opts.normalize
+	}
+	if normalizeContent && !ref.opts.raw {
+		if val, err = normalize(val); err != nil {
+			return nil, fmt.Errorf("failed to normalize credential %q read from %q: %w", credName, credPath, err)
+		}
+		bufs.add(val)
+	}
+	if ref.opts.requireNonEmpty && len(bytes.TrimSpace(val)) == 0 {
+		return nil, fmt.Errorf("credential %q read from %q is empty", credName, credPath)
+	}
+	switch {
+	case ref.opts.decode != "":
+		val, err = decoders[ref.opts.decode](val)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode credential %q read from %q as %s: %w", credName, credPath, ref.opts.decode, err)
+		}
+		bufs.add(val)
+	case !ref.opts.raw:
+		trimMode := p.cfg.trimMode
+		if ref.opts.trim != "" {
+			trimMode = ref.opts.trim
+		}
+		trim, ok := trimFuncs[trimMode]
+		if !ok {
+			return nil, fmt.Errorf("unsupported trim mode %q", trimMode)
+		}
+		val = trim(val)
+	}
+	return val, nil
+}
+
+// readCredential reads the credential called name, returning its content and where it was read from.
+func (p *provider) readCredential(name string) ([]byte, string, error) {
+	return p.lookupCredential(name, lookupRead)
 }
 
-func (p *provider) Retrieve(_ context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
-	if !strings.HasPrefix(uri, schemeName+":") {
-		return nil, fmt.Errorf("%q uri is not supported by %q provider", uri, schemeName)
+// lookupCredential looks up the credential called name in the places it can be read from, in
+// order, returning where it was found. With lookupRead its content is returned as well, with
+// lookupStat it is only checked to exist, see lookupMode.
+func (p *provider) lookupCredential(name string, mode lookupMode) ([]byte, string, error) {
+	var val []byte
+	var source string
+	err := ErrNoCredentialsDirectory
+	if p.cfg.listenFDs && mode == lookupStat {
+		source, err := p.statFDCredential(name)
+		return nil, source, err
+	}
+	if p.cfg.listenFDs {
+		return p.readFDCredential(name)
+	}
+	if path, ok := p.cfg.envFilePaths[name]; ok {
+		val, err := p.lookupOSFile(path, mode)
+		if err != nil {
+			return nil, path, fmt.Errorf("failed to read env file %q from %q: %w", name, path, err)
+		}
+		return val, path, nil
+	}
+	if p.cfg.fileRoots != nil {
+		val, source, err = p.lookupInRoots(name, mode)
+	} else if fsys, credDir, exists := p.credentialsFS(); exists {
+		val, source, err = p.lookupInFS(fsys, credDir, name, mode)
 	}
-	credName := uri[len(schemeName)+1:]
-	if !credNameValidation.MatchString(credName) {
-		return nil, fmt.Errorf("credential name %q has invalid name: must match regex %s", credName, credNameValidation.String())
+	if isMissing(err) && p.cfg.systemFallback {
+		p.cfg.logger.Debug("Looking up system credential", zap.String("credential", name))
+		sysVal, sysSource, sysErr := p.lookupInFS(credentialsDirFS(p.cfg.systemCredentialsDirectory, p.cfg.symlinkPolicy), p.cfg.systemCredentialsDirectory, name, mode)
+		if !isMissing(sysErr) {
+			if sysErr == nil {
+				p.cfg.logger.Info("Credential not found, falling back to system credential",
+					zap.String("credential", name), zap.String("path", sysSource))
+			}
+			return sysVal, sysSource, sysErr
+		}
 	}
+	if isMissing(err) && p.cfg.firmwareFallback {
+		p.cfg.logger.Debug("Looking up firmware credential", zap.String("credential", name))
+		fwVal, fwSource, fwErr := p.readFirmwareCredential(name)
+		if mode == lookupStat {
+			// Credentials in SMBIOS OEM strings can only be found by reading them.
+			clear(fwVal)
+			fwVal = nil
+		}
+		if !isMissing(fwErr) {
+			if fwErr == nil {
+				p.cfg.logger.Info("Credential not found, falling back to firmware credential",
+					zap.String("credential", name), zap.String("path", fwSource))
+			}
+			return fwVal, fwSource, fwErr
+		}
+	}
+	if isMissing(err) && p.cfg.envFallback {
+		envName := p.cfg.envFallbackPrefix + name
+		p.cfg.logger.Debug("Looking up environment variable", zap.String("credential", name), zap.String("variable", envName))
+		if envVal, ok := os.LookupEnv(envName); ok {
+			p.cfg.logger.Info("Credential not found, falling back to environment variable",
+				zap.String("credential", name), zap.String("variable", envName))
+			if mode == lookupStat {
+				return nil, "$" + envName, nil
+			}
+			return []byte(envVal), "$" + envName, nil
+		}
+	}
+	return val, source, err
+}
 
-	credDir, exists := os.LookupEnv("CREDENTIALS_DIRECTORY")
+// credentialsFS returns the file system credentials are read from, and the directory it is
+// rooted at. The directory is empty for file systems set with WithFS.
+func (p *provider) credentialsFS() (fs.FS, string, bool) {
+	if p.snapshot != nil {
+		return p.snapshot, p.snapshot.dir, true
+	}
+	if p.cfg.fsys != nil {
+		return p.cfg.fsys, "", true
+	}
+	credDir, exists := p.credentialsDirectory()
+	if !exists && p.cfg.devMode {
+		return p.devCredentialsFS()
+	}
 	if !exists {
-		return nil, fmt.Errorf("CREDENTIALS_DIRECTORY environment variable is not set")
+		return nil, "", false
+	}
+	if p.cfg.projectedVolume {
+		credDir = resolveProjectedVolume(credDir)
 	}
+	return credentialsDirFS(credDir, p.cfg.symlinkPolicy), credDir, true
+}
+
+// credentialsDirectory returns the directory credentials are read from.
+func (p *provider) credentialsDirectory() (string, bool) {
+	if p.cfg.credentialsDirectory != "" {
+		p.cfg.logger.Debug("Using configured credentials directory", zap.String("directory", p.cfg.credentialsDirectory))
+		return p.cfg.credentialsDirectory, true
+	}
+	for _, name := range p.cfg.credentialsDirectoryEnv {
+		if credDir, exists := os.LookupEnv(name); exists {
+			p.cfg.logger.Debug("Using credentials directory from environment variable",
+				zap.String("variable", name), zap.String("directory", credDir))
+			return credDir, true
+		}
+	}
+	if p.cfg.discoverUnitDirectory {
+		if credDir, exists := p.discoverUnitCredentialsDirectory(); exists {
+			p.cfg.logger.Info("CREDENTIALS_DIRECTORY is not set, using the credentials directory of the owning unit",
+				zap.String("directory", credDir))
+			return credDir, true
+		}
+	}
+	if p.cfg.detectContainer {
+		if credDir, manager, exists := p.discoverContainerCredentialsDirectory(); exists {
+			p.cfg.logger.Info("CREDENTIALS_DIRECTORY is not set, using the credentials directory of the container",
+				zap.String("directory", credDir), zap.String("container", manager))
+			return credDir, true
+		}
+	}
+	p.cfg.logger.Debug("No credentials directory found", zap.Strings("variables", p.cfg.credentialsDirectoryEnv))
+	return "", false
+}
+
+// lookupInFS looks up the credential called name in fsys, rooted at credDir, returning its
+// content and the path it was read from, see lookupCredential.
+func (p *provider) lookupInFS(fsys fs.FS, credDir, name string, mode lookupMode) ([]byte, string, error) {
+	credPath := filepath.Join(credDir, name)
+	val, err := p.lookupFile(fsys, name, credPath, mode)
+	if errors.Is(err, fs.ErrNotExist) && p.cfg.caseInsensitiveFallback {
+		match, matchErr := findCaseInsensitive(fsys, name)
+		if matchErr != nil {
+			return nil, credPath, fmt.Errorf("failed to read credential %q: %w", name, matchErr)
+		}
+		if match != "" {
+			if err := p.checkAllowed(match); err != nil {
+				p.cfg.logger.Debug("Rejected credential that is not allowed", zap.String("credential", match))
+				return nil, credPath, err
+			}
+			p.cfg.logger.Warn("Credential found using case-insensitive fallback",
+				zap.String("credential", name), zap.String("match", match))
+			credPath = filepath.Join(credDir, match)
+			val, err = p.lookupFile(fsys, match, credPath, mode)
+		}
+	}
+	if err != nil {
+		return nil, credPath, fmt.Errorf("failed to read credential %q from %q: %w", name, credPath, err)
+	}
+	return val, credPath, nil
+}
+
+// missingCredential returns the value ref resolves to when its credential does not exist,
+// or err if ref does not allow the credential to be missing.
+func missingCredential(ref *reference, err error) (*confmap.Retrieved, error) {
+	switch {
+	case ref.opts.defaultValue != nil && ref.opts.parseYAML:
+		return retrievedScalar(*ref.opts.defaultValue)
+	case ref.opts.defaultValue != nil && ref.opts.formatMap:
+		return retrievedMap(*ref.opts.defaultValue)
+	case ref.opts.defaultValue != nil:
+		return confmap.NewRetrieved(*ref.opts.defaultValue)
+	case ref.opts.optional:
+		// An empty YAML document resolves to null, or to an empty string when used inline.
+		return confmap.NewRetrievedFromYAML(nil)
+	}
+	return nil, err
+}
 
-	credPath := filepath.Join(credDir, credName)
-	val, err := os.ReadFile(credPath)
+// retrievedScalar returns content parsed as a YAML scalar. Content that isn't valid YAML is
+// returned as a string, like confmap.NewRetrievedFromYAML does; maps and lists are rejected so
+// that a credential can't inject configuration.
+func retrievedScalar(content string, opts ...confmap.RetrievedOption) (*confmap.Retrieved, error) {
+	ret, err := confmap.NewRetrievedFromYAML([]byte(content), opts...)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := ret.AsRaw()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read credential %q from %q: %w", credName, credPath, err)
+		return nil, err
+	}
+	switch raw.(type) {
+	case map[string]any, []any:
+		return nil, errors.New("credential is not a YAML scalar")
 	}
+	return ret, nil
+}
 
-	// Return the credential value as a string, trimming any trailing newline
-	return confmap.NewRetrieved(strings.TrimSuffix(string(val), "\n"))
+// retrievedMap returns content parsed as a JSON or YAML mapping.
+func retrievedMap(content string, opts ...confmap.RetrievedOption) (*confmap.Retrieved, error) {
+	ret, err := confmap.NewRetrievedFromYAML([]byte(content), opts...)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := ret.AsRaw()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := raw.(map[string]any); !ok {
+		return nil, errors.New("credential is not a JSON or YAML mapping")
+	}
+	return ret, nil
 }
 
-func (*provider) Scheme() string {
-	return schemeName
+func (p *provider) Scheme() string {
+	return p.cfg.scheme
 }
 
-func (*provider) Shutdown(context.Context) error {
+func (p *provider) Shutdown(context.Context) error {
+	p.stopWatching()
+	p.watchers.Wait()
+	if p.cache != nil {
+		p.cache.invalidateAll()
+	}
+	if p.snapshot != nil {
+		p.snapshot.wipe()
+	}
+	p.buffers.wipeAll()
+	if p.cfg.listenFDs {
+		wipePassedFDs()
+	}
+	if p.metrics != nil {
+		return p.metrics.shutdown()
+	}
 	return nil
 }
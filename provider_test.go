@@ -5,15 +5,21 @@ package systemdcredentialprovider
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"go.opentelemetry.io/collector/confmap"
 	"go.opentelemetry.io/collector/confmap/confmaptest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 const credSchemePrefix = schemeName + ":"
@@ -84,7 +90,7 @@ func TestMissingCredential(t *testing.T) {
 }
 
 func TestCredentialNameRestriction(t *testing.T) {
-	const credName = "default%config"
+	const credName = "../config"
 	credDir := t.TempDir()
 	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
 
@@ -140,6 +146,676 @@ func TestCredentialWithTrailingNewline(t *testing.T) {
 	assert.NoError(t, prov.Shutdown(context.Background()))
 }
 
+func TestDefaultValue(t *testing.T) {
+	const credName = "api_token"
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+
+	tests := []struct {
+		name     string
+		uri      string
+		expected string
+	}{
+		{name: "shorthand", uri: credSchemePrefix + credName + ":-fallback-value", expected: "fallback-value"},
+		{name: "empty shorthand", uri: credSchemePrefix + credName + ":-", expected: ""},
+		{name: "query", uri: credSchemePrefix + credName + "?default=fallback%3Fvalue", expected: "fallback?value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prov := createProvider()
+			ret, err := prov.Retrieve(context.Background(), tt.uri, nil)
+			require.NoError(t, err)
+			str, err := ret.AsString()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, str)
+			assert.NoError(t, prov.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestDefaultValueIgnoredWhenCredentialExists(t *testing.T) {
+	const credName = "api_token"
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, credName), []byte(testCredValue), 0600))
+
+	prov := createProvider()
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+credName+":-fallback", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestDefaultValueWithoutCredentialsDirectory(t *testing.T) {
+	prov := createProvider()
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"MY_CRED:-fallback", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestOptionalCredential(t *testing.T) {
+	const credName = "optional_cred"
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+
+	prov := createProvider()
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+credName+"?optional=true", nil)
+	require.NoError(t, err)
+	raw, err := ret.AsRaw()
+	require.NoError(t, err)
+	assert.Nil(t, raw)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "", str)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+credName+"?optional=false", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read credential")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestOptionalCredentialReadError(t *testing.T) {
+	const credName = "unreadable_cred"
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	// A directory exists but cannot be read as a credential, which must not be treated as missing.
+	require.NoError(t, os.Mkdir(filepath.Join(credDir, credName), 0700))
+
+	prov := createProvider()
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+credName+"?optional=true", nil)
+	require.Error(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestRequireNonEmpty(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "empty_cred"), []byte(""), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "blank_cred"), []byte(" \t\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue+"\n"), 0600))
+
+	prov := createProvider()
+	for _, credName := range []string{"empty_cred", "blank_cred"} {
+		ret, err := prov.Retrieve(context.Background(), credSchemePrefix+credName+"?require=nonempty", nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is empty")
+		assert.Nil(t, ret)
+	}
+
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token?require=nonempty", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestDecodeBase64(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	// Wrapped like the output of `base64`, decoding to a value with a trailing newline.
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "encoded"), []byte("bXktc2VjcmV0\nLXRva2VuCg==\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "invalid"), []byte("not base64!"), 0600))
+
+	prov := createProvider()
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"encoded?decode=base64", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "my-secret-token\n", str)
+
+	ret, err = prov.Retrieve(context.Background(), credSchemePrefix+"invalid?decode=base64", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `failed to decode credential "invalid"`)
+	assert.Nil(t, ret)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestDecodeHex(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "hmac_key"), []byte("6B65790A\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "invalid"), []byte("abc"), 0600))
+
+	prov := createProvider()
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"hmac_key?decode=hex", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "key\n", str)
+
+	ret, err = prov.Retrieve(context.Background(), credSchemePrefix+"invalid?decode=hex", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `failed to decode credential "invalid"`)
+	assert.Nil(t, ret)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestRawCredential(t *testing.T) {
+	binary := []byte{0x30, 0x82, 0x00, 0xff, '\n'}
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "keytab"), binary, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "hmac_key"), []byte("00ff\n"), 0600))
+
+	prov := createProvider()
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"keytab?raw=true", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(binary), str)
+
+	ret, err = prov.Retrieve(context.Background(), credSchemePrefix+"hmac_key?decode=hex&raw=true", nil)
+	require.NoError(t, err)
+	str, err = ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte{0x00, 0xff}), str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestParseYAML(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "port"), []byte("8080\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "enabled"), []byte("true\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "timeout"), []byte("30s\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "mapping"), []byte("key: value\n"), 0600))
+
+	tests := []struct {
+		uri  string
+		want any
+	}{
+		{uri: credSchemePrefix + "port?parse=yaml", want: 8080},
+		{uri: credSchemePrefix + "port?parse=string", want: "8080"},
+		{uri: credSchemePrefix + "port", want: "8080"},
+		{uri: credSchemePrefix + "enabled?parse=yaml", want: true},
+		{uri: credSchemePrefix + "timeout?parse=yaml", want: "30s"},
+		{uri: credSchemePrefix + "missing:-4317?parse=yaml", want: 4317},
+		{uri: credSchemePrefix + "missing:-4317", want: "4317"},
+	}
+	prov := createProvider()
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			ret, err := prov.Retrieve(context.Background(), tt.uri, nil)
+			require.NoError(t, err)
+			raw, err := ret.AsRaw()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, raw)
+			assert.NoError(t, ret.Close(context.Background()))
+		})
+	}
+
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"mapping?parse=yaml", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a YAML scalar")
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"port?parse=yaml&raw=true", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must not combine parse=yaml and raw")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestFormatMap(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "otlp"), []byte(`{"endpoint": "otlp.example.com:4317", "headers": {"authorization": "Bearer token"}}`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "otlp.yaml"), []byte("endpoint: otlp.example.com:4317\ncompression: none\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "token"), []byte(testCredValue), 0600))
+	conf := map[string]any{
+		"exporters": map[string]any{
+			"otlp":      "${systemdcredential:otlp?format=map}",
+			"otlp/yaml": "${systemdcredential:otlp.yaml?format=map}",
+			"otlp/none": "${systemdcredential:missing?format=map&default=%7B%7D}",
+		},
+	}
+	resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+		URIs: []string{"static:config"},
+		ProviderFactories: []confmap.ProviderFactory{
+			confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider {
+				return staticProvider{conf: conf}
+			}),
+			NewFactory(WithCredentialsDirectory(credDir)),
+		},
+	})
+	require.NoError(t, err)
+	resolved, err := resolver.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"otlp": map[string]any{
+			"endpoint": "otlp.example.com:4317",
+			"headers":  map[string]any{"authorization": "Bearer token"},
+		},
+		"otlp/yaml": map[string]any{
+			"endpoint":    "otlp.example.com:4317",
+			"compression": "none",
+		},
+		"otlp/none": map[string]any{},
+	}, resolved.Get("exporters"))
+	assert.NoError(t, resolver.Shutdown(context.Background()))
+
+	prov := NewFactory(WithCredentialsDirectory(credDir)).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"token?format=map", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a JSON or YAML mapping")
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"otlp?format=map&parse=yaml", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must not combine format=map")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestTrimMode(t *testing.T) {
+	const credName = "password"
+	const credValue = " secret \t\n\n"
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, credName), []byte(credValue), 0600))
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		query    string
+		expected string
+	}{
+		{name: "default", expected: " secret \t\n"},
+		{name: "none", query: "?trim=none", expected: credValue},
+		{name: "all whitespace", query: "?trim=all-whitespace", expected: "secret"},
+		{name: "factory", opts: []Option{WithTrimMode(TrimAllWhitespace)}, expected: "secret"},
+		{name: "uri overrides factory", opts: []Option{WithTrimMode(TrimAllWhitespace)}, query: "?trim=trailing-newline", expected: " secret \t\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prov := NewFactory(tt.opts...).Create(confmaptest.NewNopProviderSettings())
+			ret, err := prov.Retrieve(context.Background(), credSchemePrefix+credName+tt.query, nil)
+			require.NoError(t, err)
+			str, err := ret.AsString()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, str)
+			assert.NoError(t, prov.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestInvalidTrimMode(t *testing.T) {
+	const credName = "password"
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, credName), []byte(testCredValue), 0600))
+
+	prov := NewFactory(WithTrimMode("sometimes")).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+credName, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unsupported trim mode "sometimes"`)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+credName+"?trim=sometimes", nil)
+	require.Error(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestNormalization(t *testing.T) {
+	const credName = "windows_token"
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, credName), []byte("\xef\xbb\xbf"+testCredValue+"\r\n"), 0600))
+
+	prov := createProvider()
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+credName+"?normalize=true", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	prov = NewFactory(WithNormalization(true)).Create(confmaptest.NewNopProviderSettings())
+	ret, err = prov.Retrieve(context.Background(), credSchemePrefix+credName, nil)
+	require.NoError(t, err)
+	str, err = ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+
+	ret, err = prov.Retrieve(context.Background(), credSchemePrefix+credName+"?normalize=false", nil)
+	require.NoError(t, err)
+	str, err = ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "\xef\xbb\xbf"+testCredValue+"\r", str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestMaxSize(t *testing.T) {
+	const credName = "large_cred"
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, credName), make([]byte, 2<<20), 0600))
+
+	prov := createProvider()
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+credName, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the maximum of 1048576 bytes")
+	assert.Nil(t, ret)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	prov = NewFactory(WithMaxSize(0)).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+credName, nil)
+	require.NoError(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	prov = NewFactory(WithMaxSize(int64(len(testCredValue)))).Create(confmaptest.NewNopProviderSettings())
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestCredentialWithDottedName(t *testing.T) {
+	const credName = "myapp.api.token"
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, credName), []byte(testCredValue), 0600))
+
+	prov := createProvider()
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+credName, nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestPercentEncodedCredentialName(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "my.app.token"), []byte(testCredValue), 0600))
+
+	prov := createProvider()
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"my%2Eapp%2Etoken", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+
+	// Encoded slashes must still be rejected after decoding.
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"..%2Fetc%2Fpasswd", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid name")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestCaseInsensitiveFallback(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "db_password"), []byte("one"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "DB_Password"), []byte("two"), 0600))
+
+	prov := createProvider()
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"API_TOKEN", nil)
+	require.Error(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	prov = NewFactory(WithCaseInsensitiveFallback(true)).Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"API_TOKEN", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+
+	// An exact match wins over case-insensitive ones.
+	ret, err = prov.Retrieve(context.Background(), credSchemePrefix+"DB_Password", nil)
+	require.NoError(t, err)
+	str, err = ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "two", str)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"DB_PASSWORD", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "matches multiple credentials")
+
+	ret, err = prov.Retrieve(context.Background(), credSchemePrefix+"OTHER?optional=true", nil)
+	require.NoError(t, err)
+	assert.NotNil(t, ret)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestAliases(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "otel.exporter.token"), []byte(testCredValue), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("unaliased"), 0600))
+
+	aliases := map[string]string{
+		"api_token": "otel.exporter.token",
+		"invalid":   "../escape",
+	}
+	prov := NewFactory(WithAliases(aliases)).Create(confmaptest.NewNopProviderSettings())
+	// Modifying the map after creating the factory must not affect it.
+	delete(aliases, "api_token")
+
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"invalid", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid name")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestConcatenatedCredentials(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "cert"), []byte("CERT\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "intermediate"), []byte("INTERMEDIATE\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "root"), []byte("ROOT"), 0600))
+
+	prov := createProvider()
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"cert+intermediate+root", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "CERT\nINTERMEDIATE\nROOT", str)
+
+	ret, err = prov.Retrieve(context.Background(), credSchemePrefix+"cert+missing:-fallback", nil)
+	require.NoError(t, err)
+	str, err = ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", str)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"cert+", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid name")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestEnvFallback(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	t.Setenv("OTEL_CRED_api_token", "from-env")
+	t.Setenv("OTEL_CRED_db_password", "db-from-env\n")
+
+	prov := NewFactory(WithEnvFallback("OTEL_CRED_")).Create(confmaptest.NewNopProviderSettings())
+	// Without a credentials directory every credential falls back to the environment.
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", str)
+
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	ret, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	str, err = ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+
+	ret, err = prov.Retrieve(context.Background(), credSchemePrefix+"db_password", nil)
+	require.NoError(t, err)
+	str, err = ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "db-from-env", str)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"missing", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read credential")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestWithCredentialsDirectory(t *testing.T) {
+	const credName = "api_token"
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, credName), []byte(testCredValue), 0600))
+	// The option takes precedence over the environment variable.
+	t.Setenv("CREDENTIALS_DIRECTORY", t.TempDir())
+
+	prov := NewFactory(WithCredentialsDirectory(credDir)).Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+credName, nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestWithCredentialsDirectoryEnv(t *testing.T) {
+	const credName = "api_token"
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, credName), []byte(testCredValue), 0600))
+	t.Setenv("S6_CREDENTIALS", credDir)
+
+	prov := NewFactory(WithCredentialsDirectoryEnv("RUNIT_CREDENTIALS", "S6_CREDENTIALS", "CREDENTIALS_DIRECTORY")).Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+credName, nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	// The default provider doesn't look at other variables.
+	prov = createProvider()
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+credName, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "CREDENTIALS_DIRECTORY environment variable is not set")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestSystemCredentialsFallback(t *testing.T) {
+	credDir := t.TempDir()
+	systemDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(systemDir, "api_token"), []byte("system-token"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(systemDir, "vm.hostname"), []byte("vm-01"), 0600))
+	withSystemDir := optionFunc(func(cfg *config) {
+		cfg.systemCredentialsDirectory = systemDir
+	})
+
+	prov := NewFactory(WithCredentialsDirectory(credDir), withSystemDir).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"vm.hostname", nil)
+	require.Error(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	prov = NewFactory(WithCredentialsDirectory(credDir), WithSystemCredentialsFallback(true), withSystemDir).Create(confmaptest.NewNopProviderSettings())
+	for credName, expected := range map[string]string{"api_token": testCredValue, "vm.hostname": "vm-01"} {
+		ret, err := prov.Retrieve(context.Background(), credSchemePrefix+credName, nil)
+		require.NoError(t, err)
+		str, err := ret.AsString()
+		require.NoError(t, err)
+		assert.Equal(t, expected, str)
+	}
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"missing", nil)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	// The fallback also applies when no credentials directory is set at all.
+	prov = NewFactory(WithSystemCredentialsFallback(true), withSystemDir).Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"vm.hostname", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "vm-01", str)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestWithLogger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+
+	prov := NewFactory(
+		WithCredentialsDirectory(credDir),
+		WithCaseInsensitiveFallback(true),
+		WithLogger(zap.New(core)),
+	).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"API_TOKEN", nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, logs.Len())
+	entry := logs.All()[0]
+	assert.Equal(t, "Credential found using case-insensitive fallback", entry.Message)
+	assert.NotContains(t, entry.ContextMap(), testCredValue)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestProviderSettingsLogger(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	settings := confmaptest.NewNopProviderSettings()
+	settings.Logger = zap.New(core)
+
+	prov := NewFactory(WithCredentialsDirectory(credDir)).Create(settings)
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"missing|"+credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"../name", nil)
+	require.Error(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	assert.NotZero(t, logs.FilterMessage("Using configured credentials directory").Len())
+	assert.Equal(t, 1, logs.FilterMessage("Fallback source does not exist, trying the next one").Len())
+	assert.Equal(t, 1, logs.FilterMessage("Rejected invalid credential name").Len())
+	read := logs.FilterMessage("Read credential").FilterField(zap.String("credential", "api_token")).All()
+	require.Len(t, read, 1)
+	assert.Equal(t, true, read[0].ContextMap()["found"])
+	for _, entry := range logs.All() {
+		assert.NotContains(t, fmt.Sprint(entry.ContextMap()), testCredValue)
+	}
+
+	// WithLogger takes precedence over the logger of the settings.
+	logs.TakeAll()
+	prov = NewFactory(WithCredentialsDirectory(credDir), WithLogger(zap.NewNop())).Create(settings)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	assert.Zero(t, logs.Len())
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestWithFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"api_token":   {Data: []byte(testCredValue + "\n")},
+		"db_password": {Data: []byte("hunter2")},
+	}
+	prov := NewFactory(WithFS(fsys), WithCaseInsensitiveFallback(true)).Create(confmaptest.NewNopProviderSettings())
+
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+
+	ret, err = prov.Retrieve(context.Background(), credSchemePrefix+"DB_PASSWORD", nil)
+	require.NoError(t, err)
+	str, err = ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", str)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"missing", nil)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
 func createProvider() confmap.Provider {
 	return NewFactory().Create(confmaptest.NewNopProviderSettings())
 }
@@ -0,0 +1,115 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// readAll reads f, failing without reading it entirely if it is larger
+// than maxSize bytes. A maxSize <= 0 disables the limit.
+func readAll(f fs.File, maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		return io.ReadAll(f)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxSize {
+		return nil, withKind(ErrTooLarge, fmt.Errorf("size of %d bytes exceeds the maximum of %d bytes", info.Size(), maxSize))
+	}
+	// The size reported by stat is unreliable for special files and files
+	// that are being written to, so the read itself is bounded as well.
+	val, err := readSized(io.LimitReader(f, maxSize+1), info.Size())
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(val)) > maxSize {
+		return nil, withKind(ErrTooLarge, fmt.Errorf("size exceeds the maximum of %d bytes", maxSize))
+	}
+	if info.Mode().IsRegular() && int64(len(val)) < info.Size() {
+		return nil, fmt.Errorf("read %d of %d bytes: %w", len(val), info.Size(), errCredentialChanged)
+	}
+	return val, nil
+}
+
+// readSized reads r like io.ReadAll, but starts with a buffer of size+1 bytes so that a file
+// of the given size is read without reallocations, each of which would leave a copy of the
+// credential behind on the heap.
+func readSized(r io.Reader, size int64) ([]byte, error) {
+	val := make([]byte, 0, size+1)
+	for {
+		n, err := r.Read(val[len(val):cap(val)])
+		val = val[:len(val)+n]
+		if err == io.EOF {
+			return val, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(val) == cap(val) {
+			// The file is larger than stat reported, grow the buffer like io.ReadAll.
+			val = append(val, 0)[:len(val)]
+		}
+	}
+}
+
+// findCaseInsensitive returns the name of the single entry at the root of fsys that matches
+// name case-insensitively, or an empty string if there is none.
+func findCaseInsensitive(fsys fs.FS, name string) (string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	for _, entry := range entries {
+		if strings.EqualFold(entry.Name(), name) {
+			matches = append(matches, entry.Name())
+		}
+	}
+	if len(matches) > 1 {
+		return "", fmt.Errorf("name matches multiple credentials case-insensitively: %q", matches)
+	}
+	if len(matches) == 0 {
+		return "", nil
+	}
+	return matches[0], nil
+}
+
+// readCredentialContext reads the credential called name like readCredential, but returns as
+// soon as ctx is done, so that a hung credentials mount can't block Retrieve forever. The read
+// itself can't be interrupted, it finishes in the background and its result is wiped.
+func (p *provider) readCredentialContext(ctx context.Context, name string) ([]byte, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to read credential %q: %w", name, err)
+	}
+	if ctx.Done() == nil {
+		// The context can't be canceled, so there is no need to read in the background.
+		return p.readCredential(name)
+	}
+	type result struct {
+		val    []byte
+		source string
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		val, source, err := p.readCredential(name)
+		done <- result{val: val, source: source, err: err}
+	}()
+	select {
+	case r := <-done:
+		return r.val, r.source, r.err
+	case <-ctx.Done():
+		go func() {
+			clear((<-done).val)
+		}()
+		return nil, "", fmt.Errorf("failed to read credential %q: %w", name, ctx.Err())
+	}
+}
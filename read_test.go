@@ -0,0 +1,82 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestReadSized(t *testing.T) {
+	for _, size := range []int64{0, 5, 11, 64} {
+		val, err := readSized(iotest.HalfReader(strings.NewReader("hello world")), size)
+		require.NoError(t, err)
+		assert.Equal(t, "hello world", string(val), "size %d", size)
+	}
+	_, err := readSized(iotest.ErrReader(assert.AnError), 8)
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+// truncatedFile is a file that is shorter than its stat reports, like a file truncated while reading it.
+type truncatedFile struct {
+	fs.File
+	size int64
+}
+
+type truncatedInfo struct {
+	fs.FileInfo
+	size int64
+}
+
+func (f truncatedFile) Stat() (fs.FileInfo, error) {
+	info, err := f.File.Stat()
+	return truncatedInfo{FileInfo: info, size: f.size}, err
+}
+
+func (i truncatedInfo) Size() int64 { return i.size }
+
+func TestReadAllTruncated(t *testing.T) {
+	f, err := fstest.MapFS{"api_token": {Data: []byte("short")}}.Open("api_token")
+	require.NoError(t, err)
+	_, err = readAll(truncatedFile{File: f, size: 10}, defaultMaxSize)
+	assert.ErrorIs(t, err, errCredentialChanged)
+	assert.ErrorContains(t, err, "read 5 of 10 bytes")
+}
+
+// hungFS blocks opening files until release is closed, like a hung network mount.
+type hungFS struct {
+	fs.FS
+	release chan struct{}
+}
+
+func (f hungFS) Open(name string) (fs.File, error) {
+	<-f.release
+	return f.FS.Open(name)
+}
+
+func TestRetrieveHonorsContext(t *testing.T) {
+	fsys := hungFS{FS: fstest.MapFS{"api_token": {Data: []byte(testCredValue)}}, release: make(chan struct{})}
+	defer close(fsys.release)
+	prov := NewFactory(WithFS(fsys)).Create(confmaptest.NewNopProviderSettings())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := prov.Retrieve(ctx, credSchemePrefix+"api_token", nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = prov.Retrieve(canceled, credSchemePrefix+"api_token", nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
@@ -0,0 +1,216 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+// minEmbeddedRedactLength is the minimum length of a tagged value for it to be redacted when it
+// is embedded in a longer string. Shorter values are only redacted when they make up a whole
+// string, so that short credentials don't mangle unrelated values that happen to contain them.
+const minEmbeddedRedactLength = 8
+
+// Redactor keeps values resolved from credentials out of serialized configurations, such as the
+// collector's effective configuration or debug dumps.
+//
+// Pass it to the provider with WithRedactor, which tags every value it resolves: strings, as well
+// as the strings and numbers held by values resolved with `parse=yaml` or `format=map`. Booleans,
+// such as the values resolved with `exists=true`, aren't secrets and aren't tagged. Redact then
+// replaces the tagged values in a configuration with `[REDACTED:systemdcredential:NAME]`. Numbers
+// are only redacted where they make up a whole value. A value stays tagged until the
+// retrieved value it came from is closed, which the resolver does before resolving again, so
+// rotated credentials don't linger. Adding ConverterFactory to the resolver keeps a redacted
+// copy of the last resolved configuration, see Redacted; the configuration itself is left untouched.
+type Redactor struct {
+	mu sync.Mutex
+	// values maps the URIs of the resolved values that haven't been closed to their values.
+	values   map[string]*taggedValue
+	redacted map[string]any
+}
+
+// taggedValue holds the values resolved from a URI, see Redactor.tag.
+type taggedValue struct {
+	// vals holds the strings and numbers of the resolved value, see taggedScalars.
+	vals []any
+	// label identifies the credential in the redacted configuration.
+	label string
+	// refs counts the open retrieved values of the URI holding val.
+	refs int
+}
+
+// NewRedactor returns a new Redactor.
+func NewRedactor() *Redactor {
+	return &Redactor{values: map[string]*taggedValue{}}
+}
+
+// track tags the content of ret, resolved from uri, until ret is closed. It returns the
+// value to return in place of ret.
+func (r *Redactor) track(uri string, ret *confmap.Retrieved) (*confmap.Retrieved, error) {
+	raw, err := ret.AsRaw()
+	if err != nil {
+		return nil, err
+	}
+	vals := taggedScalars(raw, nil)
+	if len(vals) == 0 {
+		return ret, nil
+	}
+	r.tag(uri, vals)
+	closeFunc := confmap.WithRetrievedClose(func(ctx context.Context) error {
+		r.untag(uri, vals)
+		return ret.Close(ctx)
+	})
+	if _, ok := raw.(string); ok {
+		return confmap.NewRetrieved(raw, closeFunc)
+	}
+	// Values parsed from YAML keep their source as string form, so that they can still be used
+	// inline, e.g. a port in `localhost:${systemdcredential:port?parse=yaml}`.
+	if str, err := ret.AsString(); err == nil {
+		return confmap.NewRetrievedFromYAML([]byte(str), closeFunc)
+	}
+	return confmap.NewRetrieved(raw, closeFunc)
+}
+
+// taggedScalars appends the non-empty strings and the numbers held by raw to vals.
+func taggedScalars(raw any, vals []any) []any {
+	switch raw := raw.(type) {
+	case map[string]any:
+		for _, key := range slices.Sorted(maps.Keys(raw)) {
+			vals = taggedScalars(raw[key], vals)
+		}
+	case []any:
+		for _, val := range raw {
+			vals = taggedScalars(val, vals)
+		}
+	case string:
+		if raw != "" {
+			vals = append(vals, raw)
+		}
+	case int, int64, uint64, float64:
+		vals = append(vals, raw)
+	}
+	return vals
+}
+
+// tag records that vals were resolved from uri, replacing earlier values of uri.
+func (r *Redactor) tag(uri string, vals []any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.values[uri]; ok && slices.Equal(t.vals, vals) {
+		t.refs++
+		return
+	}
+	// The query holds options rather than the identity of the credential.
+	label, _, _ := strings.Cut(uri, "?")
+	r.values[uri] = &taggedValue{vals: vals, label: label, refs: 1}
+}
+
+// untag releases a reference to vals, resolved from uri, once the value they were retrieved in is closed.
+func (r *Redactor) untag(uri string, vals []any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.values[uri]
+	if !ok || !slices.Equal(t.vals, vals) {
+		return
+	}
+	if t.refs--; t.refs == 0 {
+		delete(r.values, uri)
+	}
+}
+
+// Redact returns a copy of conf in which every string or number that is a value resolved by the
+// provider is replaced with `[REDACTED:URI]`. String values of at least 8 bytes are also redacted
+// where they are embedded in longer strings.
+func (r *Redactor) Redact(conf map[string]any) map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	whole := make(map[any]string, len(r.values))
+	for _, uri := range slices.Sorted(maps.Keys(r.values)) {
+		t := r.values[uri]
+		for _, val := range t.vals {
+			if _, ok := whole[val]; !ok {
+				whole[val] = "[REDACTED:" + t.label + "]"
+			}
+		}
+	}
+	var values []string
+	for val := range whole {
+		if val, ok := val.(string); ok && len(val) >= minEmbeddedRedactLength {
+			values = append(values, val)
+		}
+	}
+	// Replace longer values first, so that values containing other values are redacted whole.
+	slices.SortFunc(values, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b))
+	})
+	oldnew := make([]string, 0, 2*len(values))
+	for _, val := range values {
+		oldnew = append(oldnew, val, whole[val])
+	}
+	return redactValue(conf, whole, strings.NewReplacer(oldnew...)).(map[string]any)
+}
+
+func redactValue(v any, whole map[any]string, replacer *strings.Replacer) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, val := range v {
+			out[key] = redactValue(val, whole, replacer)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = redactValue(val, whole, replacer)
+		}
+		return out
+	case string:
+		if redacted, ok := whole[v]; ok {
+			return redacted
+		}
+		return replacer.Replace(v)
+	case int, int64, uint64, float64:
+		if redacted, ok := whole[v]; ok {
+			return redacted
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// ConverterFactory returns a factory for a converter that stores a redacted copy of the
+// resolved configuration, which Redacted returns. It doesn't modify the configuration.
+func (r *Redactor) ConverterFactory() confmap.ConverterFactory {
+	return confmap.NewConverterFactory(func(confmap.ConverterSettings) confmap.Converter {
+		return redactorConverter{r: r}
+	})
+}
+
+// Redacted returns a redacted copy of the configuration last resolved with the converter
+// returned by ConverterFactory, or nil if none has been resolved yet.
+func (r *Redactor) Redacted() map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.redacted
+}
+
+type redactorConverter struct {
+	r *Redactor
+}
+
+func (c redactorConverter) Convert(_ context.Context, conf *confmap.Conf) error {
+	redacted := c.r.Redact(conf.ToStringMap())
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	c.r.redacted = redacted
+	return nil
+}
@@ -0,0 +1,181 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestRedactor(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "password"), []byte("hunter2"), 0600))
+	conf := map[string]any{
+		"token":         "${systemdcredential:api_token}",
+		"authorization": "Bearer ${systemdcredential:api_token?trim=all-whitespace}",
+		"list":          []any{"${systemdcredential:password}", "plain"},
+		"endpoint":      "https://example.com",
+		"port":          4317,
+	}
+
+	r := NewRedactor()
+	resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+		URIs: []string{"static:config"},
+		ProviderFactories: []confmap.ProviderFactory{
+			confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider {
+				return staticProvider{conf: conf}
+			}),
+			NewFactory(WithCredentialsDirectory(credDir), WithRedactor(r)),
+		},
+		ConverterFactories: []confmap.ConverterFactory{r.ConverterFactory()},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, r.Redacted())
+
+	retMap, err := resolver.Resolve(context.Background())
+	require.NoError(t, err)
+	// The resolved configuration itself keeps the values.
+	assert.Equal(t, testCredValue, retMap.Get("token"))
+
+	expected := map[string]any{
+		"token":         "[REDACTED:systemdcredential:api_token]",
+		"authorization": "Bearer [REDACTED:systemdcredential:api_token]",
+		"list":          []any{"[REDACTED:systemdcredential:password]", "plain"},
+		"endpoint":      "https://example.com",
+		"port":          4317,
+	}
+	assert.Equal(t, expected, r.Redacted())
+	assert.Equal(t, expected, r.Redact(retMap.ToStringMap()))
+	assert.NoError(t, resolver.Shutdown(context.Background()))
+}
+
+func TestRedactorOnlyRedactsCredentials(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "port"), []byte("4317"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "pin"), []byte("1234"), 0600))
+
+	r := NewRedactor()
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithRedactor(r)).Create(confmaptest.NewNopProviderSettings())
+	var rets []*confmap.Retrieved
+	for _, uri := range []string{"api_token?exists=true", "port?parse=yaml", "pin", "api_token", "api_token?trim=none"} {
+		ret, err := prov.Retrieve(context.Background(), credSchemePrefix+uri, nil)
+		require.NoError(t, err)
+		rets = append(rets, ret)
+	}
+	assert.Equal(t, map[string]any{
+		"endpoint": "localhost:4317",
+		"enabled":  "trueno",
+		"pin":      "[REDACTED:systemdcredential:pin]",
+		// Short values are only redacted whole.
+		"pins":  "12345",
+		"token": "Bearer [REDACTED:systemdcredential:api_token]",
+	}, r.Redact(map[string]any{
+		"endpoint": "localhost:4317",
+		"enabled":  "trueno",
+		"pin":      "1234",
+		"pins":     "12345",
+		"token":    "Bearer " + testCredValue,
+	}))
+
+	// Values are untagged once every value retrieved for them is closed.
+	for _, ret := range rets[:4] {
+		require.NoError(t, ret.Close(context.Background()))
+	}
+	assert.Equal(t, map[string]any{"pin": "1234", "token": "[REDACTED:systemdcredential:api_token]"},
+		r.Redact(map[string]any{"pin": "1234", "token": testCredValue}))
+	require.NoError(t, rets[4].Close(context.Background()))
+	assert.Equal(t, map[string]any{"token": testCredValue}, r.Redact(map[string]any{"token": testCredValue}))
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestRedactorParsedValues(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "pin"), []byte("123456"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "password"), []byte("'hunter2-hunter2'"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "port"), []byte("4317\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "otlp"), []byte("endpoint: https://example.com\nheaders:\n  authorization: Bearer "+testCredValue+"\ntimeout: 5\ninsecure: true\n"), 0600))
+	conf := map[string]any{
+		"pin":      "${systemdcredential:pin?parse=yaml}",
+		"password": "${systemdcredential:password?parse=yaml}",
+		"otlp":     "${systemdcredential:otlp?format=map}",
+		"enabled":  "${systemdcredential:pin?exists=true}",
+		"endpoint": "localhost:${systemdcredential:port?parse=yaml}",
+		"other":    true,
+	}
+
+	r := NewRedactor()
+	resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+		URIs: []string{"static:config"},
+		ProviderFactories: []confmap.ProviderFactory{
+			confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider {
+				return staticProvider{conf: conf}
+			}),
+			NewFactory(WithCredentialsDirectory(credDir), WithRedactor(r)),
+		},
+		ConverterFactories: []confmap.ConverterFactory{r.ConverterFactory()},
+	})
+	require.NoError(t, err)
+	retMap, err := resolver.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 123456, retMap.Get("pin"))
+	// Parsed values can still be used inline.
+	assert.Equal(t, "localhost:4317", retMap.Get("endpoint"))
+
+	assert.Equal(t, map[string]any{
+		"pin":      "[REDACTED:systemdcredential:pin]",
+		"password": "[REDACTED:systemdcredential:password]",
+		"otlp": map[string]any{
+			"endpoint": "[REDACTED:systemdcredential:otlp]",
+			"headers":  map[string]any{"authorization": "[REDACTED:systemdcredential:otlp]"},
+			"timeout":  "[REDACTED:systemdcredential:otlp]",
+			"insecure": true,
+		},
+		// Booleans, such as the values resolved with exists=true, aren't tagged.
+		"enabled": true,
+		// Numbers are only redacted where they make up a whole value.
+		"endpoint": "localhost:4317",
+		"other":    true,
+	}, r.Redacted())
+	assert.NoError(t, resolver.Shutdown(context.Background()))
+	assert.Empty(t, r.values)
+}
+
+func TestRedactorRotation(t *testing.T) {
+	credDir := t.TempDir()
+	credPath := filepath.Join(credDir, "api_token")
+	require.NoError(t, os.WriteFile(credPath, []byte(testCredValue), 0600))
+	r := NewRedactor()
+	resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+		URIs: []string{"static:config"},
+		ProviderFactories: []confmap.ProviderFactory{
+			confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider {
+				return staticProvider{conf: map[string]any{"token": "${systemdcredential:api_token}"}}
+			}),
+			NewFactory(WithCredentialsDirectory(credDir), WithRedactor(r)),
+		},
+		ConverterFactories: []confmap.ConverterFactory{r.ConverterFactory()},
+	})
+	require.NoError(t, err)
+	_, err = resolver.Resolve(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(credPath, []byte("rotated-secret-value"), 0600))
+	_, err = resolver.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"token": "[REDACTED:systemdcredential:api_token]"}, r.Redacted())
+	// The value before the rotation is no longer held.
+	assert.Equal(t, map[string]any{"old": testCredValue}, r.Redact(map[string]any{"old": testCredValue}))
+	assert.Len(t, r.values, 1)
+	assert.NoError(t, resolver.Shutdown(context.Background()))
+	assert.Empty(t, r.values)
+}
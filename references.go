@@ -0,0 +1,104 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// CredentialReferences returns the names of the credentials referenced with
+// `${systemdcredential:...}` in conf, a configuration as decoded from YAML, in the order they
+// first appear. Names are returned as written, before aliases are applied. Together with
+// LookupUnitCredentials this allows checking that a unit passes every credential a
+// configuration needs before starting the collector.
+func CredentialReferences(conf any) ([]string, error) {
+	var names []string
+	err := walkReferences(conf, func(_ string, ref *reference) {
+		for _, name := range ref.names {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// CredentialReferenceURIs returns the URIs of the `${systemdcredential:...}` references in conf,
+// a configuration as decoded from YAML, in the order they first appear, as they are passed to
+// the provider: including their options and, for fallback chains, the other sources. Retrieving
+// every URI checks that a configuration will resolve without resolving all of it.
+func CredentialReferenceURIs(conf any) ([]string, error) {
+	var uris []string
+	err := walkReferences(conf, func(uri string, _ *reference) {
+		// Chains starting with another scheme are resolved by the provider of that scheme.
+		if strings.HasPrefix(uri, schemeName+":") && !slices.Contains(uris, uri) {
+			uris = append(uris, uri)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uris, nil
+}
+
+// walkReferences calls found for every credential reference in the strings of conf. Maps are
+// walked in the order of their keys.
+func walkReferences(conf any, found func(uri string, ref *reference)) error {
+	switch v := conf.(type) {
+	case map[string]any:
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			if err := walkReferences(v[key], found); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, val := range v {
+			if err := walkReferences(val, found); err != nil {
+				return err
+			}
+		}
+	case string:
+		return referencesInString(v, found)
+	}
+	return nil
+}
+
+// referencesInString calls found for every credential reference in val.
+func referencesInString(val string, found func(uri string, ref *reference)) error {
+	for {
+		i := strings.IndexByte(val, '$')
+		if i < 0 || i == len(val)-1 {
+			return nil
+		}
+		switch val[i+1] {
+		case '$':
+			val = val[i+2:]
+		case '{':
+			end := strings.IndexByte(val[i:], '}')
+			if end < 0 {
+				return nil
+			}
+			uri := val[i+2 : i+end]
+			for _, source := range strings.Split(uri, fallbackSeparator) {
+				if !strings.HasPrefix(source, schemeName+":") {
+					continue
+				}
+				ref, err := parseURI(source, schemeName, false)
+				if err != nil {
+					return fmt.Errorf("invalid reference %q: %w", val[i:i+end+1], err)
+				}
+				found(uri, ref)
+			}
+			val = val[i+end+1:]
+		default:
+			val = val[i+1:]
+		}
+	}
+}
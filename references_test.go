@@ -0,0 +1,57 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialReferences(t *testing.T) {
+	conf := map[string]any{
+		"exporters": map[string]any{
+			"otlp": map[string]any{
+				"endpoint": "${env:ENDPOINT}",
+				"headers": map[string]any{
+					"authorization": "Bearer ${systemdcredential:api_token?trim=all-whitespace}",
+				},
+			},
+		},
+		"extensions": []any{
+			"${systemdcredential:user+password}",
+			"$${systemdcredential:escaped}",
+			"${systemdcredential:api_token}",
+			"${systemdcredential:tls.key|file:/etc/otel/tls.key}",
+			42,
+		},
+	}
+	names, err := CredentialReferences(conf)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"api_token", "user", "password", "tls.key"}, names)
+}
+
+func TestCredentialReferenceURIs(t *testing.T) {
+	conf := map[string]any{
+		"b": []any{
+			"Bearer ${systemdcredential:api_token?trim=all-whitespace}",
+			"${systemdcredential:tls.key|file:/etc/otel/tls.key}",
+			"${env:TOKEN|systemdcredential:api_token}",
+		},
+		"a": "${systemdcredential:user+password} ${systemdcredential:user+password}",
+	}
+	uris, err := CredentialReferenceURIs(conf)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"systemdcredential:user+password",
+		"systemdcredential:api_token?trim=all-whitespace",
+		"systemdcredential:tls.key|file:/etc/otel/tls.key",
+	}, uris)
+}
+
+func TestCredentialReferencesInvalid(t *testing.T) {
+	_, err := CredentialReferences([]any{"${systemdcredential:api_token?unknown=true}"})
+	assert.ErrorContains(t, err, `invalid reference "${systemdcredential:api_token?unknown=true}"`)
+}
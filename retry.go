@@ -0,0 +1,45 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// errCredentialChanged is returned when a credential was truncated while it was being read.
+var errCredentialChanged = errors.New("credential changed while reading it")
+
+// readCredentialWithRetry reads the credential called name like readCredential, retrying
+// transient failures as configured with WithReadRetry.
+func (p *provider) readCredentialWithRetry(ctx context.Context, name string) ([]byte, string, error) {
+	backoff := p.cfg.retryBackoff
+	for attempt := 1; ; attempt++ {
+		val, source, err := p.readCredentialContext(ctx, name)
+		if err == nil || attempt > p.cfg.retryAttempts || !transientReadError(err) || ctx.Err() != nil {
+			return val, source, err
+		}
+		p.cfg.logger.Debug("Retrying credential read",
+			zap.String("credential", name), zap.Int("attempt", attempt), zap.Duration("backoff", backoff), zap.Error(err))
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, source, fmt.Errorf("%w; stopped retrying: %w", err, ctx.Err())
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// transientReadError reports whether err may be caused by a credential being replaced, in
+// which case reading it again shortly after may succeed.
+func transientReadError(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, errCredentialChanged)
+}
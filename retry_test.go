@@ -0,0 +1,93 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"io/fs"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+// flakyFS fails to open files as if they didn't exist yet: files only appear after failures opens.
+// Opening the root directory, e.g. to list it, isn't counted.
+type flakyFS struct {
+	fs.FS
+	mu       sync.Mutex
+	failures int
+	opens    int
+}
+
+func (f *flakyFS) Open(name string) (fs.File, error) {
+	if name == "." {
+		return f.FS.Open(name)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.opens++
+	if f.opens <= f.failures {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return f.FS.Open(name)
+}
+
+func TestReadRetry(t *testing.T) {
+	newFS := func(failures int) *flakyFS {
+		return &flakyFS{FS: fstest.MapFS{"api_token": {Data: []byte(testCredValue)}}, failures: failures}
+	}
+
+	tests := []struct {
+		name      string
+		attempts  int
+		failures  int
+		wantOpens int
+		wantErr   bool
+	}{
+		{name: "disabled", attempts: 0, failures: 1, wantOpens: 1, wantErr: true},
+		{name: "recovers", attempts: 3, failures: 2, wantOpens: 3},
+		{name: "gives up", attempts: 2, failures: 5, wantOpens: 3, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := newFS(tt.failures)
+			prov := NewFactory(WithFS(fsys), WithReadRetry(tt.attempts, time.Millisecond)).Create(confmaptest.NewNopProviderSettings())
+			ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, fs.ErrNotExist)
+			} else {
+				require.NoError(t, err)
+				str, err := ret.AsString()
+				require.NoError(t, err)
+				assert.Equal(t, testCredValue, str)
+			}
+			assert.Equal(t, tt.wantOpens, fsys.opens)
+			assert.NoError(t, prov.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestReadRetryCanceled(t *testing.T) {
+	fsys := &flakyFS{FS: fstest.MapFS{}, failures: 100}
+	prov := NewFactory(WithFS(fsys), WithReadRetry(10, time.Hour)).Create(confmaptest.NewNopProviderSettings())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := prov.Retrieve(ctx, credSchemePrefix+"api_token", nil)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, fsys.opens)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestTransientReadError(t *testing.T) {
+	assert.True(t, transientReadError(fs.ErrNotExist))
+	assert.True(t, transientReadError(errCredentialChanged))
+	assert.False(t, transientReadError(ErrNoCredentialsDirectory))
+	assert.False(t, transientReadError(fs.ErrPermission))
+}
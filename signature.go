@@ -0,0 +1,160 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// signatureSuffix is appended to the name of a credential to get the name of its signature sidecar.
+const signatureSuffix = ".sig"
+
+const (
+	// minisignAlgorithm identifies minisign signatures and keys of the content itself, as created
+	// by `minisign -S -l`.
+	minisignAlgorithm = "Ed"
+	// minisignPrehashedAlgorithm identifies minisign signatures of the BLAKE2b hash of the content.
+	minisignPrehashedAlgorithm = "ED"
+	// minisignKeyIDLength is the length of the key ID in minisign signatures and keys.
+	minisignKeyIDLength = 8
+	// trustedCommentPrefix starts the line of a minisign signature holding the trusted comment.
+	trustedCommentPrefix = "trusted comment: "
+)
+
+// signature is a parsed signature sidecar.
+type signature struct {
+	// sig is the ed25519 signature of the credential.
+	sig []byte
+	// trustedComment and globalSig are set for minisign signatures, globalSig signing sig
+	// followed by trustedComment.
+	trustedComment []byte
+	globalSig      []byte
+}
+
+// verifySignature verifies val, the content of the credential called name retrieved for uri, against
+// its signature sidecar, read with read, with the keys set with WithSignatureVerification and
+// WithSignatureKeyCredential.
+func (p *provider) verifySignature(ctx context.Context, uri, name string, val []byte, read sidecarReader) error {
+	keys, err := p.signatureKeys(ctx, uri)
+	if err != nil {
+		return err
+	}
+	sidecar := name + signatureSuffix
+	content, _, err := read(ctx, sidecar)
+	if err != nil && isMissing(err) {
+		// The sidecar being missing must not make the credential itself count as missing.
+		return withKind(ErrInvalidSignature, fmt.Errorf("credential %q is not signed: signature sidecar %q doesn't exist", name, sidecar))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read signature sidecar %q of credential %q: %w", sidecar, name, err)
+	}
+	sig, err := parseSignature(content)
+	if err != nil {
+		return fmt.Errorf("invalid signature sidecar %q of credential %q: %w", sidecar, name, err)
+	}
+	for _, key := range keys {
+		if sig.verify(key, val) {
+			return nil
+		}
+	}
+	return withKind(ErrInvalidSignature, fmt.Errorf("credential %q doesn't match the signature in %q", name, sidecar))
+}
+
+// verify reports whether s is a valid signature of val by key.
+func (s *signature) verify(key ed25519.PublicKey, val []byte) bool {
+	if !ed25519.Verify(key, val, s.sig) {
+		return false
+	}
+	return s.globalSig == nil || ed25519.Verify(key, append(bytes.Clone(s.sig), s.trustedComment...), s.globalSig)
+}
+
+// signatureKeys returns the keys credentials retrieved for uri can be signed with.
+func (p *provider) signatureKeys(ctx context.Context, uri string) ([]ed25519.PublicKey, error) {
+	if p.cfg.signatureKeyCredential == "" {
+		return p.cfg.signatureKeys, nil
+	}
+	content, _, err := p.readCredentialObserved(ctx, uri, p.cfg.signatureKeyCredential)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature key credential %q: %w", p.cfg.signatureKeyCredential, err)
+	}
+	key, err := parsePublicKey(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature key credential %q: %w", p.cfg.signatureKeyCredential, err)
+	}
+	return append(p.cfg.signatureKeys[:len(p.cfg.signatureKeys):len(p.cfg.signatureKeys)], key), nil
+}
+
+// parseSignature parses a signature sidecar: a raw ed25519 signature, a base64-encoded one, or a
+// minisign signature file.
+func parseSignature(content []byte) (*signature, error) {
+	if len(content) == ed25519.SignatureSize {
+		return &signature{sig: content}, nil
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) == 1 {
+		sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[0]))
+		if err != nil || len(sig) != ed25519.SignatureSize {
+			return nil, errors.New("expected an ed25519 signature, raw or base64-encoded, or a minisign signature")
+		}
+		return &signature{sig: sig}, nil
+	}
+	if len(lines) != 4 || !strings.HasPrefix(lines[2], trustedCommentPrefix) {
+		return nil, errors.New("expected a minisign signature of 4 lines")
+	}
+	sig, err := decodeMinisign(lines[1], ed25519.SignatureSize)
+	if err != nil {
+		return nil, err
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return nil, errors.New("invalid global signature")
+	}
+	return &signature{
+		sig:            sig,
+		trustedComment: []byte(strings.TrimSuffix(strings.TrimPrefix(lines[2], trustedCommentPrefix), "\r")),
+		globalSig:      globalSig,
+	}, nil
+}
+
+// parsePublicKey parses an ed25519 public key, base64-encoded or as a minisign public key file.
+func parsePublicKey(content []byte) (ed25519.PublicKey, error) {
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	switch len(lines) {
+	case 1:
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[0]))
+		if err == nil && len(key) == ed25519.PublicKeySize {
+			return key, nil
+		}
+		return decodeMinisign(lines[0], ed25519.PublicKeySize)
+	case 2:
+		return decodeMinisign(lines[1], ed25519.PublicKeySize)
+	}
+	return nil, errors.New("expected a base64-encoded ed25519 public key or a minisign public key")
+}
+
+// decodeMinisign decodes line, the base64-encoded algorithm, key ID and payload of size bytes of
+// a minisign signature or public key, and returns the payload.
+func decodeMinisign(line string, size int) ([]byte, error) {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(line))
+	if err != nil {
+		return nil, fmt.Errorf("invalid minisign encoding: %w", err)
+	}
+	if len(decoded) != len(minisignAlgorithm)+minisignKeyIDLength+size {
+		return nil, errors.New("invalid minisign length")
+	}
+	switch algorithm := string(decoded[:len(minisignAlgorithm)]); algorithm {
+	case minisignAlgorithm:
+	case minisignPrehashedAlgorithm:
+		return nil, errors.New("prehashed minisign signatures are not supported, sign with `minisign -S -l`")
+	default:
+		return nil, fmt.Errorf("unsupported minisign algorithm %q", algorithm)
+	}
+	return decoded[len(minisignAlgorithm)+minisignKeyIDLength:], nil
+}
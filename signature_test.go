@@ -0,0 +1,143 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+var testKeyID = []byte("keyid123")
+
+// minisignPublicKey returns pub in the format of a minisign public key file.
+func minisignPublicKey(pub ed25519.PublicKey) []byte {
+	encoded := base64.StdEncoding.EncodeToString(append(append([]byte(minisignAlgorithm), testKeyID...), pub...))
+	return []byte("untrusted comment: minisign public key\n" + encoded + "\n")
+}
+
+// minisignSignature returns the signature of content by priv in the format of a minisign signature
+// file created with `minisign -S -l`, using algorithm.
+func minisignSignature(priv ed25519.PrivateKey, content []byte, algorithm string) []byte {
+	sig := ed25519.Sign(priv, content)
+	trustedComment := "timestamp:1700000000\tfile:credential"
+	globalSig := ed25519.Sign(priv, append(append([]byte{}, sig...), trustedComment...))
+	return []byte("untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte(algorithm), testKeyID...), sig...)) + "\n" +
+		trustedCommentPrefix + trustedComment + "\n" +
+		base64.StdEncoding.EncodeToString(globalSig) + "\n")
+}
+
+func TestSignatureVerification(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	content := []byte("https://otlp.example.com:4317\n")
+	// A changed trusted comment no longer matches the global signature.
+	tampered := bytes.Replace(minisignSignature(priv, content, minisignAlgorithm), []byte("timestamp:1700000000"), []byte("timestamp:1700000001"), 1)
+
+	credDir := t.TempDir()
+	for name, val := range map[string][]byte{
+		"endpoint.raw":           content,
+		"endpoint.raw.sig":       ed25519.Sign(priv, content),
+		"endpoint.base64":        content,
+		"endpoint.base64.sig":    []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, content)) + "\n"),
+		"endpoint.minisign":      content,
+		"endpoint.minisign.sig":  minisignSignature(priv, content, minisignAlgorithm),
+		"endpoint.prehashed":     content,
+		"endpoint.prehashed.sig": minisignSignature(priv, content, minisignPrehashedAlgorithm),
+		"endpoint.tampered":      content,
+		"endpoint.tampered.sig":  tampered,
+		"endpoint.other":         content,
+		"endpoint.other.sig":     ed25519.Sign(otherPriv, content),
+		"endpoint.rogue":         []byte("https://rogue.example.com:4317\n"),
+		"endpoint.rogue.sig":     ed25519.Sign(priv, content),
+		"endpoint.unsigned":      content,
+		"signing.pub":            minisignPublicKey(pub),
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(credDir, name), val, 0600))
+	}
+
+	tests := []struct {
+		name    string
+		wantErr string
+	}{
+		{name: "endpoint.raw"},
+		{name: "endpoint.base64"},
+		{name: "endpoint.minisign"},
+		{name: "endpoint.prehashed", wantErr: "prehashed minisign signatures are not supported"},
+		{name: "endpoint.tampered", wantErr: `credential "endpoint.tampered" doesn't match the signature`},
+		{name: "endpoint.other", wantErr: `credential "endpoint.other" doesn't match the signature`},
+		{name: "endpoint.rogue", wantErr: `credential "endpoint.rogue" doesn't match the signature`},
+		{name: "endpoint.unsigned", wantErr: `credential "endpoint.unsigned" is not signed`},
+	}
+	for _, opt := range map[string]Option{
+		"key":            WithSignatureVerification(pub),
+		"key credential": WithSignatureKeyCredential("signing.pub"),
+	} {
+		prov := NewFactory(WithCredentialsDirectory(credDir), opt).Create(confmaptest.NewNopProviderSettings())
+		for _, tt := range tests {
+			ret, err := prov.Retrieve(context.Background(), credSchemePrefix+tt.name, nil)
+			if tt.wantErr != "" {
+				require.Error(t, err, tt.name)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				continue
+			}
+			require.NoError(t, err, tt.name)
+			str, err := ret.AsString()
+			require.NoError(t, err)
+			assert.Equal(t, "https://otlp.example.com:4317", str)
+		}
+		assert.NoError(t, prov.Shutdown(context.Background()))
+	}
+}
+
+func TestSignatureErrors(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithSignatureVerification(pub)).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	assert.NotErrorIs(t, err, ErrNotFound)
+	// A default value doesn't hide an unsigned credential.
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token:-fallback", nil)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	prov = NewFactory(WithCredentialsDirectory(credDir), WithSignatureKeyCredential("missing.pub")).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	assert.ErrorContains(t, err, `failed to read signature key credential "missing.pub"`)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestParsePublicKey(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	for _, content := range [][]byte{
+		[]byte(base64.StdEncoding.EncodeToString(pub) + "\n"),
+		minisignPublicKey(pub),
+		minisignPublicKey(pub)[len("untrusted comment: minisign public key\n"):],
+	} {
+		key, err := parsePublicKey(content)
+		require.NoError(t, err, string(content))
+		assert.Equal(t, pub, key)
+	}
+	for _, content := range []string{"", "not a key", "a\nb\nc"} {
+		_, err := parsePublicKey([]byte(content))
+		assert.Error(t, err, content)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultSopsCommand is the command used to decrypt SOPS-encrypted credentials, see WithSopsCommand.
+	defaultSopsCommand = "sops"
+	// sopsAgeKeyFileEnv points sops at the file holding its age identities.
+	sopsAgeKeyFileEnv = "SOPS_AGE_KEY_FILE"
+)
+
+// decryptSops decrypts val, the content of the credential called name retrieved for uri, a
+// SOPS-encrypted YAML or JSON document, by running `sops --decrypt`. With opts.sopsKey, only the
+// value at that path is returned. With opts.ageIdentity, sops uses the age identities in that credential; otherwise it
+// finds its keys (age, KMS, PGP, ...) like it does on its own.
+func (p *provider) decryptSops(ctx context.Context, uri, name string, opts *uriOptions, val []byte, bufs *credentialBuffers) ([]byte, error) {
+	format := sopsFormat(val)
+	args := []string{"--decrypt", "--input-type", format, "--output-type", format}
+	if opts.sopsKey != "" {
+		args = append(args, "--extract", sopsExtractPath(opts.sopsKey))
+	}
+	args = append(args, "/dev/stdin")
+	var env []string
+	var identity []byte
+	if opts.ageIdentity != "" {
+		var err error
+		if _, identity, err = p.readAgeIdentity(ctx, uri, opts.ageIdentity, bufs); err != nil {
+			return nil, err
+		}
+		env = []string{sopsAgeKeyFileEnv + "=" + identityFile}
+	}
+	val, err := runDecryptCommand(ctx, p.cfg.sopsCommand, args, env, val, identity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt SOPS-encrypted credential %q: %w", name, err)
+	}
+	return bufs.add(val), nil
+}
+
+// sopsFormat returns the format of the SOPS-encrypted document val, "json" or "yaml".
+func sopsFormat(val []byte) string {
+	if bytes.HasPrefix(bytes.TrimSpace(val), []byte("{")) {
+		return "json"
+	}
+	return "yaml"
+}
+
+// sopsExtractPath converts key, a path of map keys and list indices separated by '.', e.g.
+// "database.replicas.0.password", to the syntax of `sops --extract`.
+func sopsExtractPath(key string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(key, ".") {
+		if _, err := strconv.Atoi(part); err == nil {
+			fmt.Fprintf(&b, "[%s]", part)
+		} else {
+			fmt.Fprintf(&b, "[%q]", part)
+		}
+	}
+	return b.String()
+}
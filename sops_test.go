@@ -0,0 +1,87 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+// fakeSops writes a script standing in for sops, which "decrypts" `ENC[value]` to value and
+// extracts `["db"]["password"]` only. Its arguments and age identity are written to the returned log.
+func fakeSops(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	script, log := filepath.Join(dir, "sops"), filepath.Join(dir, "log")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+echo "$@" > `+log+`
+if [ -n "$SOPS_AGE_KEY_FILE" ]; then
+	cat "$SOPS_AGE_KEY_FILE" >> `+log+`
+fi
+input=$(cat)
+if ! printf '%s\n' "$input" | grep -q "^sops:"; then
+	echo "sops metadata not found" >&2
+	exit 128
+fi
+case "$*" in
+*'--extract ["db"]["password"]'*) printf hunter2 ;;
+*--extract*) echo "component not found" >&2; exit 1 ;;
+*) printf '%s\n' "$input" | sed -E -e 's/ENC\[([^]]*)\]/\1/g' -e '/^sops:/,$d' ;;
+esac
+`), 0700))
+	return script, log
+}
+
+func TestSopsEncryptedCredential(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "secrets"), []byte("db:\n  password: ENC[hunter2]\n  host: ENC[db.example.com]\nsops:\n  version: 3.9.0\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "plain"), []byte("db:\n  password: hunter2\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "age_key"), []byte(testAgeIdentity+"\n"), 0600))
+	sops, log := fakeSops(t)
+
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithSopsCommand(sops)).Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"secrets?sops=true&sops_key=db.password", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", str)
+	args, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, `--decrypt --input-type yaml --output-type yaml --extract ["db"]["password"] /dev/stdin`+"\n", string(args))
+
+	ret, err = prov.Retrieve(context.Background(), credSchemePrefix+"secrets?sops=true&format=map&age_identity=age_key", nil)
+	require.NoError(t, err)
+	raw, err := ret.AsRaw()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"db": map[string]any{"password": "hunter2", "host": "db.example.com"}}, raw)
+	args, err = os.ReadFile(log)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(args), testAgeIdentity+"\n"), "the identity is passed through SOPS_AGE_KEY_FILE")
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"secrets?sops=true&sops_key=db.missing", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `failed to decrypt SOPS-encrypted credential "secrets"`)
+	assert.Contains(t, err.Error(), "component not found")
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"plain?sops=true", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sops metadata not found")
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"secrets?sops_key=db.password", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must not set sops_key without sops=true")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestSopsHelpers(t *testing.T) {
+	assert.Equal(t, "json", sopsFormat([]byte("\n{\"sops\": {}}")))
+	assert.Equal(t, "yaml", sopsFormat([]byte("sops:\n")))
+	assert.Equal(t, `["db"]["replicas"][0]["password"]`, sopsExtractPath("db.replicas.0.password"))
+}
@@ -0,0 +1,22 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import "go.opentelemetry.io/collector/confmap"
+
+// NewStaticProvider returns a confmap.Provider that answers the "systemdcredential" scheme from
+// credentials, a map of credential names to values, instead of a credentials directory. It is
+// meant for tests of collectors embedding the provider, including on platforms without systemd.
+//
+// The provider is the one returned by NewFactory reading from memory, see WithFS: URIs are
+// parsed and validated, and values transformed, exactly like they are by the real provider.
+// opts configure it further, e.g. WithAliases. The map is copied, later changes to it aren't seen.
+func NewStaticProvider(credentials map[string]string, opts ...Option) confmap.Provider {
+	fsys := make(memFS, len(credentials))
+	for name, val := range credentials {
+		fsys[name] = []byte(val)
+	}
+	// WithFS goes last, so that the map takes precedence over any other directory in opts.
+	return newProvider(confmap.ProviderSettings{}, newConfig(append(opts[:len(opts):len(opts)], WithFS(fsys))))
+}
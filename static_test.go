@@ -0,0 +1,75 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestStaticProvider(t *testing.T) {
+	credentials := map[string]string{
+		"api_token": testCredValue + "\n",
+		"user":      "admin",
+		"password":  "hunter2",
+	}
+	prov := NewStaticProvider(credentials, WithAliases(map[string]string{"token": "api_token"}))
+	credentials["api_token"] = "changed"
+	assert.Equal(t, schemeName, prov.Scheme())
+	t.Setenv("STATIC_ENV", "from env")
+
+	for uri, expected := range map[string]string{
+		"api_token":              testCredValue,
+		"token":                  testCredValue,
+		"api_token?raw=true":     base64.StdEncoding.EncodeToString([]byte(testCredValue + "\n")),
+		"user+password":          "admin\nhunter2",
+		"missing:-fallback":      "fallback",
+		"missing|env:STATIC_ENV": "from env",
+	} {
+		ret, err := prov.Retrieve(context.Background(), credSchemePrefix+uri, nil)
+		require.NoError(t, err, uri)
+		str, err := ret.AsString()
+		require.NoError(t, err)
+		assert.Equal(t, expected, str, uri)
+		require.NoError(t, ret.Close(context.Background()))
+	}
+
+	// Closing a retrieved value wipes its buffers, but not the credentials of the provider.
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"user", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, "admin", str)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"missing", nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"../b", nil)
+	assert.ErrorContains(t, err, "invalid name")
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token?unknown=1", nil)
+	assert.Error(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestStaticProviderResolver(t *testing.T) {
+	prov := NewStaticProvider(map[string]string{"endpoint": "https://otlp.example.com\n"})
+	resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+		URIs: []string{"static:"},
+		ProviderFactories: []confmap.ProviderFactory{
+			confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider { return prov }),
+			confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider {
+				return staticProvider{conf: map[string]any{"endpoint": "${systemdcredential:endpoint}"}}
+			}),
+		},
+	})
+	require.NoError(t, err)
+	conf, err := resolver.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "https://otlp.example.com", conf.Get("endpoint"))
+	require.NoError(t, resolver.Shutdown(context.Background()))
+}
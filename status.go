@@ -0,0 +1,44 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"bou.ke/systemdcredentialprovider/internal/sdnotify"
+)
+
+// notifyStatus reports the failure to retrieve uri to systemd, so that `systemctl status`
+// shows what went wrong, see WithStatusNotification.
+func (p *provider) notifyStatus(uri string, err error) {
+	if _, notifyErr := sdnotify.Notify("STATUS=" + statusMessage(uri, p.cfg.scheme, p.cfg.envFile, err)); notifyErr != nil {
+		p.cfg.logger.Warn("Failed to notify systemd", zap.Error(notifyErr))
+	}
+}
+
+// statusMessage returns a single-line, human-readable description of the failure to retrieve uri
+// from the provider of scheme, envFile telling whether it reads env files.
+func statusMessage(uri, scheme string, envFile bool, err error) string {
+	name := uri
+	if !strings.Contains(uri, fallbackSeparator) {
+		if ref, parseErr := parseURI(uri, scheme, envFile); parseErr == nil {
+			name = strings.Join(ref.names, "+")
+		}
+	}
+	var msg string
+	switch {
+	case isMissing(err):
+		msg = "credential " + name + " missing"
+	case errors.Is(err, fs.ErrPermission):
+		msg = "credential " + name + " not readable: permission denied"
+	default:
+		msg = "credential " + name + " failed: " + err.Error()
+	}
+	// The status is a single line.
+	return strings.Join(strings.Fields(msg), " ")
+}
@@ -0,0 +1,69 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+
+	"bou.ke/systemdcredentialprovider/internal/sdnotify"
+)
+
+func TestStatusNotification(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	socket := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+	t.Setenv(sdnotify.SocketEnv, socket)
+	readStatus := func() string {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		buf := make([]byte, 1024)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return ""
+		}
+		return string(buf[:n])
+	}
+
+	prov := NewFactory(WithCredentialsDirectory(credDir)).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"missing", nil)
+	require.Error(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	_, err = conn.Read(make([]byte, 1))
+	require.Error(t, err, "expected no notification without WithStatusNotification")
+
+	prov = NewFactory(WithCredentialsDirectory(credDir), WithStatusNotification(true)).Create(confmaptest.NewNopProviderSettings())
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"missing?require=nonempty", nil)
+	require.Error(t, err)
+	assert.Equal(t, "STATUS=credential missing missing", readStatus())
+
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "empty"), nil, 0600))
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"api_token+empty?require=nonempty", nil)
+	require.Error(t, err)
+	assert.Regexp(t, `^STATUS=credential api_token\+empty failed: credential "empty" read from ".*" is empty$`, readStatus())
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestStatusMessage(t *testing.T) {
+	assert.Equal(t, "credential api_token not readable: permission denied",
+		statusMessage(credSchemePrefix+"api_token", schemeName, false, os.ErrPermission))
+	assert.Equal(t, "credential systemdcredential:a|env:B missing",
+		statusMessage(credSchemePrefix+"a|env:B", schemeName, false, errEnvNotSet))
+	assert.Equal(t, "credential api_token failed: line one line two",
+		statusMessage(credSchemePrefix+"api_token", schemeName, false, errors.New("line one\nline two")))
+}
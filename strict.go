@@ -0,0 +1,31 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// checkContent returns an error if val, a credential destined for a string field, contains
+// invalid UTF-8 or control characters other than tabs and newlines. The error reports the
+// offset of the offending byte but never the content around it.
+func checkContent(val []byte) error {
+	for i := 0; i < len(val); {
+		r, size := utf8.DecodeRune(val[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			return fmt.Errorf("contains invalid UTF-8 at byte %d", i)
+		case r == 0:
+			return fmt.Errorf("contains a NUL byte at byte %d", i)
+		case r == '\r':
+			return fmt.Errorf("contains a carriage return at byte %d, normalize=true replaces CRLF line endings", i)
+		case r != '\t' && r != '\n' && unicode.IsControl(r):
+			return fmt.Errorf("contains control character %U at byte %d", r, i)
+		}
+		i += size
+	}
+	return nil
+}
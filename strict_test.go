@@ -0,0 +1,63 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestCheckContent(t *testing.T) {
+	for _, val := range []string{"", testCredValue, "line 1\nline 2\n", "a\tb", "pässwörd ✓"} {
+		assert.NoError(t, checkContent([]byte(val)), val)
+	}
+	for val, want := range map[string]string{
+		"abc\x00def": "contains a NUL byte at byte 3",
+		"abc\xff":    "contains invalid UTF-8 at byte 3",
+		"abc\r":      "contains a carriage return at byte 3, normalize=true replaces CRLF line endings",
+		"\x1b[0m":    "contains control character U+001B at byte 0",
+		"a\u0085":    "contains control character U+0085 at byte 1",
+	} {
+		assert.EqualError(t, checkContent([]byte(val)), want)
+	}
+}
+
+func TestStrictContent(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue+"\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "binary"), []byte{0x7f, 'E', 'L', 'F', 0x02, 0x01}, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "windows"), []byte(testCredValue+"\r\n"), 0600))
+
+	prov := NewFactory().Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"binary", nil)
+	require.NoError(t, err)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"binary?strict=true", nil)
+	require.ErrorIs(t, err, ErrInvalidContent)
+	assert.EqualError(t, err, `credential of uri "systemdcredential:binary?strict=true" contains control character U+007F at byte 0`)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	prov = NewFactory(WithStrictContent(true)).Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"windows", nil)
+	assert.ErrorIs(t, err, ErrInvalidContent)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"windows?normalize=true", nil)
+	assert.NoError(t, err)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"binary?strict=false", nil)
+	assert.NoError(t, err)
+	// Raw credentials are returned base64-encoded, so binary content is expected.
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"binary?raw=true", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
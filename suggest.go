@@ -0,0 +1,114 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// maxSuggestions is the maximum number of names suggested for a missing credential.
+const maxSuggestions = 3
+
+// suggest adds the names of the existing credentials closest to name to err, the error
+// a missing credential called name failed with.
+func (p *provider) suggest(name string, err error) error {
+	if p.cfg.listenFDs || p.cfg.fileRoots != nil {
+		return err
+	}
+	fsys, _, exists := p.credentialsFS()
+	if !exists {
+		return err
+	}
+	entries, readErr := fs.ReadDir(fsys, ".")
+	if readErr != nil {
+		return err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && validCredentialName(entry.Name()) && p.checkAllowed(entry.Name()) == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	suggestions := closestNames(name, names)
+	if len(suggestions) == 0 {
+		return err
+	}
+	quoted := make([]string, len(suggestions))
+	for i, suggestion := range suggestions {
+		quoted[i] = fmt.Sprintf("%q", suggestion)
+	}
+	return fmt.Errorf("%w; did you mean %s?", err, strings.Join(quoted, " or "))
+}
+
+// minPrefixLength is the minimum length of a name matched as a prefix of another.
+const minPrefixLength = 3
+
+// closestNames returns up to maxSuggestions of names that are likely misspellings of name,
+// closest first. A name matches when it is equal to name ignoring case and the difference
+// between '-', '_' and '.', when one is a prefix of the other, or when it is within an edit
+// distance of a third of the length of name. Prefixes must be at least minPrefixLength long.
+func closestNames(name string, names []string) []string {
+	type match struct {
+		name     string
+		distance int
+	}
+	maxDistance := max(1, len(name)/3)
+	key := suggestionKey(name)
+	var matches []match
+	for _, candidate := range names {
+		if candidate == name {
+			continue
+		}
+		candidateKey := suggestionKey(candidate)
+		distance := editDistance(key, candidateKey)
+		switch {
+		case candidateKey == key:
+			distance = 0
+		case min(len(key), len(candidateKey)) >= minPrefixLength &&
+			(strings.HasPrefix(candidateKey, key) || strings.HasPrefix(key, candidateKey)):
+			distance = min(distance, maxDistance)
+		case distance > maxDistance:
+			continue
+		}
+		matches = append(matches, match{name: candidate, distance: distance})
+	}
+	slices.SortFunc(matches, func(a, b match) int {
+		return cmp.Or(cmp.Compare(a.distance, b.distance), strings.Compare(a.name, b.name))
+	})
+	suggestions := make([]string, 0, min(len(matches), maxSuggestions))
+	for _, m := range matches[:min(len(matches), maxSuggestions)] {
+		suggestions = append(suggestions, m.name)
+	}
+	return suggestions
+}
+
+// suggestionKey normalizes name for comparison, folding case and the separators commonly mixed up.
+func suggestionKey(name string) string {
+	return strings.NewReplacer("-", "_", ".", "_").Replace(strings.ToLower(name))
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,59 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+func TestSuggestions(t *testing.T) {
+	credDir := t.TempDir()
+	for _, name := range []string{"api_token", "api_token_v2", "db_password"} {
+		require.NoError(t, os.WriteFile(filepath.Join(credDir, name), []byte(testCredValue), 0600))
+	}
+	prov := NewFactory(WithCredentialsDirectory(credDir)).Create(confmaptest.NewNopProviderSettings())
+
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api-token", nil)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorContains(t, err, `did you mean "api_token" or "api_token_v2"?`)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"unrelated", nil)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "did you mean")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestClosestNames(t *testing.T) {
+	names := []string{"api_token", "API.TOKEN", "api_tokens", "db_password", "db", "tls.key"}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "api-token", want: []string{"API.TOKEN", "api_token", "api_tokens"}},
+		{name: "db_pasword", want: []string{"db_password"}},
+		{name: "tls", want: []string{"tls.key"}},
+		{name: "d", want: []string{"db"}},
+		{name: "certificate", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, closestNames(tt.name, names))
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("token", "token"))
+	assert.Equal(t, 1, editDistance("token", "tokens"))
+	assert.Equal(t, 3, editDistance("kitten", "sitting"))
+	assert.Equal(t, 5, editDistance("", "token"))
+}
@@ -0,0 +1,117 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build integration
+
+package systemdtest
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+
+	"bou.ke/systemdcredentialprovider"
+	"bou.ke/systemdcredentialprovider/creds"
+)
+
+// helperEnv makes the test binary resolve the space-separated URIs it is set to instead of
+// running the tests.
+const helperEnv = "SYSTEMDTEST_HELPER_URIS"
+
+func TestMain(m *testing.M) {
+	if uris, ok := os.LookupEnv(helperEnv); ok {
+		os.Exit(helper(strings.Fields(uris)))
+	}
+	os.Exit(m.Run())
+}
+
+// helper prints the mode of the credentials directory and resolves uris, like a collector
+// would when started by systemd, with the strictest permission check.
+func helper(uris []string) int {
+	dir, ok := os.LookupEnv(creds.DirectoryEnv)
+	if !ok {
+		fmt.Println("no credentials directory")
+		return 1
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	fmt.Printf("directory %v\n", info.Mode().Perm())
+	prov := systemdcredentialprovider.NewFactory(
+		systemdcredentialprovider.WithPermissionCheck(systemdcredentialprovider.PermissionCheckEnforce),
+	).Create(confmaptest.NewNopProviderSettings())
+	defer prov.Shutdown(context.Background())
+	for _, uri := range uris {
+		ret, err := prov.Retrieve(context.Background(), uri, nil)
+		if err != nil {
+			fmt.Printf("%s error %v\n", uri, err)
+			continue
+		}
+		str, err := ret.AsString()
+		if err != nil {
+			fmt.Printf("%s error %v\n", uri, err)
+			continue
+		}
+		fmt.Printf("%s=%s\n", uri, str)
+	}
+	return 0
+}
+
+// resolve resolves uris in a service started as svc and returns the lines the helper printed.
+func resolve(t *testing.T, svc *Service, uris ...string) []string {
+	t.Helper()
+	svc.Env = append(svc.Env, helperEnv+"="+strings.Join(uris, " "))
+	out, err := svc.Run(t, Executable(t))
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(out)), "\n")
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestCredentials(t *testing.T) {
+	dir := t.TempDir()
+	svc := &Service{
+		LoadCredential: map[string]string{"endpoint": writeFile(t, dir, "endpoint", "https://otlp.example.com\n")},
+		SetCredential:  map[string]string{"api_token": "s3cr3t"},
+	}
+	assert.Equal(t, []string{
+		"directory " + fs.FileMode(0500).String(),
+		"systemdcredential:endpoint=https://otlp.example.com",
+		"systemdcredential:api_token=s3cr3t",
+	}, resolve(t, svc, "systemdcredential:endpoint", "systemdcredential:api_token"))
+}
+
+func TestEncryptedCredential(t *testing.T) {
+	svc := &Service{LoadCredentialEncrypted: map[string]string{"api_token": Encrypt(t, "api_token", "s3cr3t")}}
+	assert.Equal(t, []string{
+		"directory " + fs.FileMode(0500).String(),
+		"systemdcredential:api_token=s3cr3t",
+	}, resolve(t, svc, "systemdcredential:api_token"))
+}
+
+func TestRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "api_token", "token-1")
+	svc := &Service{LoadCredential: map[string]string{"api_token": path}}
+	assert.Contains(t, resolve(t, svc, "systemdcredential:api_token"), "systemdcredential:api_token=token-1")
+
+	// Credentials are copied when a service starts, a restart picks up the rotated one.
+	writeFile(t, dir, "api_token", "token-2")
+	svc.Env = nil
+	assert.Contains(t, resolve(t, svc, "systemdcredential:api_token"), "systemdcredential:api_token=token-2")
+}
@@ -0,0 +1,143 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package systemdtest runs commands as transient services of the user's systemd service
+// manager with `systemd-run --user`, to test how they handle credentials passed by systemd
+// end to end: the credentials directory systemd creates, its permissions and encrypted
+// credentials. Collector distributions can use it to test their own binaries:
+//
+//	func TestCollector(t *testing.T) {
+//		svc := &systemdtest.Service{SetCredential: map[string]string{"api_token": "s3cr3t"}}
+//		out, err := svc.Run(t, "./otelcol", "validate", "--config", "config.yaml")
+//		...
+//	}
+//
+// Tests using it are skipped when no user service manager is available, e.g. in containers.
+package systemdtest // import "bou.ke/systemdcredentialprovider/systemdtest"
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// timeout is how long a service may run before it is stopped.
+const timeout = time.Minute
+
+var (
+	availableOnce sync.Once
+	availableErr  error
+)
+
+// Service is a transient service started with systemd-run.
+type Service struct {
+	// LoadCredential maps credential names to the files or directories passed with LoadCredential=.
+	LoadCredential map[string]string
+	// LoadCredentialEncrypted maps credential names to the encrypted files passed with
+	// LoadCredentialEncrypted=, see Encrypt.
+	LoadCredentialEncrypted map[string]string
+	// SetCredential maps credential names to the values passed with SetCredential=.
+	SetCredential map[string]string
+	// Env holds environment variables for the command, as KEY=VALUE.
+	Env []string
+	// Properties holds further unit properties, as passed to `systemd-run -p`.
+	Properties []string
+}
+
+// Skip skips the test unless systemd-run can start services of the user's service manager.
+func Skip(t testing.TB) {
+	t.Helper()
+	availableOnce.Do(func() {
+		if _, err := exec.LookPath("systemd-run"); err != nil {
+			availableErr = err
+			return
+		}
+		if out, err := exec.Command("systemd-run", "--user", "--wait", "--quiet", "--collect", "true").CombinedOutput(); err != nil {
+			availableErr = fmt.Errorf("%w: %s", err, bytes.TrimSpace(out))
+		}
+	})
+	if availableErr != nil {
+		t.Skipf("systemd user service manager is not available: %v", availableErr)
+	}
+}
+
+// Run runs command as a transient service and returns what it wrote to its standard output.
+// The command runs with the credentials and settings of s. If it fails, the error includes
+// what it wrote to its standard error. The test is skipped, see Skip, when there is no
+// service manager to run it.
+func (s *Service) Run(t testing.TB, command ...string) ([]byte, error) {
+	t.Helper()
+	Skip(t)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("systemd-run", s.args(command)...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.Bytes(), fmt.Errorf("%s: %w: %s", strings.Join(command, " "), err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// args returns the arguments of systemd-run to run command as s.
+func (s *Service) args(command []string) []string {
+	args := []string{
+		"--user", "--wait", "--pipe", "--quiet", "--collect",
+		"--service-type=exec",
+		fmt.Sprintf("--property=RuntimeMaxSec=%d", int(timeout.Seconds())),
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.LoadCredential)) {
+		args = append(args, "--property=LoadCredential="+name+":"+s.LoadCredential[name])
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.LoadCredentialEncrypted)) {
+		args = append(args, "--property=LoadCredentialEncrypted="+name+":"+s.LoadCredentialEncrypted[name])
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.SetCredential)) {
+		args = append(args, "--property=SetCredential="+name+":"+escape(s.SetCredential[name]))
+	}
+	for _, kv := range s.Env {
+		args = append(args, "--setenv="+kv)
+	}
+	for _, property := range s.Properties {
+		args = append(args, "--property="+property)
+	}
+	return append(append(args, "--"), command...)
+}
+
+// Encrypt encrypts value as the credential called name with `systemd-creds encrypt --user`,
+// for LoadCredentialEncrypted=, and returns the path of the encrypted file. The test is
+// skipped when the installed systemd can't encrypt credentials for the user's service manager.
+func Encrypt(t testing.TB, name, value string) string {
+	t.Helper()
+	Skip(t)
+	path := filepath.Join(t.TempDir(), name+".cred")
+	cmd := exec.Command("systemd-creds", "encrypt", "--user", "--name="+name, "-", path)
+	cmd.Stdin = strings.NewReader(value)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("systemd-creds can't encrypt credentials for the user's service manager: %v: %s", err, bytes.TrimSpace(out))
+	}
+	return path
+}
+
+// Executable returns the path of the running test binary, so that it can be run as a helper
+// in a service. The binary must be readable by the service manager, which it is when the
+// tests run as the user owning it.
+func Executable(t testing.TB) string {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("systemdtest: %v", err)
+	}
+	return exe
+}
+
+// escape escapes value for a unit setting, in which C-style escapes and specifiers are resolved.
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", "%%", "\n", `\n`).Replace(value)
+}
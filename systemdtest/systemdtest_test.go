@@ -0,0 +1,33 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArgs(t *testing.T) {
+	svc := &Service{
+		LoadCredential:          map[string]string{"tls.key": "/etc/otel/tls.key", "ca.crt": "/etc/otel/ca.crt"},
+		LoadCredentialEncrypted: map[string]string{"api_token": "/tmp/api_token.cred"},
+		SetCredential:           map[string]string{"password": "50% off\\now\n"},
+		Env:                     []string{"HELPER=1"},
+		Properties:              []string{"PrivateTmp=yes"},
+	}
+	assert.Equal(t, []string{
+		"--user", "--wait", "--pipe", "--quiet", "--collect",
+		"--service-type=exec",
+		"--property=RuntimeMaxSec=60",
+		"--property=LoadCredential=ca.crt:/etc/otel/ca.crt",
+		"--property=LoadCredential=tls.key:/etc/otel/tls.key",
+		"--property=LoadCredentialEncrypted=api_token:/tmp/api_token.cred",
+		`--property=SetCredential=password:50%% off\\now\n`,
+		"--setenv=HELPER=1",
+		"--property=PrivateTmp=yes",
+		"--",
+		"otelcol", "--config", "config.yaml",
+	}, svc.args([]string{"otelcol", "--config", "config.yaml"}))
+}
@@ -0,0 +1,207 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tlscreds builds TLS configurations from systemd credentials holding a PEM-encoded
+// certificate, its private key and optionally a CA bundle, reloading them when they are
+// rotated. Components configured through the collector's configtls settings don't need it:
+// their cert_pem, key_pem and ca_pem fields can reference the credentials directly, e.g.
+// `key_pem: ${systemdcredential:tls.key}`.
+package tlscreds // import "bou.ke/systemdcredentialprovider/tlscreds"
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"bou.ke/systemdcredentialprovider/creds"
+)
+
+// Config names the credentials a Loader reads.
+type Config struct {
+	// CertName is the credential holding the PEM-encoded certificate, followed by its chain.
+	CertName string
+	// KeyName is the credential holding the PEM-encoded private key of the certificate.
+	KeyName string
+	// CAName is the credential holding the PEM-encoded certificates of the CAs to trust, used
+	// to verify servers in ClientConfig and clients in ServerConfig. Optional: the system
+	// roots are used to verify servers and clients aren't asked for a certificate when empty.
+	CAName string
+	// ReloadInterval is how often the credentials are checked for changes when the keypair
+	// is used. Zero disables reloading.
+	ReloadInterval time.Duration
+}
+
+// Loader holds the keypair and CA pool read from credentials, see New.
+type Loader struct {
+	cfg Config
+	// now returns the current time, overridden in tests.
+	now func() time.Time
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	caPool  *x509.CertPool
+	version []credentialVersion
+	checked time.Time
+}
+
+// credentialVersion identifies the content of a credential without reading it.
+type credentialVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// New reads the credentials named by cfg and returns a Loader serving them.
+func New(ctx context.Context, cfg Config) (*Loader, error) {
+	if cfg.CertName == "" || cfg.KeyName == "" {
+		return nil, errors.New("both the certificate and the key credential have to be set")
+	}
+	l := &Loader{cfg: cfg, now: time.Now}
+	if err := l.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Reload reads the credentials again. On failure, the previously loaded keypair and CA pool
+// stay in use.
+func (l *Loader) Reload(ctx context.Context) error {
+	version := l.credentialVersions()
+	cert, caPool, err := l.load(ctx)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cert, l.caPool, l.version, l.checked = cert, caPool, version, l.now()
+	return nil
+}
+
+// load reads and parses the credentials.
+func (l *Loader) load(ctx context.Context) (*tls.Certificate, *x509.CertPool, error) {
+	certPEM, err := creds.Bytes(ctx, l.cfg.CertName)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := creds.Bytes(ctx, l.cfg.KeyName)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	clear(keyPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load keypair from credentials %q and %q: %w", l.cfg.CertName, l.cfg.KeyName, err)
+	}
+	if l.cfg.CAName == "" {
+		return &cert, nil, nil
+	}
+	caPEM, err := creds.Bytes(ctx, l.cfg.CAName)
+	if err != nil {
+		return nil, nil, err
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caPEM) {
+		return nil, nil, fmt.Errorf("credential %q holds no PEM-encoded certificates", l.cfg.CAName)
+	}
+	return &cert, caPool, nil
+}
+
+// credentialVersions returns the versions of the credentials read by l, nil if any of them
+// can't be found.
+func (l *Loader) credentialVersions() []credentialVersion {
+	dir, err := creds.Directory()
+	if err != nil {
+		return nil
+	}
+	var versions []credentialVersion
+	for _, name := range []string{l.cfg.CertName, l.cfg.KeyName, l.cfg.CAName} {
+		if name == "" {
+			continue
+		}
+		if !creds.ValidName(name) {
+			return nil
+		}
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			return nil
+		}
+		versions = append(versions, credentialVersion{modTime: info.ModTime(), size: info.Size()})
+	}
+	return versions
+}
+
+// current returns the keypair and CA pool to use, reloading them first when the credentials
+// changed since they were last checked, at most every ReloadInterval.
+func (l *Loader) current(ctx context.Context) (*tls.Certificate, *x509.CertPool) {
+	l.mu.Lock()
+	cert, caPool := l.cert, l.caPool
+	due := l.cfg.ReloadInterval > 0 && l.now().Sub(l.checked) >= l.cfg.ReloadInterval
+	if due {
+		l.checked = l.now()
+	}
+	version := l.version
+	l.mu.Unlock()
+	if !due || sameVersions(version, l.credentialVersions()) {
+		return cert, caPool
+	}
+	// Errors are ignored, rotation may be in progress: the previous keypair stays in use
+	// until the credentials can be loaded again.
+	_ = l.Reload(ctx)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cert, l.caPool
+}
+
+// sameVersions reports whether a and b describe the same credential contents.
+func sameVersions(a, b []credentialVersion) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
+
+// ClientConfig returns a TLS configuration presenting the keypair as client certificate and
+// verifying servers against the CA bundle, if any. The keypair is reloaded when it rotates;
+// the CA pool is the one loaded when ClientConfig was called.
+func (l *Loader) ClientConfig() *tls.Config {
+	_, caPool := l.current(context.Background())
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    caPool,
+		GetClientCertificate: func(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := l.current(info.Context())
+			return cert, nil
+		},
+	}
+}
+
+// ServerConfig returns a TLS configuration serving the keypair and, when a CA bundle is set,
+// requiring clients to present a certificate signed by one of its CAs. Both the keypair and
+// the CA pool are reloaded when they rotate.
+func (l *Loader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, caPool := l.current(hello.Context())
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+			}
+			if caPool != nil {
+				cfg.ClientCAs = caPool
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return cfg, nil
+		},
+	}
+}
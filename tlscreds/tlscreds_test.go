@@ -0,0 +1,192 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tlscreds
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bou.ke/systemdcredentialprovider/creds"
+)
+
+// issue returns a PEM-encoded certificate for commonName and its key, signed by parent,
+// or self-signed as a CA when parent is nil.
+func issue(t *testing.T, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) ([]byte, []byte, *x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), cert, key
+}
+
+// writeCredential writes a credential with the given modification time.
+func writeCredential(t *testing.T, dir, name string, content []byte, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0600))
+	require.NoError(t, os.Chtimes(filepath.Join(dir, name), modTime, modTime))
+}
+
+// handshake connects a client using clientCfg to a server using serverCfg and returns the
+// certificate presented by the server.
+func handshake(t *testing.T, clientCfg, serverCfg *tls.Config) (*x509.Certificate, error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		server := tls.Server(conn, serverCfg)
+		// Reading completes the handshake, including verifying the client certificate.
+		_, err = server.Read(make([]byte, 1))
+		serverErr <- err
+		server.Close()
+	}()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	client := tls.Client(conn, clientCfg)
+	defer client.Close()
+	if err := client.Handshake(); err != nil {
+		<-serverErr
+		return nil, err
+	}
+	_, err = client.Write([]byte{0})
+	if err == nil {
+		err = <-serverErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return client.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestLoader(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(creds.DirectoryEnv, dir)
+	caPEM, _, ca, caKey := issue(t, "ca", nil, nil)
+	serverPEM, serverKey, _, _ := issue(t, "server.example.com", ca, caKey)
+	clientPEM, clientKey, _, _ := issue(t, "client", ca, caKey)
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	writeCredential(t, dir, "ca.crt", caPEM, modTime)
+	writeCredential(t, dir, "server.crt", serverPEM, modTime)
+	writeCredential(t, dir, "server.key", serverKey, modTime)
+	writeCredential(t, dir, "client.crt", clientPEM, modTime)
+	writeCredential(t, dir, "client.key", clientKey, modTime)
+
+	server, err := New(context.Background(), Config{CertName: "server.crt", KeyName: "server.key", CAName: "ca.crt"})
+	require.NoError(t, err)
+	client, err := New(context.Background(), Config{CertName: "client.crt", KeyName: "client.key", CAName: "ca.crt"})
+	require.NoError(t, err)
+
+	clientCfg := client.ClientConfig()
+	clientCfg.ServerName = "server.example.com"
+	peer, err := handshake(t, clientCfg, server.ServerConfig())
+	require.NoError(t, err)
+	assert.Equal(t, "server.example.com", peer.Subject.CommonName)
+
+	// Without a client certificate signed by the CA, the handshake fails.
+	_, err = handshake(t, &tls.Config{RootCAs: clientCfg.RootCAs, ServerName: "server.example.com", MinVersion: tls.VersionTLS12}, server.ServerConfig())
+	assert.Error(t, err)
+}
+
+func TestLoaderReload(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(creds.DirectoryEnv, dir)
+	_, _, ca, caKey := issue(t, "ca", nil, nil)
+	oldPEM, oldKey, _, _ := issue(t, "old.example.com", ca, caKey)
+	newPEM, newKey, _, _ := issue(t, "new.example.com", ca, caKey)
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	writeCredential(t, dir, "tls.crt", oldPEM, modTime)
+	writeCredential(t, dir, "tls.key", oldKey, modTime)
+
+	l, err := New(context.Background(), Config{CertName: "tls.crt", KeyName: "tls.key", ReloadInterval: time.Minute})
+	require.NoError(t, err)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	leaf := func() string {
+		cert, _ := l.current(context.Background())
+		parsed, err := x509.ParseCertificate(cert.Certificate[0])
+		require.NoError(t, err)
+		return parsed.Subject.CommonName
+	}
+	assert.Equal(t, "old.example.com", leaf())
+
+	// Half-way through a rotation the keypair doesn't match: the old one stays in use.
+	writeCredential(t, dir, "tls.crt", newPEM, modTime.Add(time.Second))
+	now = now.Add(time.Minute)
+	assert.Equal(t, "old.example.com", leaf())
+
+	writeCredential(t, dir, "tls.key", newKey, modTime.Add(time.Second))
+	// The credentials are only checked once every ReloadInterval.
+	now = now.Add(time.Second)
+	assert.Equal(t, "old.example.com", leaf())
+	now = now.Add(time.Minute)
+	assert.Equal(t, "new.example.com", leaf())
+}
+
+func TestNewErrors(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(creds.DirectoryEnv, dir)
+	certPEM, keyPEM, _, _ := issue(t, "server.example.com", nil, nil)
+	writeCredential(t, dir, "tls.crt", certPEM, time.Now())
+	writeCredential(t, dir, "tls.key", keyPEM, time.Now())
+	writeCredential(t, dir, "garbage", []byte("not PEM"), time.Now())
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "no key", cfg: Config{CertName: "tls.crt"}, wantErr: "both the certificate and the key credential have to be set"},
+		{name: "missing", cfg: Config{CertName: "missing", KeyName: "tls.key"}, wantErr: `failed to open credential "missing"`},
+		{name: "mismatch", cfg: Config{CertName: "tls.crt", KeyName: "garbage"}, wantErr: `failed to load keypair from credentials "tls.crt" and "garbage"`},
+		{name: "invalid CA", cfg: Config{CertName: "tls.crt", KeyName: "tls.key", CAName: "garbage"}, wantErr: `credential "garbage" holds no PEM-encoded certificates`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(context.Background(), tt.cfg)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the provider's spans.
+const tracerName = "bou.ke/systemdcredentialprovider"
+
+// startSpan starts the span covering a retrieval.
+func (p *provider) startSpan(ctx context.Context) (context.Context, trace.Span) {
+	return p.tracer.Start(ctx, "Retrieve "+p.cfg.scheme,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attribute.String("scheme", p.cfg.scheme)))
+}
+
+// setSpanNames records the names of the credentials referenced by the retrieved URI on span.
+// Only the names are recorded, never the values.
+func setSpanNames(span trace.Span, names []string) {
+	span.SetAttributes(attribute.StringSlice("credential.names", names))
+}
+
+// endSpan ends span, marking it as failed when err is not nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// traceRead adds an event to the span in ctx recording where the credential called name was
+// looked up, so that the directory resolution of every credential is visible in the trace.
+func traceRead(ctx context.Context, name, credPath string, err error) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("credential.name", name),
+		attribute.String("credential.path", credPath),
+		attribute.Bool("credential.found", err == nil),
+	}
+	if err != nil {
+		attrs = append(attrs, attribute.String("reason", failureReason(err)))
+	}
+	span.AddEvent("read credential", trace.WithAttributes(attrs...))
+}
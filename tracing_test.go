@@ -0,0 +1,99 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// fakeSpan records what is set on it.
+type fakeSpan struct {
+	noop.Span
+	name   string
+	attrs  map[attribute.Key]attribute.Value
+	events []trace.EventConfig
+	status codes.Code
+	ended  bool
+}
+
+func (*fakeSpan) IsRecording() bool { return true }
+
+func (s *fakeSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, attr := range kv {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *fakeSpan) AddEvent(_ string, opts ...trace.EventOption) {
+	s.events = append(s.events, trace.NewEventConfig(opts...))
+}
+
+func (s *fakeSpan) SetStatus(code codes.Code, _ string) { s.status = code }
+
+func (s *fakeSpan) End(...trace.SpanEndOption) { s.ended = true }
+
+// fakeTracer records the spans it starts.
+type fakeTracer struct {
+	noop.Tracer
+	spans []*fakeSpan
+}
+
+func (t *fakeTracer) Start(ctx context.Context, name string, _ ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &fakeSpan{name: name, attrs: map[attribute.Key]attribute.Value{}}
+	t.spans = append(t.spans, span)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type fakeTracerProvider struct {
+	noop.TracerProvider
+	tracer *fakeTracer
+}
+
+func (p fakeTracerProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return p.tracer
+}
+
+func TestTracing(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	tracer := &fakeTracer{}
+
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithTracerProvider(fakeTracerProvider{tracer: tracer})).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"missing", nil)
+	require.Error(t, err)
+	assert.NoError(t, prov.Shutdown(context.Background()))
+
+	require.Len(t, tracer.spans, 2)
+	found, missing := tracer.spans[0], tracer.spans[1]
+	assert.Equal(t, "Retrieve systemdcredential", found.name)
+	assert.True(t, found.ended)
+	assert.Equal(t, codes.Unset, found.status)
+	assert.Equal(t, []string{"api_token"}, found.attrs["credential.names"].AsStringSlice())
+	require.Len(t, found.events, 1)
+	assert.Contains(t, found.events[0].Attributes(), attribute.String("credential.path", filepath.Join(credDir, "api_token")))
+	assert.Contains(t, found.events[0].Attributes(), attribute.Bool("credential.found", true))
+	for _, span := range tracer.spans {
+		for _, attr := range span.attrs {
+			assert.NotContains(t, attr.Emit(), testCredValue)
+		}
+	}
+
+	assert.True(t, missing.ended)
+	assert.Equal(t, codes.Error, missing.status)
+	require.Len(t, missing.events, 1)
+	assert.Contains(t, missing.events[0].Attributes(), attribute.String("reason", "not_found"))
+}
@@ -0,0 +1,30 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"bytes"
+)
+
+// TrimMode controls which whitespace is removed from a credential before it is returned.
+type TrimMode string
+
+const (
+	// TrimNone returns the credential as is.
+	TrimNone TrimMode = "none"
+	// TrimTrailingNewline removes a single trailing newline, as written by echo.
+	TrimTrailingNewline TrimMode = "trailing-newline"
+	// TrimAllWhitespace removes all leading and trailing whitespace.
+	TrimAllWhitespace TrimMode = "all-whitespace"
+)
+
+var trimFuncs = map[TrimMode]func([]byte) []byte{
+	TrimNone: func(val []byte) []byte {
+		return val
+	},
+	TrimTrailingNewline: func(val []byte) []byte {
+		return bytes.TrimSuffix(val, []byte("\n"))
+	},
+	TrimAllWhitespace: bytes.TrimSpace,
+}
@@ -0,0 +1,193 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+
+	"github.com/godbus/dbus/v5"
+
+	"bou.ke/systemdcredentialprovider/internal/cgroup"
+)
+
+const (
+	systemdBusName    = "org.freedesktop.systemd1"
+	systemdObjectPath = dbus.ObjectPath("/org/freedesktop/systemd1")
+	serviceInterface  = "org.freedesktop.systemd1.Service"
+)
+
+// UnitCredentials describes the credentials a systemd service passes to its processes.
+type UnitCredentials struct {
+	// Unit is the name of the service.
+	Unit string
+	// Names are the credentials set with LoadCredential=, LoadCredentialEncrypted=,
+	// SetCredential= and SetCredentialEncrypted=.
+	Names []string
+	// ImportPatterns are the glob patterns set with ImportCredential=.
+	ImportPatterns []string
+}
+
+// Provides reports whether the service passes the credential called name.
+func (u *UnitCredentials) Provides(name string) bool {
+	if slices.Contains(u.Names, name) {
+		return true
+	}
+	for _, pattern := range u.ImportPatterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an error listing every credential in names that the service doesn't pass,
+// and which can therefore never be resolved when running as part of the service.
+func (u *UnitCredentials) Check(names []string) error {
+	var errs []error
+	for _, name := range names {
+		if !u.Provides(name) {
+			errs = append(errs, fmt.Errorf("credential %q is not passed by unit %q: it has no matching LoadCredential=, SetCredential= or ImportCredential= setting", name, u.Unit))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LookupUnitCredentials queries the credential settings of unit from the systemd manager over
+// D-Bus. If unit is empty, the service owning the current process is used, from the manager
+// running it. Otherwise unit is looked up from the system manager, see LookupUserUnitCredentials
+// for user services.
+func LookupUnitCredentials(ctx context.Context, unit string) (*UnitCredentials, error) {
+	user := false
+	if unit == "" {
+		var err error
+		if unit, user, err = currentUnit(); err != nil {
+			return nil, err
+		}
+	}
+	return lookupUnitCredentialsOnBus(ctx, unit, user)
+}
+
+// LookupUserUnitCredentials queries the credential settings of unit, a user service, from the
+// service manager of the current user over the session bus. If unit is empty, the service owning
+// the current process is used.
+func LookupUserUnitCredentials(ctx context.Context, unit string) (*UnitCredentials, error) {
+	if unit == "" {
+		var err error
+		if unit, _, err = currentUnit(); err != nil {
+			return nil, err
+		}
+	}
+	return lookupUnitCredentialsOnBus(ctx, unit, true)
+}
+
+// currentUnit returns the service owning the current process, and whether it is run by a user
+// service manager.
+func currentUnit() (string, bool, error) {
+	cgroups, err := os.ReadFile(procSelfCgroup)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to determine the unit of the current process: %w", err)
+	}
+	unit, ok := cgroup.Unit(cgroups)
+	if !ok {
+		return "", false, errors.New("failed to determine the unit of the current process: not running as part of a service")
+	}
+	return unit, cgroup.UserUnit(cgroups), nil
+}
+
+// lookupUnitCredentialsOnBus looks up the credential settings of unit from the system manager,
+// or from the manager of the current user if user is set.
+func lookupUnitCredentialsOnBus(ctx context.Context, unit string, user bool) (*UnitCredentials, error) {
+	connect, bus := dbus.ConnectSystemBus, "system"
+	if user {
+		connect, bus = dbus.ConnectSessionBus, "session"
+	}
+	conn, err := connect(dbus.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the %s bus: %w", bus, err)
+	}
+	defer conn.Close()
+	return lookupUnitCredentials(ctx, conn, unit)
+}
+
+func lookupUnitCredentials(ctx context.Context, conn *dbus.Conn, unit string) (*UnitCredentials, error) {
+	var unitPath dbus.ObjectPath
+	if err := conn.Object(systemdBusName, systemdObjectPath).
+		CallWithContext(ctx, "org.freedesktop.systemd1.Manager.LoadUnit", 0, unit).
+		Store(&unitPath); err != nil {
+		return nil, fmt.Errorf("failed to load unit %q: %w", unit, err)
+	}
+	obj := conn.Object(systemdBusName, unitPath)
+	creds := &UnitCredentials{Unit: unit}
+
+	for _, property := range []string{"LoadCredential", "LoadCredentialEncrypted"} {
+		var settings []struct {
+			ID   string
+			Path string
+		}
+		if err := getProperty(ctx, obj, property, &settings); err != nil {
+			return nil, fmt.Errorf("failed to get %s of unit %q: %w", property, unit, err)
+		}
+		for _, setting := range settings {
+			creds.Names = append(creds.Names, setting.ID)
+		}
+	}
+	for _, property := range []string{"SetCredential", "SetCredentialEncrypted"} {
+		var settings []struct {
+			ID    string
+			Value []byte
+		}
+		if err := getProperty(ctx, obj, property, &settings); err != nil {
+			return nil, fmt.Errorf("failed to get %s of unit %q: %w", property, unit, err)
+		}
+		for _, setting := range settings {
+			creds.Names = append(creds.Names, setting.ID)
+		}
+	}
+	// ImportCredential= was added in systemd 254, older managers don't know the property.
+	if err := getProperty(ctx, obj, "ImportCredential", &creds.ImportPatterns); err != nil && !isUnknownProperty(err) {
+		return nil, fmt.Errorf("failed to get ImportCredential of unit %q: %w", unit, err)
+	}
+	return creds, nil
+}
+
+func getProperty(ctx context.Context, obj dbus.BusObject, property string, value any) error {
+	var variant dbus.Variant
+	if err := obj.CallWithContext(ctx, "org.freedesktop.DBus.Properties.Get", 0, serviceInterface, property).Store(&variant); err != nil {
+		return err
+	}
+	return variant.Store(value)
+}
+
+func isUnknownProperty(err error) bool {
+	var dbusErr dbus.Error
+	return errors.As(err, &dbusErr) &&
+		(dbusErr.Name == "org.freedesktop.DBus.Error.UnknownProperty" || dbusErr.Name == "org.freedesktop.DBus.Error.InvalidArgs")
+}
+
+// explainMissing adds to err, returned for the missing credential called name, the names of
+// similarly named credentials that do exist, and whether the unit configured with
+// WithUnitValidation passes that credential at all.
+func (p *provider) explainMissing(ctx context.Context, name string, err error) error {
+	err = p.suggest(name, err)
+	if !p.cfg.unitValidation {
+		return err
+	}
+	lookup := LookupUnitCredentials
+	if _, user := p.serviceUnit(); user {
+		lookup = LookupUserUnitCredentials
+	}
+	creds, lookupErr := lookup(ctx, p.cfg.validationUnit)
+	if lookupErr != nil {
+		return err
+	}
+	if checkErr := creds.Check([]string{name}); checkErr != nil {
+		return fmt.Errorf("%w; %w", err, checkErr)
+	}
+	return err
+}
@@ -0,0 +1,38 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitCredentialsProvides(t *testing.T) {
+	creds := &UnitCredentials{
+		Unit:           "otelcol.service",
+		Names:          []string{"api_token", "tls.key"},
+		ImportPatterns: []string{"otelcol.*"},
+	}
+	assert.True(t, creds.Provides("api_token"))
+	assert.True(t, creds.Provides("tls.key"))
+	assert.True(t, creds.Provides("otelcol.password"))
+	assert.False(t, creds.Provides("password"))
+	assert.False(t, creds.Provides("tls.crt"))
+}
+
+func TestUnitCredentialsCheck(t *testing.T) {
+	creds := &UnitCredentials{
+		Unit:  "otelcol.service",
+		Names: []string{"api_token"},
+	}
+	require.NoError(t, creds.Check([]string{"api_token"}))
+
+	err := creds.Check([]string{"api_token", "password", "tls.key"})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `credential "password" is not passed by unit "otelcol.service"`)
+	assert.ErrorContains(t, err, `credential "tls.key" is not passed by unit "otelcol.service"`)
+	assert.NotContains(t, err.Error(), `"api_token"`)
+}
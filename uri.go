@@ -0,0 +1,232 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"bou.ke/systemdcredentialprovider/internal/envfile"
+)
+
+// reference is a parsed `SCHEME:NAME[+NAME...][:-DEFAULT][?key=value][#fragment]` URI.
+type reference struct {
+	// names are the percent-decoded names of the credentials to read.
+	// More than one name is given when credentials are joined with '+'.
+	names []string
+	// opts holds the per-reference options set through the URI query.
+	opts uriOptions
+	// key is the variable to read from the env file, for `systemdenvfile:NAME#KEY` URIs.
+	key string
+	// pem selects a PEM block from the credential, for `systemdcredential:NAME#pem=TYPE` URIs.
+	pem *pemSelector
+}
+
+// uriOptions holds the options that can be attached to a single reference
+// through the URI query, e.g. `systemdcredential:NAME?optional=true`.
+type uriOptions struct {
+	// defaultValue is returned instead of an error when the credential is missing.
+	defaultValue *string
+	// optional resolves a missing credential to an empty value instead of an error.
+	optional bool
+	// requireNonEmpty rejects credentials that are empty or only contain whitespace.
+	requireNonEmpty bool
+	// decode is the name of the decoder applied to the credential, see decoders.
+	decode string
+	// raw returns the credential bytes base64-encoded instead of as a trimmed string.
+	raw bool
+	// trim overrides the factory-wide trim mode when set.
+	trim TrimMode
+	// normalize overrides the factory-wide normalization setting when set.
+	normalize *bool
+	// strict overrides the factory-wide strict content setting when set.
+	strict *bool
+	// expand resolves references to other credentials and environment variables in the content.
+	expand bool
+	// encrypted decrypts the credential with systemd-creds before using it.
+	encrypted bool
+	// parseYAML returns the credential parsed as a YAML scalar instead of as a string.
+	parseYAML bool
+	// formatMap returns the credential parsed as a JSON or YAML mapping instead of as a string.
+	formatMap bool
+	// ageIdentity is the name of the credential holding the age identity to decrypt the credential with.
+	ageIdentity string
+	// sops decrypts the credential, a SOPS-encrypted document, with sops.
+	sops bool
+	// sopsKey selects the value at this path of the SOPS-encrypted document.
+	sopsKey string
+	// dropIns reads the drop-ins of the credential after it, see withDropIns.
+	dropIns bool
+	// exists resolves to whether the credential exists, without reading it.
+	exists bool
+}
+
+// queryParams maps every supported query parameter to the function applying it to uriOptions.
+var queryParams = map[string]func(opts *uriOptions, value string) error{
+	"default": func(opts *uriOptions, value string) error {
+		opts.defaultValue = &value
+		return nil
+	},
+	"optional": func(opts *uriOptions, value string) (err error) {
+		opts.optional, err = strconv.ParseBool(value)
+		return err
+	},
+	"require": func(opts *uriOptions, value string) error {
+		if value != "nonempty" {
+			return fmt.Errorf("must be %q", "nonempty")
+		}
+		opts.requireNonEmpty = true
+		return nil
+	},
+	"decode": func(opts *uriOptions, value string) error {
+		if _, ok := decoders[value]; !ok {
+			return fmt.Errorf("unsupported decoding %q", value)
+		}
+		opts.decode = value
+		return nil
+	},
+	"raw": func(opts *uriOptions, value string) (err error) {
+		opts.raw, err = strconv.ParseBool(value)
+		return err
+	},
+	"trim": func(opts *uriOptions, value string) error {
+		if _, ok := trimFuncs[TrimMode(value)]; !ok {
+			return fmt.Errorf("unsupported trim mode %q", value)
+		}
+		opts.trim = TrimMode(value)
+		return nil
+	},
+	"normalize": func(opts *uriOptions, value string) error {
+		normalize, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		opts.normalize = &normalize
+		return nil
+	},
+	"strict": func(opts *uriOptions, value string) error {
+		strict, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		opts.strict = &strict
+		return nil
+	},
+	"expand": func(opts *uriOptions, value string) (err error) {
+		opts.expand, err = strconv.ParseBool(value)
+		return err
+	},
+	"encrypted": func(opts *uriOptions, value string) (err error) {
+		opts.encrypted, err = strconv.ParseBool(value)
+		return err
+	},
+	"parse": func(opts *uriOptions, value string) error {
+		switch value {
+		case "yaml":
+			opts.parseYAML = true
+		case "string":
+			opts.parseYAML = false
+		default:
+			return fmt.Errorf("unsupported parsing %q", value)
+		}
+		return nil
+	},
+	"age_identity": func(opts *uriOptions, value string) error {
+		if value == "" {
+			return errors.New("must name the credential holding the identity")
+		}
+		opts.ageIdentity = value
+		return nil
+	},
+	"sops": func(opts *uriOptions, value string) (err error) {
+		opts.sops, err = strconv.ParseBool(value)
+		return err
+	},
+	"sops_key": func(opts *uriOptions, value string) error {
+		if value == "" || slices.Contains(strings.Split(value, "."), "") {
+			return fmt.Errorf("invalid path %q", value)
+		}
+		opts.sopsKey = value
+		return nil
+	},
+	"dropins": func(opts *uriOptions, value string) (err error) {
+		opts.dropIns, err = strconv.ParseBool(value)
+		return err
+	},
+	"exists": func(opts *uriOptions, value string) (err error) {
+		opts.exists, err = strconv.ParseBool(value)
+		return err
+	},
+	"format": func(opts *uriOptions, value string) error {
+		if value != "map" {
+			return fmt.Errorf("must be %q", "map")
+		}
+		opts.formatMap = true
+		return nil
+	},
+}
+
+// parseURI parses uri into a reference. The uri must use scheme, the scheme of the provider;
+// the credential name is not validated here. envFile makes the fragment select a variable of an
+// env file, see NewEnvFileFactory.
+func parseURI(uri, scheme string, envFile bool) (*reference, error) {
+	if !strings.HasPrefix(uri, scheme+":") {
+		return nil, fmt.Errorf("%q uri is not supported by %q provider", uri, scheme)
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse uri %q: %w", uri, err)
+	}
+	ref := &reference{}
+	if envFile {
+		if !envfile.ValidName(u.Fragment) {
+			return nil, fmt.Errorf("uri %q must select a valid variable name with '#'", uri)
+		}
+		ref.key = u.Fragment
+	} else if u.Fragment != "" {
+		if ref.pem, err = parsePEMSelector(u.EscapedFragment()); err != nil {
+			return nil, fmt.Errorf("invalid PEM block selector in uri %q: %w", uri, err)
+		}
+	}
+	// Like the env provider, a default value can follow the name after ":-".
+	name, defaultValue, hasDefault := strings.Cut(u.Opaque, ":-")
+	if hasDefault {
+		ref.opts.defaultValue = &defaultValue
+	}
+	for _, part := range strings.Split(name, "+") {
+		// The name may be percent-encoded to express characters that are awkward in URIs.
+		decoded, err := url.PathUnescape(part)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode credential name %q in uri %q: %w", part, uri, err)
+		}
+		ref.names = append(ref.names, decoded)
+	}
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query of uri %q: %w", uri, err)
+	}
+	// Apply the parameters in a stable order so that errors are deterministic.
+	for _, key := range slices.Sorted(maps.Keys(query)) {
+		apply, ok := queryParams[key]
+		if !ok {
+			return nil, fmt.Errorf("unsupported query parameter %q in uri %q", key, uri)
+		}
+		values := query[key]
+		if key == "default" && hasDefault {
+			return nil, fmt.Errorf("uri %q must not set a default value both with \":-\" and the %q query parameter", uri, key)
+		}
+		if len(values) > 1 {
+			return nil, fmt.Errorf("query parameter %q is specified more than once in uri %q", key, uri)
+		}
+		if err := apply(&ref.opts, values[0]); err != nil {
+			return nil, fmt.Errorf("invalid value for query parameter %q in uri %q: %w", key, uri, err)
+		}
+	}
+	return ref, nil
+}
@@ -0,0 +1,65 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseURI(t *testing.T) {
+	ref, err := parseURI(credSchemePrefix+"api_token", schemeName, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api_token"}, ref.names)
+	assert.Nil(t, ref.opts.defaultValue)
+
+	ref, err = parseURI(credSchemePrefix+"api_token:-fallback:-value", schemeName, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api_token"}, ref.names)
+	require.NotNil(t, ref.opts.defaultValue)
+	assert.Equal(t, "fallback:-value", *ref.opts.defaultValue)
+
+	ref, err = parseURI(credSchemePrefix+"my%2Eapp%3Ftoken%23%25?optional=true", schemeName, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"my.app?token#%"}, ref.names)
+	assert.True(t, ref.opts.optional)
+
+	ref, err = parseURI(credSchemePrefix+"cert+intermediate+with%2Bplus:-none", schemeName, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cert", "intermediate", "with+plus"}, ref.names)
+}
+
+func TestParseURIErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		uri         string
+		errContains string
+	}{
+		{name: "other scheme", uri: "env:FOO", errContains: "is not supported"},
+		{name: "uppercase scheme", uri: "SYSTEMDCREDENTIAL:FOO", errContains: "is not supported"},
+		{name: "fragment", uri: credSchemePrefix + "FOO#bar", errContains: `unsupported parameter "bar"`},
+		{name: "empty PEM selector", uri: credSchemePrefix + "FOO#index=", errContains: `parameter "index" must be a non-negative integer`},
+		{name: "unknown parameter", uri: credSchemePrefix + "FOO?unknown=1", errContains: `unsupported query parameter "unknown"`},
+		{name: "conflicting defaults", uri: credSchemePrefix + "FOO:-a?default=b", errContains: "must not set a default value both"},
+		{name: "repeated parameter", uri: credSchemePrefix + "FOO?default=a&default=b", errContains: "more than once"},
+		{name: "invalid optional", uri: credSchemePrefix + "FOO?optional=maybe", errContains: `invalid value for query parameter "optional"`},
+		{name: "invalid require", uri: credSchemePrefix + "FOO?require=yes", errContains: `invalid value for query parameter "require"`},
+		{name: "invalid decode", uri: credSchemePrefix + "FOO?decode=rot13", errContains: `unsupported decoding "rot13"`},
+		{name: "invalid parse", uri: credSchemePrefix + "FOO?parse=json", errContains: `unsupported parsing "json"`},
+		{name: "invalid format", uri: credSchemePrefix + "FOO?format=list", errContains: `invalid value for query parameter "format"`},
+		{name: "empty age identity", uri: credSchemePrefix + "FOO?age_identity=", errContains: `invalid value for query parameter "age_identity"`},
+		{name: "invalid sops key", uri: credSchemePrefix + "FOO?sops=true&sops_key=db..password", errContains: `invalid path "db..password"`},
+		{name: "invalid escape", uri: credSchemePrefix + "default%config", errContains: "failed to decode credential name"},
+		{name: "invalid query", uri: credSchemePrefix + "FOO?a=%zz", errContains: "failed to parse query"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseURI(tt.uri, schemeName, false)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContains)
+		})
+	}
+}
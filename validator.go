@@ -0,0 +1,65 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+// Validator reports every credential reference of a configuration that failed to resolve at
+// once, instead of only the first one the resolver runs into.
+//
+// Pass it to the provider with WithValidator and add its ConverterFactory to the resolver. The
+// provider then records failing references and resolves them to null so that resolution
+// continues, and the converter fails resolution with all recorded errors afterwards.
+type Validator struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+// NewValidator returns a new Validator.
+func NewValidator() *Validator {
+	return &Validator{}
+}
+
+// ConverterFactory returns a factory for the converter that fails resolution if any
+// reference recorded by the validator failed to resolve.
+func (v *Validator) ConverterFactory() confmap.ConverterFactory {
+	return confmap.NewConverterFactory(func(confmap.ConverterSettings) confmap.Converter {
+		return validatorConverter{v: v}
+	})
+}
+
+// record records that uri failed to resolve with err.
+func (v *Validator) record(uri string, err error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.errs = append(v.errs, fmt.Errorf("%s: %w", uri, err))
+}
+
+// reset returns the recorded errors and forgets them, so that the next resolution starts afresh.
+func (v *Validator) reset() []error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	errs := v.errs
+	v.errs = nil
+	return errs
+}
+
+type validatorConverter struct {
+	v *Validator
+}
+
+func (c validatorConverter) Convert(context.Context, *confmap.Conf) error {
+	errs := c.v.reset()
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d credential references failed to resolve: %w", len(errs), errors.Join(errs...))
+}
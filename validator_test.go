@@ -0,0 +1,74 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+// staticProvider serves conf for every URI with the "static" scheme.
+type staticProvider struct {
+	conf map[string]any
+}
+
+func (p staticProvider) Retrieve(context.Context, string, confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	return confmap.NewRetrieved(p.conf)
+}
+
+func (staticProvider) Scheme() string {
+	return "static"
+}
+
+func (staticProvider) Shutdown(context.Context) error {
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	conf := map[string]any{
+		"token":    "${systemdcredential:api_token}",
+		"password": "${systemdcredential:password}",
+		"nested": map[string]any{
+			"key":     "${systemdcredential:tls.key}",
+			"invalid": "${systemdcredential:api_token?unknown=true}",
+		},
+	}
+
+	v := NewValidator()
+	resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+		URIs: []string{"static:config"},
+		ProviderFactories: []confmap.ProviderFactory{
+			confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider {
+				return staticProvider{conf: conf}
+			}),
+			NewFactory(WithCredentialsDirectory(credDir), WithValidator(v)),
+		},
+		ConverterFactories: []confmap.ConverterFactory{v.ConverterFactory()},
+	})
+	require.NoError(t, err)
+
+	_, err = resolver.Resolve(context.Background())
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "3 credential references failed to resolve")
+	assert.ErrorContains(t, err, `systemdcredential:password: failed to read credential "password"`)
+	assert.ErrorContains(t, err, `systemdcredential:tls.key: failed to read credential "tls.key"`)
+	assert.ErrorContains(t, err, `unsupported query parameter "unknown"`)
+	assert.NotContains(t, err.Error(), "systemdcredential:api_token:")
+
+	// Errors don't carry over to the next resolution.
+	delete(conf, "password")
+	delete(conf, "nested")
+	retMap, err := resolver.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, retMap.Get("token"))
+	assert.NoError(t, resolver.Shutdown(context.Background()))
+}
@@ -0,0 +1,94 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+)
+
+// varlinkCall is a varlink method call.
+type varlinkCall struct {
+	Method     string `json:"method"`
+	Parameters any    `json:"parameters,omitempty"`
+}
+
+// varlinkReply is a varlink method reply.
+type varlinkReply struct {
+	Parameters json.RawMessage `json:"parameters"`
+	Error      string          `json:"error"`
+}
+
+// credentialsDecryptParameters are the parameters of io.systemd.Credentials.Decrypt.
+type credentialsDecryptParameters struct {
+	Name string `json:"name,omitempty"`
+	Blob string `json:"blob"`
+	// Scope and UID select credentials encrypted for a user with `systemd-creds encrypt --user`.
+	Scope string `json:"scope,omitempty"`
+	UID   *int   `json:"uid,omitempty"`
+}
+
+// credentialsDecryptResult is the result of io.systemd.Credentials.Decrypt.
+type credentialsDecryptResult struct {
+	Data string `json:"data"`
+}
+
+// decryptWithVarlink decrypts blob, the binary content of an encrypted credential called name,
+// by calling io.systemd.Credentials.Decrypt on the varlink service listening on p.cfg.varlinkSocket.
+func (p *provider) decryptWithVarlink(ctx context.Context, name string, blob []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", p.cfg.varlinkSocket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to io.systemd.Credentials: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	params := credentialsDecryptParameters{
+		Name: name,
+		Blob: base64.StdEncoding.EncodeToString(blob),
+	}
+	if _, user := p.serviceUnit(); user {
+		uid := os.Getuid()
+		params.Scope, params.UID = "user", &uid
+	}
+	call, err := json.Marshal(varlinkCall{
+		Method:     "io.systemd.Credentials.Decrypt",
+		Parameters: params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Varlink messages are terminated by a NUL byte.
+	if _, err = conn.Write(append(call, 0)); err != nil {
+		return nil, fmt.Errorf("failed to call io.systemd.Credentials.Decrypt: %w", err)
+	}
+	msg, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reply of io.systemd.Credentials.Decrypt: %w", err)
+	}
+
+	var reply varlinkReply
+	if err = json.Unmarshal(bytes.TrimSuffix(msg, []byte{0}), &reply); err != nil {
+		return nil, fmt.Errorf("invalid reply of io.systemd.Credentials.Decrypt: %w", err)
+	}
+	if reply.Error != "" {
+		return nil, fmt.Errorf("io.systemd.Credentials.Decrypt failed: %s", reply.Error)
+	}
+	var result credentialsDecryptResult
+	if err = json.Unmarshal(reply.Parameters, &result); err != nil {
+		return nil, fmt.Errorf("invalid reply of io.systemd.Credentials.Decrypt: %w", err)
+	}
+	return base64.StdEncoding.DecodeString(result.Data)
+}
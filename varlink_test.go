@@ -0,0 +1,95 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+// serveCredentialsDecrypt serves io.systemd.Credentials.Decrypt on a unix socket, "decrypting"
+// blobs by reversing them. Blobs of credentials named "nokey" fail to decrypt.
+func serveCredentialsDecrypt(t *testing.T) string {
+	socket := filepath.Join(t.TempDir(), "io.systemd.Credentials")
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			msg, err := bufio.NewReader(conn).ReadBytes(0)
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			var call struct {
+				Method     string
+				Parameters credentialsDecryptParameters
+			}
+			_ = json.Unmarshal(bytes.TrimSuffix(msg, []byte{0}), &call)
+			blob, _ := base64.StdEncoding.DecodeString(call.Parameters.Blob)
+			var reply any
+			if call.Method != "io.systemd.Credentials.Decrypt" || call.Parameters.Name == "nokey" {
+				reply = map[string]any{"error": "io.systemd.Credentials.KeyBelongsToOtherTPM"}
+			} else {
+				for i, j := 0, len(blob)-1; i < j; i, j = i+1, j-1 {
+					blob[i], blob[j] = blob[j], blob[i]
+				}
+				reply = map[string]any{"parameters": credentialsDecryptResult{Data: base64.StdEncoding.EncodeToString(blob)}}
+			}
+			out, _ := json.Marshal(reply)
+			_, _ = conn.Write(append(out, 0))
+			conn.Close()
+		}
+	}()
+	return socket
+}
+
+func TestVarlinkDecryption(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("54321-nekot-terces-ym"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "nokey"), []byte("blob"), 0600))
+
+	prov := NewFactory(
+		WithVarlinkSocket(serveCredentialsDecrypt(t)),
+		WithSystemdCredsCommand("systemd-creds-does-not-exist"),
+	).Create(confmaptest.NewNopProviderSettings())
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token?encrypted=true", nil)
+	require.NoError(t, err)
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"nokey?encrypted=true", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "io.systemd.Credentials.KeyBelongsToOtherTPM")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestVarlinkDecryptionUnavailable(t *testing.T) {
+	credDir := t.TempDir()
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("blob"), 0600))
+
+	prov := NewFactory(WithVarlinkSocket(filepath.Join(credDir, "missing.socket"))).Create(confmaptest.NewNopProviderSettings())
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token?encrypted=true", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to connect to io.systemd.Credentials")
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
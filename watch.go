@@ -0,0 +1,127 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"context"
+	"crypto/sha256"
+	"maps"
+	"time"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.uber.org/zap"
+)
+
+// defaultWatchInterval is the default interval of the checks for changes, see WithWatchInterval.
+const defaultWatchInterval = 5 * time.Second
+
+// watchKey is the context key of the *watchRecorder of a Retrieve call.
+type watchKey struct{}
+
+// watchRecorder collects the credentials and files read by a Retrieve call, with hashes of
+// their contents and where they were read from, so that they can be watched for changes.
+type watchRecorder struct {
+	credentials map[string][sha256.Size]byte
+	files       map[string][sha256.Size]byte
+}
+
+// recordWatched adds the credential called name, read from path with err, to the credentials the
+// Retrieve call ctx belongs to watches, if it watches any. Missing credentials are watched for
+// being created.
+func recordWatched(ctx context.Context, name, path string, val []byte, err error) {
+	if r, ok := ctx.Value(watchKey{}).(*watchRecorder); ok {
+		recordFingerprint(r.credentials, name, path, val, err)
+	}
+}
+
+// recordWatchedFile is like recordWatched for the file at path, read for a `file:` source.
+func recordWatchedFile(ctx context.Context, path string, val []byte, err error) {
+	if r, ok := ctx.Value(watchKey{}).(*watchRecorder); ok {
+		recordFingerprint(r.files, path, path, val, err)
+	}
+}
+
+// recordFingerprint records the fingerprint of val, the content of key read from path with err,
+// in fingerprints. Missing ones are recorded with a zero fingerprint. It returns false if the
+// read failed for another reason.
+func recordFingerprint(fingerprints map[string][sha256.Size]byte, key, path string, val []byte, err error) bool {
+	switch {
+	case isMissing(err):
+		fingerprints[key] = [sha256.Size]byte{}
+	case err != nil:
+		return false
+	default:
+		fingerprints[key] = fingerprint(path, val)
+	}
+	return true
+}
+
+// fingerprint returns the hash of val, read from path. The path is part of it, so that a
+// credential read from somewhere else, such as a new snapshot of a projected volume, is a change.
+func fingerprint(path string, val []byte) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(val)
+	return [sha256.Size]byte(h.Sum(nil))
+}
+
+// watch checks the credentials and files recorded in r for changes every watch interval, and
+// calls watcher once one of them changed, was created or was removed, until the provider is shut down.
+func (p *provider) watch(r *watchRecorder, watcher confmap.WatcherFunc) {
+	if len(r.credentials) == 0 && len(r.files) == 0 {
+		return
+	}
+	p.watchers.Add(1)
+	go func() {
+		defer p.watchers.Done()
+		ticker := time.NewTicker(p.cfg.watchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.watchCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			current, ok := p.checkWatched(r)
+			if !ok {
+				// The check is retried, e.g. when a credential is replaced while reading it.
+				continue
+			}
+			if !maps.Equal(r.credentials, current.credentials) || !maps.Equal(r.files, current.files) {
+				p.cfg.logger.Info("Credentials changed")
+				watcher(&confmap.ChangeEvent{})
+				return
+			}
+		}
+	}()
+}
+
+// checkWatched reads the credentials and files recorded in r again, returning their current
+// fingerprints. ok is false if any of them failed to be read.
+func (p *provider) checkWatched(r *watchRecorder) (current *watchRecorder, ok bool) {
+	current = &watchRecorder{
+		credentials: make(map[string][sha256.Size]byte, len(r.credentials)),
+		files:       make(map[string][sha256.Size]byte, len(r.files)),
+	}
+	for name := range r.credentials {
+		val, path, err := p.readCredential(name)
+		recorded := recordFingerprint(current.credentials, name, path, val, err)
+		clear(val)
+		if !recorded {
+			p.cfg.logger.Debug("Failed to check credential for changes", zap.String("credential", name), zap.Error(err))
+			return nil, false
+		}
+	}
+	for path := range r.files {
+		val, err := p.readFileSource(path)
+		recorded := recordFingerprint(current.files, path, path, val, err)
+		clear(val)
+		if !recorded {
+			p.cfg.logger.Debug("Failed to check file for changes", zap.String("path", path), zap.Error(err))
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,96 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+// retrieveWatched retrieves uri from prov, returning a channel receiving the change events.
+func retrieveWatched(t *testing.T, prov confmap.Provider, uri string) <-chan *confmap.ChangeEvent {
+	events := make(chan *confmap.ChangeEvent, 1)
+	ret, err := prov.Retrieve(context.Background(), uri, func(event *confmap.ChangeEvent) {
+		events <- event
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, ret.Close(context.Background()))
+	})
+	return events
+}
+
+func TestWatch(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithWatchInterval(10*time.Millisecond)).Create(confmaptest.NewNopProviderSettings())
+	t.Cleanup(func() {
+		assert.NoError(t, prov.Shutdown(context.Background()))
+	})
+
+	events := retrieveWatched(t, prov, credSchemePrefix+"api_token")
+	missing := retrieveWatched(t, prov, credSchemePrefix+"missing?optional=true")
+	select {
+	case <-events:
+		t.Fatal("watcher called without a change")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("rotated"), 0600))
+	select {
+	case event := <-events:
+		assert.NoError(t, event.Error)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher not called after the credential changed")
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "missing"), []byte("created"), 0600))
+	select {
+	case event := <-missing:
+		assert.NoError(t, event.Error)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher not called after the credential was created")
+	}
+}
+
+func TestWatchProjectedVolume(t *testing.T) {
+	dir := t.TempDir()
+	publish := writeProjectedVolume(t, dir, map[string]string{"api_token": testCredValue})
+	prov := NewKubernetesSecretFactory(dir, WithWatchInterval(10*time.Millisecond)).Create(confmaptest.NewNopProviderSettings())
+	t.Cleanup(func() {
+		assert.NoError(t, prov.Shutdown(context.Background()))
+	})
+
+	events := retrieveWatched(t, prov, kubernetesSchemePrefix+"api_token")
+	publish(map[string]string{"api_token": "rotated"})
+	select {
+	case event := <-events:
+		assert.NoError(t, event.Error)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher not called after the volume was updated")
+	}
+}
+
+func TestWatchStopsOnShutdown(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	prov := NewFactory(WithCredentialsDirectory(credDir), WithWatchInterval(10*time.Millisecond)).Create(confmaptest.NewNopProviderSettings())
+
+	events := retrieveWatched(t, prov, credSchemePrefix+"api_token")
+	require.NoError(t, prov.Shutdown(context.Background()))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte("rotated"), 0600))
+	select {
+	case <-events:
+		t.Fatal("watcher called after shutdown")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider // import "bou.ke/systemdcredentialprovider"
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+// credentialBuffers holds the buffers allocated for the contents of the credentials of a
+// single retrieval, so that they can be wiped once the retrieved value is no longer needed.
+type credentialBuffers struct {
+	bufs [][]byte
+}
+
+// add tracks buf and returns it.
+func (b *credentialBuffers) add(buf []byte) []byte {
+	b.bufs = append(b.bufs, buf)
+	return buf
+}
+
+// wipe overwrites all tracked buffers, including their spare capacity, with zeroes.
+func (b *credentialBuffers) wipe() {
+	for _, buf := range b.bufs {
+		clear(buf[:cap(buf)])
+	}
+	b.bufs = nil
+}
+
+// bufferRegistry holds the credentialBuffers of the values a provider returned that haven't
+// been closed yet.
+type bufferRegistry struct {
+	mu      sync.Mutex
+	pending map[*credentialBuffers]struct{}
+}
+
+// track registers bufs until they are released.
+func (r *bufferRegistry) track(bufs *credentialBuffers) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending == nil {
+		r.pending = map[*credentialBuffers]struct{}{}
+	}
+	r.pending[bufs] = struct{}{}
+}
+
+// release wipes bufs and stops tracking them.
+func (r *bufferRegistry) release(bufs *credentialBuffers) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, bufs)
+	bufs.wipe()
+}
+
+// retrievedClose returns the option that cleans up after a retrieved value once it is closed,
+// which the resolver does when the configuration is resolved again: bufs, the buffers holding
+// its credential contents, are wiped, and the credentials called names are removed from the cache.
+func (p *provider) retrievedClose(bufs *credentialBuffers, names []string) confmap.RetrievedOption {
+	p.buffers.track(bufs)
+	return confmap.WithRetrievedClose(func(context.Context) error {
+		p.buffers.release(bufs)
+		if p.cache != nil {
+			p.cache.invalidate(names...)
+		}
+		return nil
+	})
+}
+
+// wipeAll wipes the buffers of all values that haven't been closed.
+func (r *bufferRegistry) wipeAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for bufs := range r.pending {
+		bufs.wipe()
+	}
+	clear(r.pending)
+}
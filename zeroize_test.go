@@ -0,0 +1,80 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdcredentialprovider
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+)
+
+// pendingBuffers returns the buffers prov hasn't wiped yet.
+func pendingBuffers(t *testing.T, prov *provider) [][]byte {
+	t.Helper()
+	prov.buffers.mu.Lock()
+	defer prov.buffers.mu.Unlock()
+	var bufs [][]byte
+	for pending := range prov.buffers.pending {
+		bufs = append(bufs, pending.bufs...)
+	}
+	return bufs
+}
+
+func TestZeroizeOnClose(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue+"\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "encoded"), []byte("aGVsbG8="), 0600))
+	prov := NewFactory(WithCredentialsDirectory(credDir)).Create(confmaptest.NewNopProviderSettings()).(*provider)
+
+	ret, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token", nil)
+	require.NoError(t, err)
+	bufs := pendingBuffers(t, prov)
+	require.Len(t, bufs, 1)
+	assert.Equal(t, testCredValue+"\n", string(bufs[0]))
+
+	require.NoError(t, ret.Close(context.Background()))
+	assert.Empty(t, pendingBuffers(t, prov))
+	assert.Equal(t, make([]byte, cap(bufs[0])), bufs[0][:cap(bufs[0])])
+	// The retrieved value is a copy and stays intact.
+	str, err := ret.AsString()
+	require.NoError(t, err)
+	assert.Equal(t, testCredValue, str)
+
+	_, err = prov.Retrieve(context.Background(), credSchemePrefix+"encoded?decode=base64", nil)
+	require.NoError(t, err)
+	bufs = pendingBuffers(t, prov)
+	require.Len(t, bufs, 2)
+	require.NoError(t, prov.Shutdown(context.Background()))
+	assert.Empty(t, pendingBuffers(t, prov))
+	for _, buf := range bufs {
+		assert.Equal(t, make([]byte, len(buf)), buf)
+	}
+}
+
+func TestZeroizeOnFailure(t *testing.T) {
+	credDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(credDir, "api_token"), []byte(testCredValue), 0600))
+	prov := NewFactory(WithCredentialsDirectory(credDir)).Create(confmaptest.NewNopProviderSettings()).(*provider)
+
+	_, err := prov.Retrieve(context.Background(), credSchemePrefix+"api_token+missing", nil)
+	require.Error(t, err)
+	assert.Empty(t, pendingBuffers(t, prov))
+	assert.NoError(t, prov.Shutdown(context.Background()))
+}
+
+func TestCredentialBuffersWipe(t *testing.T) {
+	buf := []byte("secret\n")
+	var bufs credentialBuffers
+	trimmed := bufs.add(bytes.TrimSuffix(buf, []byte("\n")))
+	bufs.wipe()
+	assert.Equal(t, make([]byte, len(buf)), buf)
+	assert.Equal(t, make([]byte, len(trimmed)), trimmed)
+	assert.Nil(t, bufs.bufs)
+}